@@ -0,0 +1,243 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/logging"
+	"github.com/albeebe/service/pkg/logger"
+)
+
+// AccessLogPolicy controls which requests the service records in its access log. Every request
+// that's logged records its method, route, status, latency, response size, user agent, remote
+// IP and principal.
+type AccessLogPolicy struct {
+	// Disabled turns off the access log entirely.
+	Disabled bool
+
+	// SampleRate is the fraction of requests that are logged, between 0 and 1. Requests that fail
+	// with a 5xx status are always logged. Defaults to 1, logging every request.
+	SampleRate float64
+
+	// ExcludePaths are the route templates (e.g. "/orders/:id") of endpoints whose requests aren't
+	// logged. Requests that don't match a route are matched against their path instead.
+	ExcludePaths []string
+
+	// IncludeHealthChecks logs requests to the liveness and readiness endpoints, which are
+	// excluded by default since they're requested frequently by load balancers and probes.
+	IncludeHealthChecks bool
+}
+
+// WithoutAccessLog excludes the endpoint's requests from the access log.
+func WithoutAccessLog() EndpointOption {
+	return func(o *endpointOptions) {
+		o.withoutAccessLog = true
+	}
+}
+
+// excludeFromAccessLog returns a handler that excludes the request from the access log before
+// invoking the handler.
+func excludeFromAccessLog(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if state := requestStateFromContext(r.Context()); state != nil {
+			state.withoutAccessLog = true
+		}
+		handler(w, r)
+	}
+}
+
+// withDefaults returns a copy of the policy with the defaults applied to any unset fields.
+func (policy AccessLogPolicy) withDefaults() AccessLogPolicy {
+	if policy.SampleRate <= 0 || policy.SampleRate > 1 {
+		policy.SampleRate = 1
+	}
+	return policy
+}
+
+// logs reports whether a request, with the state it finished with, should be logged.
+func (policy AccessLogPolicy) logs(r *http.Request, state *requestState, status int) bool {
+	path := r.URL.Path
+	if route := state.route; route != nil {
+		if route.Kind == EndpointHealth && !policy.IncludeHealthChecks {
+			return false
+		}
+		path = route.Path
+	}
+	if state.withoutAccessLog || slices.Contains(policy.ExcludePaths, path) {
+		return false
+	}
+	return status >= http.StatusInternalServerError || policy.SampleRate >= 1 || rand.Float64() < policy.SampleRate
+}
+
+// accessLogMiddleware returns router middleware that records requests in the access log, as
+// permitted by the service's AccessLogPolicy. Entries are sent with a logger.HTTPRequest
+// attribute, so they're shown in the Cloud Logging request view in production.
+func (s *Service) accessLogMiddleware() func(next http.Handler) http.Handler {
+	policy := s.internal.options.accessLogPolicy.withDefaults()
+	return func(next http.Handler) http.Handler {
+		if policy.Disabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			r, state := withRequestState(r)
+			recorder := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(recorder, r)
+
+			status := recorder.Status()
+			if !policy.logs(r, state, status) {
+				return
+			}
+
+			httpRequest := &logging.HTTPRequest{
+				Request:      r,
+				Status:       status,
+				ResponseSize: recorder.size,
+				Latency:      time.Since(start),
+				RemoteIP:     s.remoteIP(r),
+			}
+			if r.ContentLength > 0 {
+				httpRequest.RequestSize = r.ContentLength
+			}
+			attrs := []slog.Attr{logger.HTTPRequest(httpRequest)}
+			if state.route != nil {
+				attrs = append(attrs, slog.String("route", state.route.Path))
+			}
+			if state.identity != nil {
+				attrs = append(attrs, slog.String("principal", state.identity.Subject))
+			}
+			if state.requestID != "" {
+				attrs = append(attrs, slog.String("request_id", state.requestID))
+			}
+
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelWarn
+			}
+			s.Log.LogAttrs(correlationContext(r), level, fmt.Sprintf("%s %s %d", r.Method, r.URL.Path, status), attrs...)
+		})
+	}
+}
+
+// remoteIP returns the IP address of the client that made the request. Behind trusted proxies,
+// it's the entry of the X-Forwarded-For header appended by the outermost of them, since the
+// entries to its left are supplied by the client and can't be trusted. Otherwise, or when the
+// header is missing, it's the address the request was received from.
+func (s *Service) remoteIP(r *http.Request) string {
+	if proxies := s.internal.options.trustedProxies; proxies > 0 {
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			entries := strings.Split(strings.Join(forwarded, ","), ",")
+			if ip := strings.TrimSpace(entries[max(len(entries)-proxies, 0)]); ip != "" {
+				return ip
+			}
+		}
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// responseRecorder is an http.ResponseWriter that records the status and size of the response
+// written through it. It supports flushing and hijacking when the underlying writer does, so
+// it can wrap streaming and websocket responses.
+type responseRecorder struct {
+	http.ResponseWriter
+	status   int
+	size     int64
+	hijacked bool
+}
+
+// WriteHeader records the status of the response, and sends it with the underlying writer.
+func (w *responseRecorder) WriteHeader(status int) {
+	if w.status == 0 && status >= http.StatusOK {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the size of the response, and writes it with the underlying writer.
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// Flush sends any buffered data to the client, if the underlying writer supports it.
+func (w *responseRecorder) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		flusher.Flush()
+	}
+}
+
+// Hijack lets the caller take over the connection, such as to upgrade it to a websocket.
+func (w *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer doesn't support hijacking")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the underlying writer, for use by http.ResponseController.
+func (w *responseRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Started reports whether the response has started being sent, after which its status can no
+// longer be changed.
+func (w *responseRecorder) Started() bool {
+	return w.status != 0 || w.hijacked
+}
+
+// Status returns the status of the response. A hijacked connection is reported as switching
+// protocols, and a response that was never written is reported as 200 OK, as net/http sends it.
+func (w *responseRecorder) Status() int {
+	switch {
+	case w.status != 0:
+		return w.status
+	case w.hijacked:
+		return http.StatusSwitchingProtocols
+	default:
+		return http.StatusOK
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/albeebe/service"
+	"github.com/albeebe/service/servicetest"
+)
+
+func TestAccessLog(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []service.Option
+		target   string
+		expected map[string]any // expected are the fields of the entry, which isn't logged when nil
+	}{
+		{
+			name:   "logged",
+			target: "/orders/42?expand=items",
+			expected: map[string]any{
+				"level": "INFO", "msg": "GET /orders/42 200", "route": "/orders/:id", "request_id": "request-1",
+				"httpRequest": map[string]any{"method": "GET", "url": "/orders/42?expand=items", "status": float64(200), "responseSize": float64(len("order 42")), "userAgent": "orders-cli/1.0", "remoteIp": "192.0.2.1"},
+			},
+		},
+		{
+			name:   "server error",
+			target: "/unavailable",
+			expected: map[string]any{
+				"level": "WARN", "msg": "GET /unavailable 503", "route": "/unavailable",
+				"httpRequest": map[string]any{"status": float64(503)},
+			},
+		},
+		{
+			name:     "unmatched path",
+			target:   "/missing",
+			expected: map[string]any{"level": "INFO", "msg": "GET /missing 404", "httpRequest": map[string]any{"status": float64(404)}},
+		},
+		{
+			name:    "excluded route",
+			options: []service.Option{service.WithAccessLogPolicy(service.AccessLogPolicy{ExcludePaths: []string{"/orders/:id"}})},
+			target:  "/orders/42",
+		},
+		{
+			name:    "excluded unmatched path",
+			options: []service.Option{service.WithAccessLogPolicy(service.AccessLogPolicy{ExcludePaths: []string{"/missing"}})},
+			target:  "/missing",
+		},
+		{
+			name:   "excluded endpoint",
+			target: "/ping",
+		},
+		{
+			name:   "health check",
+			target: service.ReadinessPath,
+		},
+		{
+			name:     "included health check",
+			options:  []service.Option{service.WithAccessLogPolicy(service.AccessLogPolicy{IncludeHealthChecks: true})},
+			target:   service.LivenessPath,
+			expected: map[string]any{"msg": "GET /livez 200", "route": service.LivenessPath},
+		},
+		{
+			name:    "disabled",
+			options: []service.Option{service.WithAccessLogPolicy(service.AccessLogPolicy{Disabled: true})},
+			target:  "/orders/42",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, output := newAccessLogHarness(t, test.options...)
+			r := httptest.NewRequest("GET", test.target, nil)
+			r.Header.Set("User-Agent", "orders-cli/1.0")
+			r.Header.Set("X-Request-ID", "request-1")
+			r.RemoteAddr = "192.0.2.1:4711"
+			h.Do(r)
+
+			entries := accessLogEntries(t, output)
+			if test.expected == nil {
+				if len(entries) != 0 {
+					t.Errorf("expected the request not to be logged, got %v", entries)
+				}
+				return
+			}
+			if len(entries) != 1 {
+				t.Fatalf("expected the request to be logged once, got %v", entries)
+			}
+			for key, expected := range test.expected {
+				if fields, ok := expected.(map[string]any); ok {
+					actual, _ := entries[0][key].(map[string]any)
+					for field, value := range fields {
+						if actual[field] != value {
+							t.Errorf("expected %s.%s to be %v, got %v", key, field, value, actual[field])
+						}
+					}
+				} else if entries[0][key] != expected {
+					t.Errorf("expected %s to be %v, got %v", key, expected, entries[0][key])
+				}
+			}
+			if latency, _ := entries[0]["httpRequest"].(map[string]any)["latency"].(float64); latency <= 0 {
+				t.Errorf("expected the latency to be recorded, got %v", entries[0]["httpRequest"])
+			}
+		})
+	}
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	tests := []struct {
+		name       string
+		sampleRate float64
+		target     string
+		min, max   int // min and max are the bounds of the number of the 1000 requests logged
+	}{
+		{name: "every request by default", target: "/orders/1", min: 1000, max: 1000},
+		{name: "sampled", sampleRate: 0.5, target: "/orders/1", min: 400, max: 600},
+		{name: "out of range", sampleRate: 1.5, target: "/orders/1", min: 1000, max: 1000},
+		{name: "server errors are always logged", sampleRate: 0.001, target: "/unavailable", min: 1000, max: 1000},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, output := newAccessLogHarness(t, service.WithAccessLogPolicy(service.AccessLogPolicy{SampleRate: test.sampleRate}))
+			for range 1000 {
+				h.Do(httptest.NewRequest("GET", test.target, nil))
+			}
+			if logged := len(accessLogEntries(t, output)); logged < test.min || logged > test.max {
+				t.Errorf("expected between %d and %d requests to be logged, got %d", test.min, test.max, logged)
+			}
+		})
+	}
+}
+
+func TestRemoteIP(t *testing.T) {
+	tests := []struct {
+		name      string
+		proxies   *int
+		forwarded []string
+		expected  string
+	}{
+		{name: "connection", expected: "192.0.2.1"},
+		{name: "appended by the proxy", forwarded: []string{"203.0.113.7"}, expected: "203.0.113.7"},
+		{name: "spoofed by the client", forwarded: []string{"10.0.0.1, 203.0.113.7"}, expected: "203.0.113.7"},
+		{name: "several headers", forwarded: []string{"10.0.0.1", "203.0.113.7"}, expected: "203.0.113.7"},
+		{name: "two proxies", proxies: ptr(2), forwarded: []string{"10.0.0.1, 203.0.113.7, 198.51.100.2"}, expected: "203.0.113.7"},
+		{name: "fewer entries than proxies", proxies: ptr(3), forwarded: []string{"203.0.113.7, 198.51.100.2"}, expected: "203.0.113.7"},
+		{name: "no trusted proxies", proxies: ptr(0), forwarded: []string{"203.0.113.7"}, expected: "192.0.2.1"},
+		{name: "empty entry", forwarded: []string{"203.0.113.7, "}, expected: "192.0.2.1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var options []service.Option
+			if test.proxies != nil {
+				options = append(options, service.WithTrustedProxies(*test.proxies))
+			}
+			h, output := newAccessLogHarness(t, options...)
+			r := httptest.NewRequest("GET", "/orders/42", nil)
+			r.RemoteAddr = "192.0.2.1:4711"
+			for _, forwarded := range test.forwarded {
+				r.Header.Add("X-Forwarded-For", forwarded)
+			}
+			h.Do(r)
+
+			// The address is recorded in the access log, and on the request's span
+			entries := accessLogEntries(t, output)
+			if len(entries) != 1 {
+				t.Fatalf("expected the request to be logged once, got %v", entries)
+			}
+			if ip := entries[0]["httpRequest"].(map[string]any)["remoteIp"]; ip != test.expected {
+				t.Errorf("expected the remote IP %s to be logged, got %v", test.expected, ip)
+			}
+			spans := h.Spans.GetSpans()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+			for _, attr := range spans[0].Attributes {
+				if attr.Key == "client.address" && attr.Value.AsString() != test.expected {
+					t.Errorf("expected the span's client address to be %s, got %s", test.expected, attr.Value.AsString())
+				}
+			}
+		})
+	}
+}
+
+// newAccessLogHarness returns a harness for a service with the options, serving the endpoints the
+// access log tests request, and the buffer the service logs to.
+func newAccessLogHarness(t *testing.T, options ...service.Option) (*servicetest.Harness, *lockedBuffer) {
+	t.Helper()
+	var output lockedBuffer
+	h := servicetest.New(t, servicetest.Config{
+		Logger:  slog.New(slog.NewJSONHandler(&output, nil)),
+		Options: options,
+	})
+	endpoints := []struct {
+		path     string
+		response *service.HTTPResponse
+		options  []service.EndpointOption
+	}{
+		{path: "/orders/:id", response: service.Text(http.StatusOK, "order 42")},
+		{path: "/unavailable", response: service.Text(http.StatusServiceUnavailable, "unavailable")},
+		{path: "/ping", response: service.Text(http.StatusOK, "pong"), options: []service.EndpointOption{service.WithoutAccessLog()}},
+	}
+	for _, endpoint := range endpoints {
+		err := h.Service.AddPublicEndpoint("GET", endpoint.path, func(ctx context.Context, s *service.Service, r *http.Request) *service.HTTPResponse {
+			return endpoint.response
+		}, endpoint.options...)
+		if err != nil {
+			t.Fatalf("failed to add endpoint: %s", err.Error())
+		}
+	}
+	return h, &output
+}
+
+// accessLogEntries returns the access log entries in the output, which are the entries with an
+// httpRequest attribute.
+func accessLogEntries(t *testing.T, output *lockedBuffer) []map[string]any {
+	t.Helper()
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("failed to parse entry %q: %s", line, err.Error())
+		}
+		if _, ok := entry["httpRequest"]; ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// ptr returns a pointer to the value.
+func ptr[T any](value T) *T {
+	return &value
+}
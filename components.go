@@ -0,0 +1,359 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Names of the components the service registers itself. Components added with AddComponent
+// can depend on these by name.
+const (
+	ComponentAuth         = "auth"
+	ComponentCloudSQL     = "cloud-sql"
+	ComponentCloudStorage = "cloud-storage"
+	ComponentCloudTasks   = "cloud-tasks"
+	ComponentIAMClient    = "iam-client"
+	ComponentPubSub       = "pubsub"
+	ComponentRouter       = "router"
+	ComponentTracing      = "tracing"
+)
+
+// AddComponent registers a component with the service. Components added after New are set up
+// when Run is called, before the service begins accepting requests, and are torn down when the
+// service terminates. A component may depend on any other registered component, including the
+// components the service registers itself (e.g., ComponentCloudSQL).
+//
+// The service's own components that don't need setting up, such as the auth component registered
+// by SetAuthProvider, are treated as set up as soon as they're added, so readiness reflects their
+// health even if they're added after New.
+func (s *Service) AddComponent(component Component) error {
+
+	// Validate the component
+	if component == nil {
+		return errors.New("component is nil")
+	}
+	name := strings.TrimSpace(component.Name())
+	if name == "" {
+		return errors.New("component name is empty")
+	}
+
+	// Register the component, ensuring its name is unique
+	s.internal.components.mux.Lock()
+	defer s.internal.components.mux.Unlock()
+	if _, exists := s.internal.components.byName[name]; exists {
+		return fmt.Errorf("a component named '%s' is already registered", name)
+	}
+	s.internal.components.byName[name] = component
+	s.internal.components.order = append(s.internal.components.order, component)
+	if builtin, ok := component.(*builtinComponent); ok && builtin.setup == nil {
+		s.internal.components.setUp[name] = true
+	}
+	return nil
+}
+
+// builtinComponent adapts the setup and teardown functions of the components
+// the service provides itself to the Component interface.
+type builtinComponent struct {
+	name      string
+	dependsOn []string
+	setup     func() error
+	teardown  func(ctx context.Context) error
+	health    func(ctx context.Context) error
+}
+
+func (c *builtinComponent) Name() string        { return c.name }
+func (c *builtinComponent) DependsOn() []string { return c.dependsOn }
+
+func (c *builtinComponent) Setup(ctx context.Context) error {
+	if c.setup == nil {
+		return nil
+	}
+	return c.setup()
+}
+
+func (c *builtinComponent) Teardown(ctx context.Context) error {
+	if c.teardown == nil {
+		return nil
+	}
+	return c.teardown(ctx)
+}
+
+func (c *builtinComponent) CheckHealth(ctx context.Context) error {
+	if c.health == nil {
+		return nil
+	}
+	return c.health(ctx)
+}
+
+// componentRegistry tracks the components registered with the service and which of them
+// have been set up.
+type componentRegistry struct {
+	byName map[string]Component // Registered components keyed by name
+	mux    sync.Mutex           // Protects the registry
+	order  []Component          // Registered components in registration order
+	setUp  map[string]bool      // Names of the components that have been successfully set up
+}
+
+// newComponentRegistry returns an empty component registry.
+func newComponentRegistry() *componentRegistry {
+	return &componentRegistry{
+		byName: map[string]Component{},
+		setUp:  map[string]bool{},
+	}
+}
+
+// setupComponents sets up every registered component that hasn't been set up yet. Components
+// are set up concurrently, except that a component is only set up once all of the components it
+// depends on have been set up successfully.
+func (s *Service) setupComponents(ctx context.Context) error {
+	registry := s.internal.components
+
+	// Collect the components that still need to be set up
+	registry.mux.Lock()
+	if err := registry.validate(); err != nil {
+		registry.mux.Unlock()
+		return err
+	}
+	pending := []Component{}
+	for _, component := range registry.order {
+		if !registry.setUp[component.Name()] {
+			pending = append(pending, component)
+		}
+	}
+	registry.mux.Unlock()
+
+	// A component has to wait for the dependencies that are also pending
+	prerequisites := map[string][]string{}
+	isPending := map[string]bool{}
+	for _, component := range pending {
+		isPending[component.Name()] = true
+	}
+	for _, component := range pending {
+		for _, dependency := range component.DependsOn() {
+			if isPending[dependency] {
+				prerequisites[component.Name()] = append(prerequisites[component.Name()], dependency)
+			}
+		}
+	}
+
+	results := runInDependencyOrder(ctx, pending, prerequisites, true, func(ctx context.Context, c Component) error {
+		if err := c.Setup(ctx); err != nil {
+			return fmt.Errorf("failed to set up %s: %w", c.Name(), err)
+		}
+		registry.mux.Lock()
+		registry.setUp[c.Name()] = true
+		registry.mux.Unlock()
+		return nil
+	})
+
+	// Join the errors of every component that failed to set up
+	var errs []error
+	for _, result := range results {
+		if result.err != nil {
+			errs = append(errs, result.err)
+		} else if !result.completed {
+			errs = append(errs, fmt.Errorf("timed out setting up %s: %w", result.name, ctx.Err()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// teardownComponents tears down every component that has been set up and is selected by include,
+// in reverse dependency order. A component is only torn down once every selected component that
+// depends on it has been torn down, even if tearing down a dependent failed. It returns the outcome
+// of tearing down each component, including those that didn't finish before the context expired.
+func (s *Service) teardownComponents(ctx context.Context, include func(Component) bool) []ComponentShutdown {
+	registry := s.internal.components
+
+	// Collect the components that need to be torn down
+	registry.mux.Lock()
+	active := []Component{}
+	isActive := map[string]bool{}
+	for _, component := range registry.order {
+		if registry.setUp[component.Name()] && include(component) {
+			active = append(active, component)
+			isActive[component.Name()] = true
+		}
+	}
+	registry.mux.Unlock()
+
+	// A component has to wait for the components that depend on it
+	prerequisites := map[string][]string{}
+	for _, component := range active {
+		for _, dependency := range component.DependsOn() {
+			if isActive[dependency] {
+				prerequisites[dependency] = append(prerequisites[dependency], component.Name())
+			}
+		}
+	}
+
+	results := runInDependencyOrder(ctx, active, prerequisites, false, func(ctx context.Context, c Component) error {
+		registry.mux.Lock()
+		delete(registry.setUp, c.Name())
+		registry.mux.Unlock()
+		return c.Teardown(ctx)
+	})
+
+	// Describe the outcome of tearing down each component
+	shutdowns := make([]ComponentShutdown, 0, len(results))
+	for _, result := range results {
+		shutdown := ComponentShutdown{
+			Name:     result.name,
+			Duration: result.duration,
+			Error:    result.err,
+		}
+		if !result.completed {
+			shutdown.TimedOut = true
+			shutdown.Error = ctx.Err()
+		}
+		shutdowns = append(shutdowns, shutdown)
+	}
+	return shutdowns
+}
+
+// validate ensures every dependency refers to a registered component and that the
+// dependencies don't form a cycle. The caller must hold the registry's lock.
+func (r *componentRegistry) validate() error {
+
+	// Confirm every dependency exists
+	for _, component := range r.order {
+		for _, dependency := range component.DependsOn() {
+			if _, exists := r.byName[dependency]; !exists {
+				return fmt.Errorf("component '%s' depends on '%s', which is not registered", component.Name(), dependency)
+			}
+		}
+	}
+
+	// Detect cycles with a depth-first search
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("components have a circular dependency: %s", strings.Join(append(path, name), " -> "))
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dependency := range r.byName[name].DependsOn() {
+			if err := visit(dependency, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	for _, component := range r.order {
+		if err := visit(component.Name(), nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// componentResult describes the outcome of calling a function for a single component.
+type componentResult struct {
+	name      string        // Name of the component
+	err       error         // Error returned for the component, if any
+	duration  time.Duration // Time taken for the call to complete
+	completed bool          // Whether the call completed before the context was done
+}
+
+// runInDependencyOrder calls fn for every component concurrently, except that fn is only called
+// for a component once it has returned for each of the component's prerequisites. When
+// requireSuccess is true, a component whose prerequisite failed is skipped. It returns the result
+// for each component, in the order the components were provided, once every call has completed or
+// the context is done. Calls that hadn't completed when the context was done are left running.
+func runInDependencyOrder(ctx context.Context, components []Component, prerequisites map[string][]string, requireSuccess bool, fn func(context.Context, Component) error) []componentResult {
+
+	// Create a channel for each component that is closed once fn has returned for it
+	done := make(map[string]chan struct{}, len(components))
+	for _, component := range components {
+		done[component.Name()] = make(chan struct{})
+	}
+
+	var mux sync.Mutex
+	failed := map[string]bool{}
+	results := make([]componentResult, len(components))
+	for i, component := range components {
+		results[i].name = component.Name()
+	}
+
+	// Launch a goroutine for each component, which waits for its prerequisites before calling fn
+	for i, component := range components {
+		go func(i int, c Component) {
+			defer close(done[c.Name()])
+			for _, prerequisite := range prerequisites[c.Name()] {
+				select {
+				case <-done[prerequisite]:
+				case <-ctx.Done():
+					return
+				}
+				mux.Lock()
+				prerequisiteFailed := failed[prerequisite]
+				mux.Unlock()
+				if requireSuccess && prerequisiteFailed {
+					mux.Lock()
+					failed[c.Name()] = true
+					results[i].err = fmt.Errorf("skipped %s because %s failed", c.Name(), prerequisite)
+					results[i].completed = true
+					mux.Unlock()
+					return
+				}
+			}
+			start := time.Now()
+			err := fn(ctx, c)
+			mux.Lock()
+			defer mux.Unlock()
+			failed[c.Name()] = err != nil
+			results[i].err = err
+			results[i].duration = time.Since(start)
+			results[i].completed = true
+		}(i, component)
+	}
+
+	// Wait for every component, or for the context to be done
+	for _, component := range components {
+		select {
+		case <-done[component.Name()]:
+		case <-ctx.Done():
+		}
+	}
+
+	// Copy the results, as calls that haven't completed may still update them
+	mux.Lock()
+	defer mux.Unlock()
+	return append([]componentResult{}, results...)
+}
@@ -0,0 +1,190 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/albeebe/service"
+	"github.com/albeebe/service/servicetest"
+)
+
+func TestComponents(t *testing.T) {
+	tests := []struct {
+		name       string
+		components []component
+		err        string      // err is part of the error the service terminates with, if it fails to start
+		setUp      [][2]string // setUp are pairs of components, the first of which must be set up before the second
+		tornDown   [][2]string // tornDown are pairs of components, the first of which must be torn down before the second
+	}{
+		{
+			name: "dependency order",
+			components: []component{
+				{name: "worker", dependsOn: []string{"cache", service.ComponentRouter}},
+				{name: "cache", dependsOn: []string{"db"}},
+				{name: "db"},
+				{name: "metrics"},
+			},
+			setUp:    [][2]string{{"db", "cache"}, {"cache", "worker"}},
+			tornDown: [][2]string{{"worker", "cache"}, {"cache", "db"}},
+		},
+		{
+			name: "cycle",
+			components: []component{
+				{name: "a", dependsOn: []string{"b"}},
+				{name: "b", dependsOn: []string{"c"}},
+				{name: "c", dependsOn: []string{"a"}},
+			},
+			err: "circular dependency",
+		},
+		{
+			name:       "missing dependency",
+			components: []component{{name: "a", dependsOn: []string{"missing"}}},
+			err:        "depends on 'missing', which is not registered",
+		},
+		{
+			name: "failed dependency",
+			components: []component{
+				{name: "a", err: errors.New("connection refused")},
+				{name: "b", dependsOn: []string{"a"}},
+			},
+			err: "failed to set up a: connection refused",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := servicetest.New(t, servicetest.Config{})
+			events := &events{}
+			for i := range test.components {
+				test.components[i].events = events
+				if err := h.Service.AddComponent(&test.components[i]); err != nil {
+					t.Fatalf("failed to add component: %s", err.Error())
+				}
+			}
+			if err := h.Service.AddComponent(&component{name: test.components[0].name}); err == nil {
+				t.Error("expected a component with a duplicate name to be rejected")
+			}
+
+			// Run the service until it's running, or until it fails to start
+			var terminating error
+			running := make(chan struct{})
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				h.Service.Run(service.State{
+					Running:     func() { close(running) },
+					Terminating: func(err error) { terminating = err },
+				})
+			}()
+			select {
+			case <-running:
+				h.Service.Shutdown()
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("expected the service to start")
+			}
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("expected the service to terminate")
+			}
+
+			if test.err != "" {
+				if terminating == nil || !strings.Contains(terminating.Error(), test.err) {
+					t.Errorf("expected an error containing %q, got %v", test.err, terminating)
+				}
+				if slices.Contains(events.names("setup"), "b") {
+					t.Errorf("expected b not to be set up, got %v", events.names("setup"))
+				}
+				return
+			}
+			setUp, tornDown := events.names("setup"), events.names("teardown")
+			if len(setUp) != len(test.components) || len(tornDown) != len(test.components) {
+				t.Fatalf("expected every component to be set up and torn down, got %v and %v", setUp, tornDown)
+			}
+			for _, pair := range test.setUp {
+				if slices.Index(setUp, pair[0]) > slices.Index(setUp, pair[1]) {
+					t.Errorf("expected %s to be set up before %s, got %v", pair[0], pair[1], setUp)
+				}
+			}
+			for _, pair := range test.tornDown {
+				if slices.Index(tornDown, pair[0]) > slices.Index(tornDown, pair[1]) {
+					t.Errorf("expected %s to be torn down before %s, got %v", pair[0], pair[1], tornDown)
+				}
+			}
+		})
+	}
+}
+
+// component is a Component recording when it's set up and torn down.
+type component struct {
+	dependsOn []string // dependsOn are the names of the components it depends on.
+	err       error    // err is returned by Setup.
+	events    *events  // events records when it's set up and torn down.
+	name      string   // name is the name of the component.
+}
+
+func (c *component) Name() string        { return c.name }
+func (c *component) DependsOn() []string { return c.dependsOn }
+
+func (c *component) Setup(ctx context.Context) error {
+	c.events.add("setup", c.name)
+	return c.err
+}
+
+func (c *component) Teardown(ctx context.Context) error {
+	c.events.add("teardown", c.name)
+	return nil
+}
+
+// events records the events of components, in the order they happened.
+type events struct {
+	mux    sync.Mutex  // mux protects events.
+	events [][2]string // events are the events, paired with the names of their components.
+}
+
+// add records the event of the component.
+func (e *events) add(event, name string) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	e.events = append(e.events, [2]string{event, name})
+}
+
+// names returns the names of the components with the event, in the order they happened.
+func (e *events) names(event string) []string {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	var names []string
+	for _, recorded := range e.events {
+		if recorded[0] == event {
+			names = append(names, recorded[1])
+		}
+	}
+	return names
+}
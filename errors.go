@@ -0,0 +1,146 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Codes identifying the errors generated by the service. Handlers are free to use their own codes.
+const (
+	CodeForbidden        = "forbidden"
+	CodeInternal         = "internal"
+	CodeInvalidRequest   = "invalid_request"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeNotFound         = "not_found"
+	CodeNotImplemented   = "not_implemented"
+	CodeUnauthorized     = "unauthorized"
+)
+
+// ProblemContentType is the content type of the RFC 7807 problem details used for error responses.
+const ProblemContentType = "application/problem+json"
+
+// HTTPError is an error that's sent to the client as an RFC 7807 problem details response.
+// Handlers can return one using its Response method, or with Problem, and typed endpoint
+// handlers can return one as their error.
+//
+// Example:
+//
+//	return service.NewHTTPError(http.StatusNotFound, "order_not_found", "order %s does not exist", id).Response()
+type HTTPError struct {
+	Status    int    // HTTP status code (e.g. 404)
+	Code      string // Machine readable code identifying the error (e.g. "order_not_found")
+	Message   string // Human readable description of this occurrence of the error
+	Details   any    // Additional information about the error, encoded as JSON (e.g. the fields that failed validation)
+	Retryable bool   // Whether the client can expect the request to succeed if it's retried later
+}
+
+// NewHTTPError creates an HTTPError with the status, code and message. The message is formatted
+// with the arguments, if any are provided.
+func NewHTTPError(status int, code, message string, args ...any) *HTTPError {
+	if len(args) > 0 {
+		message = fmt.Sprintf(message, args...)
+	}
+	return &HTTPError{
+		Status:  status,
+		Code:    code,
+		Message: message,
+	}
+}
+
+// Error returns the error's message, or the text for its status code if it doesn't have one.
+func (e *HTTPError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return http.StatusText(e.Status)
+}
+
+// problemDetails is the JSON representation of an HTTPError, as described by RFC 7807. The
+// code, retryable and details members are extensions to the standard members.
+type problemDetails struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Code      string `json:"code,omitempty"`
+	Retryable bool   `json:"retryable"`
+	Details   any    `json:"details,omitempty"`
+}
+
+// Response returns an HTTPResponse containing the error as an application/problem+json document.
+// A status outside of the 400-599 range is sent as a 500 Internal Server Error.
+func (e *HTTPError) Response() *HTTPResponse {
+	status := e.Status
+	if status < 400 || status > 599 {
+		status = http.StatusInternalServerError
+	}
+	body, err := json.Marshal(problemDetails{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    e.Message,
+		Code:      e.Code,
+		Retryable: e.Retryable,
+		Details:   e.Details,
+	})
+	if err != nil {
+		// The details couldn't be encoded, so send the error without them
+		body, _ = json.Marshal(problemDetails{
+			Type:      "about:blank",
+			Title:     http.StatusText(status),
+			Status:    status,
+			Detail:    e.Message,
+			Code:      e.Code,
+			Retryable: e.Retryable,
+		})
+	}
+	r := &HTTPResponse{
+		StatusCode: status,
+		Headers:    http.Header{},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+	}
+	r.Headers.Set("Content-Type", ProblemContentType)
+	return r
+}
+
+// Problem returns the response for an error. If err is, or wraps, an HTTPError, the HTTPError
+// is sent. Otherwise, a generic 500 Internal Server Error is sent, so that internal details
+// of the error aren't exposed to the client.
+func Problem(err error) *HTTPResponse {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Response()
+	}
+	return internalError().Response()
+}
+
+// internalError returns the HTTPError sent when an unexpected error occurs.
+func internalError() *HTTPError {
+	return NewHTTPError(http.StatusInternalServerError, CodeInternal, "internal server error")
+}
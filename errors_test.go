@@ -0,0 +1,135 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/albeebe/service"
+	"github.com/albeebe/service/servicetest"
+)
+
+func TestFrameworkErrors(t *testing.T) {
+	h := servicetest.New(t, servicetest.Config{})
+	handler := func(ctx context.Context, s *service.Service, r *http.Request) *service.HTTPResponse {
+		return service.Text(http.StatusOK, "ok")
+	}
+	if err := h.Service.AddAuthenticatedEndpoint("GET", "/orders", "orders.read", handler); err != nil {
+		t.Fatalf("failed to add endpoint: %s", err.Error())
+	}
+	if err := h.Service.AddServiceEndpoint("POST", "/internal/sync", "", handler); err != nil {
+		t.Fatalf("failed to add endpoint: %s", err.Error())
+	}
+	authorize := func(claims servicetest.Claims) func(r *http.Request) {
+		return func(r *http.Request) {
+			if err := h.Auth.Authorize(r, claims); err != nil {
+				t.Fatalf("failed to authorize request: %s", err.Error())
+			}
+		}
+	}
+
+	// Every error the service generates itself is sent as problem details
+	tests := []struct {
+		name      string
+		method    string
+		target    string
+		authorize func(r *http.Request)
+		status    int
+		code      string
+		detail    string
+	}{
+		{name: "missing token", method: "GET", target: "/orders", status: http.StatusUnauthorized, code: service.CodeUnauthorized, detail: "missing authorization header"},
+		{name: "malformed header", method: "GET", target: "/orders", authorize: func(r *http.Request) { r.Header.Set("Authorization", "Basic abc") }, status: http.StatusUnauthorized, code: service.CodeUnauthorized, detail: "malformed authorization header"},
+		{name: "missing permission", method: "GET", target: "/orders", authorize: authorize(servicetest.Claims{Subject: "user-1"}), status: http.StatusForbidden, code: service.CodeForbidden, detail: "missing required permission 'orders.read'"},
+		{name: "not a service", method: "POST", target: "/internal/sync", authorize: authorize(servicetest.Claims{Subject: "user-1"}), status: http.StatusForbidden, code: service.CodeForbidden, detail: "restricted to services"},
+		{name: "no route", method: "GET", target: "/missing", status: http.StatusNotFound, code: service.CodeNotFound, detail: "no endpoint exists for GET /missing"},
+		{name: "method not allowed", method: "DELETE", target: "/orders", status: http.StatusMethodNotAllowed, code: service.CodeMethodNotAllowed, detail: "method DELETE is not allowed for /orders"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(test.method, test.target, nil)
+			if test.authorize != nil {
+				test.authorize(r)
+			}
+			resp := h.Do(r)
+			problem := decodeProblem(t, resp)
+			if resp.StatusCode != test.status || problem.Status != test.status || problem.Code != test.code || problem.Detail != test.detail {
+				t.Errorf("expected status %d, code %q and detail %q, got %d and %+v", test.status, test.code, test.detail, resp.StatusCode, problem)
+			}
+			if problem.Type != "about:blank" || problem.Title != http.StatusText(test.status) {
+				t.Errorf("expected the type and title of the status, got %+v", problem)
+			}
+		})
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	h := servicetest.New(t, servicetest.Config{})
+	errs := map[string]error{
+		"/http-error": &service.HTTPError{Status: http.StatusServiceUnavailable, Code: "backend_unavailable", Message: "try again", Retryable: true, Details: []service.FieldError{{Field: "backend", Message: "down"}}},
+		"/wrapped":    fmt.Errorf("loading order: %w", service.NewHTTPError(http.StatusNotFound, "order_not_found", "order %s does not exist", "42")),
+		"/internal":   errors.New("connection refused to 10.0.0.1"),
+		"/no-status":  service.NewHTTPError(http.StatusOK, "odd", "not an error"),
+	}
+	for path, err := range errs {
+		if err := h.Service.AddPublicEndpoint("GET", path, func(ctx context.Context, s *service.Service, r *http.Request) *service.HTTPResponse {
+			return service.Problem(err)
+		}); err != nil {
+			t.Fatalf("failed to add endpoint: %s", err.Error())
+		}
+	}
+
+	tests := []struct {
+		target    string
+		status    int
+		code      string
+		detail    string
+		retryable bool
+	}{
+		{target: "/http-error", status: http.StatusServiceUnavailable, code: "backend_unavailable", detail: "try again", retryable: true},
+		{target: "/wrapped", status: http.StatusNotFound, code: "order_not_found", detail: "order 42 does not exist"},
+		{target: "/internal", status: http.StatusInternalServerError, code: service.CodeInternal, detail: "internal server error"},
+		{target: "/no-status", status: http.StatusInternalServerError, code: "odd", detail: "not an error"},
+	}
+	for _, test := range tests {
+		t.Run(strings.TrimPrefix(test.target, "/"), func(t *testing.T) {
+			resp := h.Do(httptest.NewRequest("GET", test.target, nil))
+			problem := decodeProblem(t, resp)
+			if resp.StatusCode != test.status || problem.Code != test.code || problem.Detail != test.detail || problem.Retryable != test.retryable {
+				t.Errorf("expected status %d, code %q, detail %q and retryable %t, got %d and %+v", test.status, test.code, test.detail, test.retryable, resp.StatusCode, problem)
+			}
+		})
+	}
+
+	// The details are sent as they were given
+	resp := h.Do(httptest.NewRequest("GET", "/http-error", nil))
+	if problem := decodeProblem(t, resp); len(problem.Details) != 1 || problem.Details[0].Field != "backend" {
+		t.Errorf("expected the details to be sent, got %+v", problem.Details)
+	}
+}
@@ -0,0 +1,278 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Access is the minimum level of authentication required by the endpoints in a Group.
+type Access int
+
+const (
+	AccessPublic        Access = iota // Endpoints don't require authentication
+	AccessAuthenticated               // Endpoints require an authenticated request
+	AccessService                     // Endpoints require an authenticated request from a service
+)
+
+// Group registers endpoints that share a path prefix, and optionally a default permission,
+// middleware and a minimum level of authentication. Groups are created with Service.Group,
+// and can be nested with Group.Group.
+type Group struct {
+	access     Access
+	err        error // Set when the group's prefix is invalid, and returned when endpoints are added
+	middleware []Middleware
+	permission string
+	prefix     string
+	service    *Service
+}
+
+// GroupOption configures a Group when it's created.
+type GroupOption func(*Group)
+
+// WithAccess sets the minimum level of authentication required by the group's public,
+// authenticated and service endpoints. Endpoints are upgraded to the group's level, so a public
+// endpoint added to a group with AccessAuthenticated is registered as an authenticated endpoint,
+// and an authenticated endpoint added to a group with AccessService is registered as a service endpoint.
+// A nested group can raise its parent's level, but not lower it.
+func WithAccess(access Access) GroupOption {
+	return func(g *Group) {
+		if access > g.access {
+			g.access = access
+		}
+	}
+}
+
+// WithDefaultPermission sets the permission required by the group's authenticated and service
+// endpoints when they're added without one.
+func WithDefaultPermission(permission string) GroupOption {
+	return func(g *Group) {
+		g.permission = strings.TrimSpace(permission)
+	}
+}
+
+// WithGroupMiddleware adds middleware to every endpoint in the group. The middleware runs inside
+// any middleware added to the service with Use, and outside any middleware added to the endpoint.
+func WithGroupMiddleware(middleware ...Middleware) GroupOption {
+	return func(g *Group) {
+		g.middleware = append(g.middleware, middleware...)
+	}
+}
+
+// Group creates a group of endpoints whose paths all begin with the prefix (e.g. "/v1/orders").
+//
+// Example:
+//
+//	orders := s.Group("/v1/orders", service.WithAccess(service.AccessAuthenticated), service.WithDefaultPermission("orders.read"))
+//	orders.AddPublicEndpoint("GET", "/:id", endpoints.GetOrder)                        // Requires authentication and "orders.read"
+//	orders.AddAuthenticatedEndpoint("POST", "", "orders.write", endpoints.CreateOrder) // Requires authentication and "orders.write"
+func (s *Service) Group(prefix string, opts ...GroupOption) *Group {
+	g := &Group{
+		prefix:  strings.TrimSpace(prefix),
+		service: s,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// Group creates a nested group whose prefix is appended to this group's prefix. The nested group
+// inherits this group's access level, default permission and middleware, any of which can be
+// extended or overridden by the options.
+func (g *Group) Group(prefix string, opts ...GroupOption) *Group {
+	nested := &Group{
+		access:     g.access,
+		err:        g.err,
+		middleware: append([]Middleware{}, g.middleware...),
+		permission: g.permission,
+		prefix:     g.prefix,
+		service:    g.service,
+	}
+	if nested.err == nil {
+		nested.prefix, nested.err = joinPaths(g.prefix, strings.TrimSpace(prefix))
+		if nested.err != nil {
+			g.service.internal.routes.record(nested.err)
+		}
+	}
+	for _, opt := range opts {
+		opt(nested)
+	}
+	return nested
+}
+
+// AddPublicEndpoint registers an endpoint at the relativePath within the group. The endpoint is
+// public unless the group requires a higher level of access, in which case it's registered as an
+// authenticated or service endpoint requiring the group's default permission.
+func (g *Group) AddPublicEndpoint(method, relativePath string, handler EndpointHandler, opts ...EndpointOption) error {
+	fullPath, err := g.path(relativePath)
+	if err != nil {
+		return err
+	}
+	switch g.access {
+	case AccessService:
+		return g.service.AddServiceEndpoint(method, fullPath, g.permission, handler, g.options(opts)...)
+	case AccessAuthenticated:
+		return g.service.AddAuthenticatedEndpoint(method, fullPath, g.permission, handler, g.options(opts)...)
+	default:
+		return g.service.AddPublicEndpoint(method, fullPath, handler, g.options(opts)...)
+	}
+}
+
+// AddAuthenticatedEndpoint registers an authenticated endpoint at the relativePath within the group.
+// If no permission is specified, the group's default permission is required. If the group requires
+// AccessService, the endpoint is registered as a service endpoint.
+func (g *Group) AddAuthenticatedEndpoint(method, relativePath, permission string, handler EndpointHandler, opts ...EndpointOption) error {
+	fullPath, err := g.path(relativePath)
+	if err != nil {
+		return err
+	}
+	if g.access == AccessService {
+		return g.service.AddServiceEndpoint(method, fullPath, g.permissionOrDefault(permission), handler, g.options(opts)...)
+	}
+	return g.service.AddAuthenticatedEndpoint(method, fullPath, g.permissionOrDefault(permission), handler, g.options(opts)...)
+}
+
+// AddServiceEndpoint registers a service endpoint at the relativePath within the group. If no
+// permission is specified, the group's default permission is required.
+func (g *Group) AddServiceEndpoint(method, relativePath, permission string, handler EndpointHandler, opts ...EndpointOption) error {
+	fullPath, err := g.path(relativePath)
+	if err != nil {
+		return err
+	}
+	return g.service.AddServiceEndpoint(method, fullPath, g.permissionOrDefault(permission), handler, g.options(opts)...)
+}
+
+// AddCloudTaskEndpoint registers a Cloud Task endpoint at the relativePath within the group. The
+// request is verified as coming from Google, so the group's access level and default permission
+// don't apply.
+func (g *Group) AddCloudTaskEndpoint(relativePath string, handler EndpointHandler, opts ...EndpointOption) error {
+	fullPath, err := g.path(relativePath)
+	if err != nil {
+		return err
+	}
+	return g.service.AddCloudTaskEndpoint(fullPath, handler, g.options(opts)...)
+}
+
+// AddCloudSchedulerEndpoint registers a Cloud Scheduler endpoint at the relativePath within the
+// group. The request is verified as coming from Google, so the group's access level and default
+// permission don't apply.
+func (g *Group) AddCloudSchedulerEndpoint(relativePath string, handler EndpointHandler, opts ...EndpointOption) error {
+	fullPath, err := g.path(relativePath)
+	if err != nil {
+		return err
+	}
+	return g.service.AddCloudSchedulerEndpoint(fullPath, handler, g.options(opts)...)
+}
+
+// AddCloudWorkflowEndpoint registers a Cloud Workflow endpoint at the relativePath within the
+// group. The request is verified as coming from Google, so the group's access level and default
+// permission don't apply.
+func (g *Group) AddCloudWorkflowEndpoint(relativePath string, handler EndpointHandler, opts ...EndpointOption) error {
+	fullPath, err := g.path(relativePath)
+	if err != nil {
+		return err
+	}
+	return g.service.AddCloudWorkflowEndpoint(fullPath, handler, g.options(opts)...)
+}
+
+// AddPubSubEndpoint registers a Pub/Sub endpoint at the relativePath within the group. The
+// request is verified as coming from Google, so the group's access level and default permission
+// don't apply.
+func (g *Group) AddPubSubEndpoint(relativePath string, handler EndpointHandler, opts ...EndpointOption) error {
+	fullPath, err := g.path(relativePath)
+	if err != nil {
+		return err
+	}
+	return g.service.AddPubSubEndpoint(fullPath, handler, g.options(opts)...)
+}
+
+// AddWebsocketEndpoint registers a websocket endpoint at the relativePath within the group.
+// Websocket endpoints don't support authentication or middleware, so an error is returned if the
+// group requires authentication.
+func (g *Group) AddWebsocketEndpoint(relativePath string, handler WebsocketHandler) error {
+	fullPath, err := g.path(relativePath)
+	if err != nil {
+		return err
+	}
+	if g.access != AccessPublic {
+		err := fmt.Errorf("websocket endpoint GET %s can't be added to a group that requires authentication", fullPath)
+		g.service.internal.routes.record(err)
+		return err
+	}
+	return g.service.AddWebsocketEndpoint(fullPath, handler)
+}
+
+// path returns the relativePath prefixed with the group's prefix. An error is returned, and
+// recorded so that the service fails to run, if the group's prefix is invalid or the relativePath
+// would escape it.
+func (g *Group) path(relativePath string) (string, error) {
+	if g.err != nil {
+		return "", g.err
+	}
+	joined, err := joinPaths(g.prefix, strings.TrimSpace(relativePath))
+	if err != nil {
+		g.service.internal.routes.record(err)
+		return "", err
+	}
+	return joined, nil
+}
+
+// permissionOrDefault returns the permission, or the group's default permission if it's empty.
+func (g *Group) permissionOrDefault(permission string) string {
+	if strings.TrimSpace(permission) == "" {
+		return g.permission
+	}
+	return permission
+}
+
+// options returns the endpoint's options, preceded by the group's middleware so that it runs
+// outside of the endpoint's own middleware.
+func (g *Group) options(opts []EndpointOption) []EndpointOption {
+	if len(g.middleware) == 0 {
+		return opts
+	}
+	return append([]EndpointOption{WithMiddleware(g.middleware...)}, opts...)
+}
+
+// joinPaths appends the relativePath to the prefix, preserving a trailing slash on the
+// relativePath, since the router treats "/orders" and "/orders/" as different paths. An error is
+// returned if the relativePath contains a ".." segment, since joining it would remove part of the
+// prefix, letting an endpoint escape its group.
+func joinPaths(prefix, relativePath string) (string, error) {
+	if relativePath == "" {
+		return prefix, nil
+	}
+	for _, segment := range strings.Split(relativePath, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("path %s can't be added to the group %s, as it contains \"..\"", relativePath, prefix)
+		}
+	}
+	joined := path.Join(prefix, relativePath)
+	if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(joined, "/") {
+		joined += "/"
+	}
+	return joined, nil
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/albeebe/service"
+	"github.com/albeebe/service/servicetest"
+)
+
+func TestGroupRejectsEscapingPaths(t *testing.T) {
+	h := servicetest.New(t, servicetest.Config{})
+	handler := func(ctx context.Context, s *service.Service, r *http.Request) *service.HTTPResponse {
+		return service.Text(http.StatusOK, "ok")
+	}
+
+	admin := h.Service.Group("/admin", service.WithAccess(service.AccessAuthenticated))
+	if err := admin.AddPublicEndpoint("GET", "../public", handler); err == nil {
+		t.Error("expected an error adding a path that escapes the group")
+	}
+	if err := admin.Group("/reports/..").AddPublicEndpoint("GET", "/", handler); err == nil {
+		t.Error("expected an error adding a path to a nested group that escapes its parent")
+	}
+	if err := admin.AddPublicEndpoint("GET", "/users/", handler); err != nil {
+		t.Fatalf("failed to add endpoint: %s", err.Error())
+	}
+
+	// The escaping path must not have been registered as a public endpoint
+	if resp := h.Do(httptest.NewRequest("GET", "/public", nil)); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+	if resp := h.Do(httptest.NewRequest("GET", "/admin/users/", nil)); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	routes, err := h.Service.Routes()
+	if err == nil {
+		t.Error("expected the rejected paths to be reported by Routes")
+	}
+	for _, route := range routes {
+		if route.Path == "/public" || route.Path == "/" {
+			t.Errorf("expected %s not to be registered", route.Path)
+		}
+	}
+}
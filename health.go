@@ -0,0 +1,177 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/albeebe/service/pkg/router"
+)
+
+// Paths of the health endpoints the service serves automatically.
+const (
+	LivenessPath  = "/livez"  // Reports whether the process is alive
+	ReadinessPath = "/readyz" // Reports whether the service is ready to receive traffic
+)
+
+// healthCheckTimeout is the maximum time a single component's health check may take.
+const healthCheckTimeout = 5 * time.Second
+
+// HealthChecker can be implemented by a Component to report its health. Components that
+// implement it are checked every time the readiness endpoint is requested.
+type HealthChecker interface {
+	// CheckHealth returns an error if the component is currently unable to do its job.
+	CheckHealth(ctx context.Context) error
+}
+
+// HealthReport is the JSON response returned by the health endpoints.
+type HealthReport struct {
+	Status     string                     `json:"status"`               // Overall status ("ok", "failing" or "terminating")
+	Components map[string]ComponentHealth `json:"components,omitempty"` // Health of each component, keyed by name
+}
+
+// ComponentHealth describes the health of a single component.
+type ComponentHealth struct {
+	Status string `json:"status"`          // Status of the component ("ok" or "failing")
+	Error  string `json:"error,omitempty"` // Reason the component is failing
+}
+
+// registerHealthEndpoints registers the liveness and readiness endpoints, so they're served
+// regardless of how the service's other endpoints are configured.
+func (s *Service) registerHealthEndpoints() error {
+
+	// The liveness endpoint only confirms the process is able to serve requests. It deliberately
+	// doesn't check any dependencies, so a failing dependency doesn't cause the process to be restarted.
+	livenessHandler := func(w http.ResponseWriter, r *http.Request) {
+		resp := JSON(http.StatusOK, HealthReport{Status: "ok"})
+		router.SendResponse(w, resp.StatusCode, resp.Headers, resp.Body)
+	}
+	if err := s.registerRoute(newRoute(EndpointHealth, "GET", LivenessPath, ""), livenessHandler); err != nil {
+		return err
+	}
+
+	// The readiness endpoint checks the health of every component, and fails as soon
+	// as the service begins terminating
+	readinessHandler := func(w http.ResponseWriter, r *http.Request) {
+		report := s.checkReadiness(r.Context())
+		statusCode := http.StatusOK
+		if report.Status != "ok" {
+			statusCode = http.StatusServiceUnavailable
+		}
+		resp := JSON(statusCode, report)
+		router.SendResponse(w, resp.StatusCode, resp.Headers, resp.Body)
+	}
+	return s.registerRoute(newRoute(EndpointHealth, "GET", ReadinessPath, ""), readinessHandler)
+}
+
+// checkReadiness checks the health of every registered component concurrently and reports whether
+// the service is ready to receive traffic. Components that have not been set up are reported as failing.
+func (s *Service) checkReadiness(ctx context.Context) HealthReport {
+
+	// Fail immediately once the service has begun terminating
+	if s.internal.terminating.Load() {
+		return HealthReport{Status: "terminating"}
+	}
+
+	// Collect the components and whether they've been set up
+	registry := s.internal.components
+	registry.mux.Lock()
+	components := append([]Component{}, registry.order...)
+	setUp := make(map[string]bool, len(registry.setUp))
+	for name, ok := range registry.setUp {
+		setUp[name] = ok
+	}
+	registry.mux.Unlock()
+
+	// Check each component concurrently
+	report := HealthReport{
+		Status:     "ok",
+		Components: map[string]ComponentHealth{},
+	}
+	var mux sync.Mutex
+	var wg sync.WaitGroup
+	for _, component := range components {
+		checker, isChecker := component.(HealthChecker)
+		if setUp[component.Name()] && !isChecker {
+			continue
+		}
+		wg.Add(1)
+		go func(c Component) {
+			defer wg.Done()
+			var err error
+			if !setUp[c.Name()] {
+				err = errors.New("component has not been set up")
+			} else {
+				checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+				defer cancel()
+				err = checker.CheckHealth(checkCtx)
+			}
+			health := ComponentHealth{Status: "ok"}
+			if err != nil {
+				health = ComponentHealth{Status: "failing", Error: err.Error()}
+			}
+			mux.Lock()
+			defer mux.Unlock()
+			report.Components[c.Name()] = health
+			if err != nil {
+				report.Status = "failing"
+			}
+		}(component)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// checkCloudSQLHealth pings the database, if one is configured.
+func (s *Service) checkCloudSQLHealth(ctx context.Context) error {
+	if s.DB == nil {
+		return nil
+	}
+	return s.DB.PingContext(ctx)
+}
+
+// checkAuthHealth confirms the keys used to validate tokens have been loaded.
+func (s *Service) checkAuthHealth(ctx context.Context) error {
+	if s.internal.auth == nil || !s.internal.auth.KeysLoaded() {
+		return errors.New("auth keys have not been loaded")
+	}
+	return nil
+}
+
+// checkRouterHealth confirms the router is accepting connections once the service is running.
+// Before Run is called the router can only be reached in-process (see ServeHTTP), so the
+// check passes.
+func (s *Service) checkRouterHealth(ctx context.Context) error {
+	if !s.internal.running.Load() {
+		return nil
+	}
+	if s.internal.router == nil || !s.internal.router.Serving() {
+		return errors.New("router is not accepting connections")
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/albeebe/service"
+	"github.com/albeebe/service/servicetest"
+)
+
+func TestReadinessBeforeRun(t *testing.T) {
+	h := servicetest.New(t, servicetest.Config{})
+
+	// The auth component is added after the other components are set up, and is ready once its keys
+	// have loaded, without the service having to run
+	resp := h.Do(httptest.NewRequest("GET", service.ReadinessPath, nil))
+	var report service.HealthReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("failed to decode the health report: %s", err.Error())
+	}
+	if resp.StatusCode != http.StatusOK || report.Status != "ok" {
+		t.Fatalf("expected status %d and %q, got %d and %+v", http.StatusOK, "ok", resp.StatusCode, report)
+	}
+	if health := report.Components[service.ComponentAuth]; health.Status != "ok" {
+		t.Errorf("expected the auth component to be ok, got %+v", health)
+	}
+}
+
+func TestReadinessOfLateComponents(t *testing.T) {
+	s, err := service.New("test-service", service.Config{Host: "localhost:0"}, service.WithoutGoogleCloud())
+	if err != nil {
+		t.Fatalf("failed to create service: %s", err.Error())
+	}
+	t.Cleanup(s.Shutdown)
+	authProvider, err := servicetest.NewAuthProvider()
+	if err != nil {
+		t.Fatalf("failed to create auth provider: %s", err.Error())
+	}
+	if err := s.SetAuthProvider(authProvider); err != nil {
+		t.Fatalf("failed to set auth provider: %s", err.Error())
+	}
+
+	// The auth component doesn't need setting up, so readiness reports its health rather than it
+	// waiting to be set up, which only happens once the service runs
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest("GET", service.ReadinessPath, nil))
+	var report service.HealthReport
+	if err := json.NewDecoder(recorder.Body).Decode(&report); err != nil {
+		t.Fatalf("failed to decode the health report: %s", err.Error())
+	}
+	expected := service.ComponentHealth{Status: "failing", Error: "auth keys have not been loaded"}
+	if health := report.Components[service.ComponentAuth]; health != expected {
+		t.Errorf("expected the auth component to be %+v, got %+v", expected, health)
+	}
+}
@@ -25,12 +25,14 @@ package service
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
-	"sync"
 	"time"
 
+	"cloud.google.com/go/cloudsqlconn"
 	"cloud.google.com/go/cloudsqlconn/mysql/mysql"
 	cloudtasks "cloud.google.com/go/cloudtasks/apiv2"
 	credentials "cloud.google.com/go/iam/credentials/apiv1"
@@ -38,80 +40,142 @@ import (
 	"github.com/albeebe/service/pkg/logger"
 	"github.com/albeebe/service/pkg/pubsub"
 	"github.com/albeebe/service/pkg/router"
+	"github.com/albeebe/service/pkg/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 	"google.golang.org/api/option"
 )
 
 // initializeLogger sets up the structured logger for the service, configuring it based on the environment.
-// In production, it uses Google Cloud Logging with the Info level to capture operational logs.
+// In production, it uses Google Cloud Logging with the Info level to capture operational logs, delivered
+// either through the API or as structured JSON written to stdout, as chosen by Config.LogDelivery.
 // In development, it defaults to a local console logger with the Debug level for more verbose output.
+// Both mask secrets, such as tokens and passwords, with the default redaction policy. A logger
+// provided with WithLogger takes precedence over both. Entries are also written to the file
+// configured with WithLogFile, if any, and are delivered asynchronously to the environment's
+// logger and the file alike when WithAsyncLogging is provided.
 func (s *Service) initializeLogger() error {
 	var err error
 
+	// Use the logger provided by the options, if any. Its level can't be changed, but the levels
+	// of the named loggers created from it can.
+	if s.internal.options.logger != nil {
+		s.Log = s.internal.options.logger
+		return s.initializeLogLevels(slog.LevelInfo)
+	}
+
 	// Choose logger configuration based on the environment
 	if runningInProduction() {
+		// Use Info level for production
+		if err := s.initializeLogLevels(slog.LevelInfo); err != nil {
+			return err
+		}
+
 		// Set up Google Cloud logging for production
-		s.Log, err = logger.NewGoogleCloudLogger(s.Context, logger.Config{
+		config := logger.Config{
 			GCPProjectID:   s.internal.config.GCPProjectID,
 			LogName:        "service-log",
-			Level:          slog.LevelInfo, // Use Info level for production
+			Level:          s.LogLevels,
 			ServiceName:    s.Name,
 			ServiceVersion: "1.0",
-		})
+			Redaction:      &logger.RedactionPolicy{},
+			ClientOptions:  s.clientOptions(),
+		}
+		if s.internal.config.LogDelivery == LogDeliveryStdout {
+			s.Log, err = logger.NewStructuredLogger(s.Context, config)
+		} else {
+			s.Log, err = logger.NewGoogleCloudLogger(s.Context, config)
+		}
 	} else {
-		// Set up development logging for non-production environments
+		// Set up development logging for non-production environments, using Debug level
+		if err := s.initializeLogLevels(slog.LevelDebug); err != nil {
+			return err
+		}
 		s.Log, err = logger.NewDevelopmentLogger(s.Context, logger.Config{
-			Level: slog.LevelDebug, // Use Debug level for development
+			Level:     s.LogLevels,
+			Redaction: &logger.RedactionPolicy{},
 		})
 	}
+	if err != nil {
+		return err
+	}
 
-	return err
+	// Also write entries to a rotating file, if one was configured
+	if s.internal.options.logFile != nil {
+		if err := s.addLogFile(*s.internal.options.logFile); err != nil {
+			return err
+		}
+	}
+
+	// Deliver the entries in the background once every destination has been added, so they share
+	// a single buffer and goroutine
+	return s.deliverLogsAsync()
 }
 
-// setup initializes various components of the service concurrently to enhance performance.
+// setup registers the components the service provides itself and sets them up concurrently
+// to enhance performance. Components disabled by the options aren't registered, and components
+// whose clients were provided by the options are registered without being set up again.
 func (s *Service) setup() error {
+	o := s.internal.options
+
+	// Use the clients provided by the options
+	s.DB = o.db
+	s.CloudStorageClient = o.cloudStorageClient
+	s.CloudTasksClient = o.cloudTasksClient
+	s.IAMClient = o.iamClient
+	if o.publisher != nil {
+		s.internal.pubsub = o.publisher
+	}
 
-	// Define the components we want to set up
-	type Component struct {
-		Name     string
-		Function func() error
+	// Define the components we want to set up. Only the clients the service creates itself
+	// are closed when it's torn down, clients provided by the options are left open.
+	cloudSQL := &builtinComponent{name: ComponentCloudSQL, setup: s.setupCloudSQL, health: s.checkCloudSQLHealth}
+	if o.db == nil {
+		cloudSQL.teardown = s.teardownCloudSQL
 	}
 	components := []Component{
-		{"Cloud SQL", s.setupCloudSQL},
-		{"Cloud Storage", s.setupCloudStorage},
-		{"Cloud Tasks", s.setupCloudTasks},
-		{"IAM Client", s.setupIAMClient},
-		{"Pub/Sub", s.setupPubSub},
-		{"Router", s.setupRouter},
+		cloudSQL,
+		&builtinComponent{name: ComponentRouter, setup: s.setupRouter, teardown: s.teardownRouter, health: s.checkRouterHealth},
+	}
+	if !o.withoutCloudStorage {
+		component := &builtinComponent{name: ComponentCloudStorage}
+		if o.createsCloudStorage() {
+			component.setup = s.setupCloudStorage
+			component.teardown = s.teardownCloudStorage
+		}
+		components = append(components, component)
+	}
+	if !o.withoutCloudTasks {
+		component := &builtinComponent{name: ComponentCloudTasks}
+		if o.createsCloudTasks() {
+			component.setup = s.setupCloudTasks
+			component.teardown = s.teardownCloudTasks
+		}
+		components = append(components, component)
+	}
+	if !o.withoutIAMClient {
+		component := &builtinComponent{name: ComponentIAMClient}
+		if o.createsIAMClient() {
+			component.setup = s.setupIAMClient
+			component.teardown = s.teardownIAMClient
+		}
+		components = append(components, component)
+	}
+	if !o.withoutPubSub {
+		component := &builtinComponent{name: ComponentPubSub}
+		if o.createsPubSub() {
+			component.setup = s.setupPubSub
+			component.teardown = s.teardownPubSub
+		}
+		components = append(components, component)
 	}
-
-	// Set up the various components concurrently to enhance performance
-	wg := sync.WaitGroup{}
-	errCh := make(chan error, len(components))
 	for _, component := range components {
-		wg.Add(1)
-		go func(c Component) {
-			defer wg.Done()
-			if err := c.Function(); err != nil {
-				errCh <- fmt.Errorf("failed to set up %s: %w", c.Name, err)
-			}
-		}(component)
-	}
-
-	// Wait for the components to finish setting up
-	go func() {
-		wg.Wait()
-		close(errCh)
-	}()
-	var finalErr error
-	for err := range errCh {
-		if err != nil {
-			if finalErr == nil {
-				finalErr = err
-			}
+		if err := s.AddComponent(component); err != nil {
+			return err
 		}
 	}
 
-	return finalErr
+	// Set up the components concurrently to enhance performance
+	return s.setupComponents(s.Context)
 }
 
 // setupCloudSQL initializes the Cloud SQL database connection using the provided configuration.
@@ -119,14 +183,20 @@ func (s *Service) setup() error {
 // database setup and returns early with no error, as Cloud SQL is considered optional.
 func (s *Service) setupCloudSQL() (err error) {
 
-	// Return early if Cloud SQL connection is not configured
-	if s.internal.config.CloudSQLConnection == "" {
+	// Return early if Cloud SQL connection is not configured, or a database was provided by the options
+	if s.internal.config.CloudSQLConnection == "" || s.internal.options.db != nil {
 		return nil
 	}
 
-	// Set up the driver
+	// Set up the driver, dialing with the service's credentials when they're available
+	var dialerOptions []cloudsqlconn.Option
+	if s.GoogleCredentials != nil {
+		dialerOptions = append(dialerOptions, cloudsqlconn.WithTokenSource(s.GoogleCredentials.TokenSource))
+	}
 	mysqlDriver := "mysql-driver"
-	_, err = mysql.RegisterDriver(mysqlDriver)
+	if _, err := mysql.RegisterDriver(mysqlDriver, dialerOptions...); err != nil {
+		return fmt.Errorf("failed to register driver: %w", err)
+	}
 
 	// Open the connection to the database
 	dsn := fmt.Sprintf("%s:@%s(%s)/%s?parseTime=true", s.internal.config.CloudSQLUser, mysqlDriver, s.internal.config.CloudSQLConnection, s.internal.config.CloudSQLDatabase)
@@ -135,6 +205,13 @@ func (s *Service) setupCloudSQL() (err error) {
 		return fmt.Errorf("failed to open connection: %w", err)
 	}
 
+	// Trace every query when tracing is enabled, by reopening the database with a traced connector
+	if s.internal.tracerProvider != nil {
+		db := s.DB
+		s.DB = tracing.OpenDB(db.Driver(), dsn, s.internal.tracerProvider, semconv.DBSystemMySQL)
+		db.Close()
+	}
+
 	// Verify the connection to the database
 	if err := s.DB.Ping(); err != nil {
 		return fmt.Errorf("failed to ping database: %w", err)
@@ -145,43 +222,58 @@ func (s *Service) setupCloudSQL() (err error) {
 
 // setupCloudStorage creates a new Cloud Storage client using the specified Google credentials.
 func (s *Service) setupCloudStorage() (err error) {
-	opts := []option.ClientOption{
-		option.WithCredentials(s.GoogleCredentials),
-	}
-	s.CloudStorageClient, err = storage.NewClient(s.Context, opts...)
+	s.CloudStorageClient, err = storage.NewClient(s.Context, s.clientOptions()...)
 	return err
 }
 
 // setupCloudTasks initializes the Cloud Tasks client for the service.
 func (s *Service) setupCloudTasks() (err error) {
-	s.CloudTasksClient, err = cloudtasks.NewClient(s.Context)
+	s.CloudTasksClient, err = cloudtasks.NewClient(s.Context, s.clientOptions()...)
 	return err
 }
 
 // setupIAMClient initializes the IAM (Identity and Access Management) client for the service.
 func (s *Service) setupIAMClient() (err error) {
-	s.IAMClient, err = credentials.NewIamCredentialsClient(s.Context)
+	s.IAMClient, err = credentials.NewIamCredentialsClient(s.Context, s.clientOptions()...)
 	return err
 }
 
 // setupPubSub creates a new Pub/Sub client for the service using the provided GCP project ID.
 func (s *Service) setupPubSub() (err error) {
 	s.internal.pubsub, err = pubsub.New(s.Context, pubsub.Config{
-		GCPProjectID: s.internal.config.GCPProjectID,
+		GCPProjectID:  s.internal.config.GCPProjectID,
+		ClientOptions: s.clientOptions(),
+		Metrics:       s.Metrics,
 	})
 	return err
 }
 
+// clientOptions returns the options used to create the Google Cloud clients, which
+// authenticate with the service's credentials when they're available.
+func (s *Service) clientOptions() []option.ClientOption {
+	if s.GoogleCredentials == nil {
+		return nil
+	}
+	return []option.ClientOption{
+		option.WithCredentials(s.GoogleCredentials),
+	}
+}
+
 // setupRouter initializes the HTTP router for the service.
 func (s *Service) setupRouter() (err error) {
 
 	noRouteHandler := func(w http.ResponseWriter, r *http.Request) {
-		sendResponse(w, http.StatusNotFound, "not found")
+		sendError(w, NewHTTPError(http.StatusNotFound, CodeNotFound, "no endpoint exists for %s %s", r.Method, r.URL.Path))
+	}
+	noMethodHandler := func(w http.ResponseWriter, r *http.Request) {
+		sendError(w, NewHTTPError(http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method %s is not allowed for %s", r.Method, r.URL.Path))
 	}
 
 	s.internal.router, err = router.NewRouter(s.Context, router.Config{
-		Host:           s.internal.config.Host,
-		NoRouteHandler: &noRouteHandler,
+		Host:            s.internal.config.Host,
+		NoMethodHandler: &noMethodHandler,
+		NoRouteHandler:  &noRouteHandler,
+		ShutdownTimeout: s.internal.options.shutdownPolicy.withDefaults().RouterTimeout,
 		Cors: &router.Cors{
 			AllowOrigins:     []string{"*"},
 			AllowMethods:     []string{"*"},
@@ -191,7 +283,23 @@ func (s *Service) setupRouter() (err error) {
 			MaxAge:           time.Hour,
 		},
 	})
-	return err
+	if err != nil {
+		return err
+	}
+
+	// Recover from panics, record metrics and the access log, and give every request an ID,
+	// accepting the ID provided by the caller if there is one. Each middleware wraps the one before
+	// it, so recovered panics are recorded with a 500 status, and logged with the request's ID.
+	s.internal.router.Use(s.recoveryMiddleware)
+	s.internal.router.Use(s.metricsMiddleware)
+	s.internal.router.Use(s.accessLogMiddleware())
+	s.internal.router.Use(requestIDMiddleware)
+
+	// Serve the liveness, readiness and metrics endpoints
+	if err := s.registerHealthEndpoints(); err != nil {
+		return err
+	}
+	return s.registerMetricsEndpoint()
 }
 
 // startAuthService starts the auth service and blocks, listening for errors
@@ -210,83 +318,79 @@ func (s *Service) startAuthService() {
 	}
 }
 
-// teardown gracefully shuts down multiple service components concurrently within a specified timeout.
-// If the process exceeds the timeout, it stops waiting and returns. Any errors encountered are collected,
-// with the first error being returned, if any.
-func (s *Service) teardown(timeout time.Duration) error {
-
-	// Define the components we want to tear down
-	type Component struct {
-		Name     string
-		Function func() error
-	}
-	components := []Component{
-		{"Router", s.teardownRouter},
-	}
-
-	// Create a context with a timeout
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-
-	wg := sync.WaitGroup{}
-	errCh := make(chan error, len(components))
-
-	// Launch the teardown process for each component in a separate goroutine
-	for _, component := range components {
-		wg.Add(1)
-		go func(c Component) {
-			defer wg.Done()
-			if err := c.Function(); err != nil {
-				select {
-				case errCh <- fmt.Errorf("failed to tear down %s: %w", c.Name, err):
-				case <-ctx.Done():
-					return
-				}
-			}
-		}(component)
+// teardown gracefully shuts down the service following its ShutdownPolicy, and reports the outcome
+// of tearing down each component. The shutdown happens in the following order:
+//
+//  1. When drain is true, requests continue to be served for the policy's DrainDelay while the
+//     readiness endpoint fails, giving load balancers time to stop routing new traffic.
+//  2. The router stops accepting connections, and waits up to the policy's RouterTimeout for
+//     in-flight requests to complete.
+//  3. The service's context is canceled, signaling background work to stop.
+//  4. The remaining components are torn down concurrently in reverse dependency order, closing every
+//     client the service created, within the policy's TeardownTimeout.
+//  5. Spans that haven't been exported yet are exported, within what remains of the TeardownTimeout.
+//  6. Any components that failed or timed out are logged, and the logger is flushed.
+func (s *Service) teardown(drain bool) ShutdownReport {
+	policy := s.internal.options.shutdownPolicy.withDefaults()
+	report := ShutdownReport{}
+
+	// Keep serving requests while load balancers observe the failing readiness endpoint. The drain
+	// is skipped if the context was canceled, as the router is already shutting down.
+	if drain && policy.DrainDelay > 0 {
+		select {
+		case <-time.After(policy.DrainDelay):
+		case <-s.Context.Done():
+		}
 	}
 
-	// Close the error channel when all goroutines are done
-	go func() {
-		wg.Wait()
-		close(errCh)
-	}()
-
-	var finalErr error
-
-	// Wait until either the timeout occurs or all components have finished tearing down, whichever happens first
-	select {
-	case <-ctx.Done():
-		return nil
-	case err := <-errCh:
-		finalErr = err
+	// Stop accepting requests before tearing anything else down
+	isRouter := func(c Component) bool { return c.Name() == ComponentRouter }
+	routerCtx, cancelRouter := context.WithTimeout(context.Background(), policy.RouterTimeout)
+	report.Components = append(report.Components, s.teardownComponents(routerCtx, isRouter)...)
+	cancelRouter()
+
+	// Signal background work to stop
+	s.internal.cancel()
+
+	// Tear down the remaining components in reverse dependency order
+	ctx, cancel := context.WithTimeout(context.Background(), policy.TeardownTimeout)
+	report.Components = append(report.Components, s.teardownComponents(ctx, func(c Component) bool { return !isRouter(c) })...)
+
+	// Export any spans that haven't been exported, including those recorded while tearing down
+	if s.internal.tracerProvider != nil {
+		start := time.Now()
+		err := s.internal.tracerProvider.Shutdown(ctx)
+		report.Components = append(report.Components, ComponentShutdown{
+			Name:     ComponentTracing,
+			Duration: time.Since(start),
+			Error:    err,
+			TimedOut: errors.Is(err, context.DeadlineExceeded),
+		})
 	}
+	cancel()
 
-	// Drain the remaining errors from the error channel
-	for err := range errCh {
-		if err != nil && finalErr == nil {
-			finalErr = err
-		}
+	// Report any components that failed or timed out
+	if err := report.Err(); err != nil {
+		s.Log.Error("teardown completed with an error", slog.String("error", err.Error()), slog.Any("timed_out", report.TimedOut()))
 	}
 
-	// Teardown CloudSQL followed by flushing the logger.
-	// We delay tearing down the CloudSQL (database) and flushing the logger until last
-	// because other components may still require access to the database or logging
-	// capabilities during their own teardown processes. By shutting down the database
-	// and flushing logs last, we ensure that any necessary resources remain available
-	// and that all logged messages are written out before completing the overall shutdown.
-	if err := s.teardownCloudSQL(); err != nil {
-		s.Log.Error("failed to tear down CloudSQL", slog.String("error", err.Error()))
-	}
+	// Flush the logger last, ensuring that all logged messages, including those logged
+	// by components during their own teardown, are written out before completing the
+	// overall shutdown. Entries buffered by WithAsyncLogging are delivered first.
 	if err := s.flushLogger(); err != nil {
 		s.Log.Error("failed to flush the logger", slog.String("error", err.Error()))
+		s.flushLogger() // Deliver the error, in case it was buffered
+	}
+	s.closeLogger()
+	if s.internal.logFile != nil {
+		s.internal.logFile.Close()
 	}
 
-	return finalErr
+	return report
 }
 
 // teardownCloudSQL gracefully closes the Cloud SQL database connection if it is open.
-func (s *Service) teardownCloudSQL() (err error) {
+func (s *Service) teardownCloudSQL(ctx context.Context) (err error) {
 	if s.DB != nil {
 		if err := s.DB.Close(); err != nil {
 			return err
@@ -296,20 +400,73 @@ func (s *Service) teardownCloudSQL() (err error) {
 	return nil
 }
 
-// flushLogger ensures that all pending log entries are flushed to their destination
-// (such as Google Cloud Logging)
+// teardownCloudStorage closes the Cloud Storage client if it is open.
+func (s *Service) teardownCloudStorage(ctx context.Context) (err error) {
+	if s.CloudStorageClient != nil {
+		if err := s.CloudStorageClient.Close(); err != nil {
+			return err
+		}
+		s.CloudStorageClient = nil
+	}
+	return nil
+}
+
+// teardownCloudTasks closes the Cloud Tasks client if it is open.
+func (s *Service) teardownCloudTasks(ctx context.Context) (err error) {
+	if s.CloudTasksClient != nil {
+		if err := s.CloudTasksClient.Close(); err != nil {
+			return err
+		}
+		s.CloudTasksClient = nil
+	}
+	return nil
+}
+
+// teardownIAMClient closes the IAM client if it is open.
+func (s *Service) teardownIAMClient(ctx context.Context) (err error) {
+	if s.IAMClient != nil {
+		if err := s.IAMClient.Close(); err != nil {
+			return err
+		}
+		s.IAMClient = nil
+	}
+	return nil
+}
+
+// teardownPubSub stops the cached Pub/Sub topics, flushing any messages that are still
+// being published, and closes the Pub/Sub client.
+func (s *Service) teardownPubSub(ctx context.Context) (err error) {
+	if closer, ok := s.internal.pubsub.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// flushLogger ensures that all pending log entries, including those buffered to be logged
+// asynchronously, are flushed to their destination (such as Google Cloud Logging). Loggers
+// supplied with WithLogger are owned by the caller and are left for the caller to flush.
 func (s *Service) flushLogger() (err error) {
-	if s.Log != nil {
+	if s.Log != nil && s.internal.options.logger == nil {
 		err = logger.FlushLogger(s.Log)
 	}
 	return
 }
 
+// closeLogger stops the goroutines delivering the entries logged asynchronously, once the logger
+// has been flushed. Entries logged afterwards are delivered by the caller. Loggers supplied with
+// WithLogger are owned by the caller and are left for the caller to close.
+func (s *Service) closeLogger() {
+	if s.Log != nil && s.internal.options.logger == nil {
+		logger.CloseLogger(s.Log)
+	}
+}
+
 // teardownRouter gracefully shuts down the router, immediately stopping it from accepting
-// new incoming connections while allowing existing connections to complete before returning.
-func (s *Service) teardownRouter() (err error) {
+// new incoming connections while allowing existing connections to complete before returning,
+// or until the context expires.
+func (s *Service) teardownRouter(ctx context.Context) (err error) {
 	if s.internal.router != nil {
-		return s.internal.router.Shutdown()
+		return s.internal.router.Shutdown(ctx)
 	}
 	return nil
 }
@@ -0,0 +1,218 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/albeebe/service"
+	"github.com/albeebe/service/servicetest"
+)
+
+func TestShutdownTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		components []*blockingComponent
+		timedOut   []string // timedOut are the components expected to be reported as timing out, in order
+	}{
+		{
+			name: "slow component",
+			components: []*blockingComponent{
+				{name: "slow", blocks: true},
+				{name: "fast"},
+			},
+			timedOut: []string{"slow", service.ComponentTracing},
+		},
+		{
+			name: "dependency of a slow component",
+			components: []*blockingComponent{
+				{name: "db"},
+				{name: "slow", dependsOn: []string{"db"}, blocks: true},
+			},
+			timedOut: []string{"db", "slow", service.ComponentTracing},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := servicetest.New(t, servicetest.Config{
+				Options: []service.Option{service.WithShutdownPolicy(service.ShutdownPolicy{TeardownTimeout: 100 * time.Millisecond})},
+			})
+			release := make(chan struct{})
+			t.Cleanup(func() { close(release) })
+			for _, component := range test.components {
+				component.release = release
+				if err := h.Service.AddComponent(component); err != nil {
+					t.Fatalf("failed to add component: %s", err.Error())
+				}
+			}
+
+			// Shut the service down as soon as it's running, and wait for the report
+			reports := make(chan service.ShutdownReport, 1)
+			go h.Service.Run(service.State{
+				Running:    h.Service.Shutdown,
+				Terminated: func(report service.ShutdownReport) { reports <- report },
+			})
+			var report service.ShutdownReport
+			select {
+			case report = <-reports:
+			case <-time.After(5 * time.Second):
+				t.Fatal("expected the service to terminate without waiting for the slow component")
+			}
+
+			// Exporting spans shares the deadline, so it times out too once the deadline has passed
+			timedOut := report.TimedOut()
+			if !slices.Equal(timedOut, test.timedOut) {
+				t.Errorf("expected %v to time out, got %v", test.timedOut, timedOut)
+			}
+			for _, component := range report.Components {
+				if !slices.Contains(test.timedOut, component.Name) {
+					continue
+				}
+				if !component.TimedOut || !errors.Is(component.Error, context.DeadlineExceeded) {
+					t.Errorf("expected %s to time out with %v, got %+v", component.Name, context.DeadlineExceeded, component)
+				}
+			}
+			for _, name := range test.timedOut {
+				if err := report.Err(); err == nil || !strings.Contains(err.Error(), "timed out tearing down "+name) {
+					t.Errorf("expected the error to report %s timing out, got %v", name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestShutdownDrainDelay(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("interrupting the process isn't supported on Windows")
+	}
+	const drainDelay = 500 * time.Millisecond
+
+	// Serve on a real listener, so it can be observed closing
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %s", err.Error())
+	}
+	host := listener.Addr().String()
+	listener.Close()
+	h := servicetest.New(t, servicetest.Config{
+		Config:  service.Config{Host: host},
+		Options: []service.Option{service.WithShutdownPolicy(service.ShutdownPolicy{DrainDelay: drainDelay})},
+	})
+	running := make(chan struct{})
+	terminated := make(chan time.Time, 1)
+	go h.Service.Run(service.State{
+		Running:    func() { close(running) },
+		Terminated: func(service.ShutdownReport) { terminated <- time.Now() },
+	})
+	select {
+	case <-running:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the service to start")
+	}
+
+	// readiness returns the status of the readiness endpoint, as served by the listener
+	client := &http.Client{Timeout: time.Second}
+	readiness := func() (string, error) {
+		resp, err := client.Get("http://" + host + service.ReadinessPath)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		var report service.HealthReport
+		if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+			return "", err
+		}
+		return report.Status, nil
+	}
+	waitFor := func(expected string) time.Time {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			status, err := readiness()
+			if err == nil && status == expected {
+				return time.Now()
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("expected readiness to be %q, got %q and %v", expected, status, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitFor("ok")
+
+	// Interrupting the service starts draining, which fails readiness while requests are still served
+	interrupted := time.Now()
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("failed to find the process: %s", err.Error())
+	}
+	if err := process.Signal(os.Interrupt); err != nil {
+		t.Fatalf("failed to interrupt the process: %s", err.Error())
+	}
+	unready := waitFor("terminating")
+	var stopped time.Time
+	select {
+	case stopped = <-terminated:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the service to terminate")
+	}
+	if !unready.Before(stopped) {
+		t.Error("expected readiness to fail before the service terminated")
+	}
+	if elapsed := stopped.Sub(interrupted); elapsed < drainDelay {
+		t.Errorf("expected the service to drain for at least %s, terminated after %s", drainDelay, elapsed)
+	}
+	if _, err := readiness(); err == nil {
+		t.Error("expected the listener to be closed once the service terminated")
+	}
+}
+
+// blockingComponent is a Component whose teardown, when it blocks, doesn't return until it's
+// released, ignoring the deadline.
+type blockingComponent struct {
+	blocks    bool          // blocks is whether Teardown waits to be released.
+	dependsOn []string      // dependsOn are the names of the components it depends on.
+	name      string        // name is the name of the component.
+	release   chan struct{} // release is closed to release a blocked Teardown.
+}
+
+func (c *blockingComponent) Name() string                    { return c.name }
+func (c *blockingComponent) DependsOn() []string             { return c.dependsOn }
+func (c *blockingComponent) Setup(ctx context.Context) error { return nil }
+
+func (c *blockingComponent) Teardown(ctx context.Context) error {
+	if c.blocks {
+		<-c.release
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/albeebe/service/pkg/logger"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Logger returns the service's logger bound to the request, so entries it logs carry the request's
+// ID, and are correlated with the request's trace and span in Cloud Logging, even when they're
+// logged without the request's context (e.g. with Info rather than InfoContext).
+func (s *Service) Logger(r *http.Request) *slog.Logger {
+	l := logger.WithContext(s.Log, correlationContext(r))
+	if id := RequestID(r); id != "" {
+		l = l.With(slog.String("request_id", id))
+	}
+	return l
+}
+
+// correlationContext returns the context that entries about the request are logged with. Middleware
+// that runs before the request is routed doesn't see the context the request is handled with, so
+// the span the request was handled in is restored from the request's state.
+func correlationContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+	if state := requestStateFromContext(ctx); state != nil && state.spanContext.IsValid() {
+		return trace.ContextWithSpanContext(ctx, state.spanContext)
+	}
+	return ctx
+}
+
+// asyncLogPolicy returns the policy provided with WithAsyncLogging, if any, counting the entries
+// it drops in the service's metrics as well as reporting them to the policy's OnDrop.
+func (s *Service) asyncLogPolicy() *logger.AsyncPolicy {
+	if s.internal.options.asyncLogging == nil {
+		return nil
+	}
+	policy := *s.internal.options.asyncLogging
+	onDrop := policy.OnDrop
+	policy.OnDrop = func(level slog.Level) {
+		s.internal.metrics.logEntriesDropped.Inc(level.String())
+		if onDrop != nil {
+			onDrop(level)
+		}
+	}
+	return &policy
+}
+
+// addLogFile writes the service's entries to a rotating file as well, as structured JSON, with the
+// same level and redaction as the logger of the environment.
+func (s *Service) addLogFile(config logger.RotatingFileConfig) error {
+	file, err := logger.NewRotatingFile(config)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	fileLogger, err := logger.NewStructuredLogger(s.Context, logger.Config{
+		GCPProjectID:   s.internal.config.GCPProjectID,
+		Level:          s.LogLevels,
+		ServiceName:    s.Name,
+		ServiceVersion: "1.0",
+		Writer:         file,
+		Redaction:      &logger.RedactionPolicy{},
+	})
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to create file logger: %w", err)
+	}
+	s.internal.logFile = file
+	s.Log = slog.New(logger.NewMultiHandler(s.Log.Handler(), fileLogger.Handler()))
+	return nil
+}
+
+// deliverLogsAsync wraps the service's logger so its entries, including those written to the log
+// file, are delivered from a single background goroutine, as controlled by the policy provided
+// with WithAsyncLogging. The logger is left unchanged without one.
+func (s *Service) deliverLogsAsync() error {
+	policy := s.asyncLogPolicy()
+	if policy == nil {
+		return nil
+	}
+	if err := policy.Validate(); err != nil {
+		return fmt.Errorf("invalid async logging policy: %w", err)
+	}
+	s.Log = slog.New(logger.NewAsyncHandler(s.Log.Handler(), *policy))
+	return nil
+}
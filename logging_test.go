@@ -0,0 +1,285 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"github.com/albeebe/service"
+	"github.com/albeebe/service/pkg/logger"
+	"github.com/albeebe/service/servicetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// correlation is how an entry is correlated with the trace and span it was logged in.
+type correlation struct {
+	Trace   string
+	SpanID  string
+	Sampled bool
+}
+
+func TestLoggerTraceCorrelation(t *testing.T) {
+	tests := []struct {
+		name string
+		// newLogger returns a logger for the service, and a function returning how the entries
+		// it has logged were correlated
+		newLogger func(t *testing.T) (*slog.Logger, func() []correlation)
+	}{
+		{
+			name: "Cloud Logging",
+			newLogger: func(t *testing.T) (*slog.Logger, func() []correlation) {
+				server := newFakeCloudLogging(t)
+				l, err := logger.NewGoogleCloudLogger(context.Background(), logger.Config{
+					GCPProjectID:  "test-project",
+					LogName:       "test-service",
+					ClientOptions: server.clientOptions,
+				})
+				if err != nil {
+					t.Fatalf("failed to create logger: %s", err.Error())
+				}
+				return l, func() []correlation {
+					if err := logger.FlushLogger(l); err != nil {
+						t.Fatalf("failed to flush logger: %s", err.Error())
+					}
+					return server.correlations()
+				}
+			},
+		},
+		{
+			name: "structured",
+			newLogger: func(t *testing.T) (*slog.Logger, func() []correlation) {
+				var output lockedBuffer
+				l, err := logger.NewStructuredLogger(context.Background(), logger.Config{
+					GCPProjectID: "test-project",
+					Writer:       &output,
+				})
+				if err != nil {
+					t.Fatalf("failed to create logger: %s", err.Error())
+				}
+				return l, func() []correlation {
+					var correlations []correlation
+					for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+						var entry struct {
+							Trace   string `json:"logging.googleapis.com/trace"`
+							SpanID  string `json:"logging.googleapis.com/spanId"`
+							Sampled bool   `json:"logging.googleapis.com/trace_sampled"`
+						}
+						if err := json.Unmarshal([]byte(line), &entry); err != nil {
+							t.Fatalf("failed to decode entry %q: %s", line, err.Error())
+						}
+						correlations = append(correlations, correlation(entry))
+					}
+					return correlations
+				}
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l, logged := test.newLogger(t)
+			h := servicetest.New(t, servicetest.Config{Logger: l})
+
+			// The handler logs without the request's context, relying on Logger to correlate the
+			// entry, and responds with the ID of the span it was logged in
+			err := h.Service.AddPublicEndpoint("GET", "/orders", func(ctx context.Context, s *service.Service, r *http.Request) *service.HTTPResponse {
+				s.Logger(r).Info("listing orders")
+				return service.Text(http.StatusOK, trace.SpanContextFromContext(ctx).SpanID().String())
+			}, service.WithoutAccessLog())
+			if err != nil {
+				t.Fatalf("failed to add endpoint: %s", err.Error())
+			}
+
+			traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+			var expected []correlation
+			for _, flags := range []string{"01", "00"} {
+				r := httptest.NewRequest("GET", "/orders", nil)
+				r.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-"+flags)
+				resp := h.Do(r)
+				spanID, err := io.ReadAll(resp.Body)
+				if err != nil {
+					t.Fatalf("failed to read response: %s", err.Error())
+				}
+				expected = append(expected, correlation{
+					Trace:   "projects/test-project/traces/" + traceID,
+					SpanID:  string(spanID),
+					Sampled: flags == "01",
+				})
+			}
+
+			correlations := logged()
+			if len(correlations) != len(expected) {
+				t.Fatalf("expected %d entries, got %d: %+v", len(expected), len(correlations), correlations)
+			}
+			for i := range expected {
+				if correlations[i] != expected[i] {
+					t.Errorf("expected entry %d to be correlated with %+v, got %+v", i, expected[i], correlations[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLogFileDelivery(t *testing.T) {
+	const entries = 500
+	tests := []struct {
+		name  string
+		async *logger.AsyncPolicy
+	}{
+		{name: "synchronous"},
+		{name: "async", async: &logger.AsyncPolicy{}},
+		{name: "async with a small buffer", async: &logger.AsyncPolicy{BufferSize: 8, BatchSize: 4, Overflow: logger.OverflowBlock}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// The development logger writes to standard output, so capture it while the service runs
+			stdout := os.Stdout
+			reader, writer, err := os.Pipe()
+			if err != nil {
+				t.Fatalf("failed to create pipe: %s", err.Error())
+			}
+			os.Stdout = writer
+			t.Cleanup(func() { os.Stdout = stdout })
+			printed := make(chan string, 1)
+			go func() {
+				b, _ := io.ReadAll(reader)
+				printed <- string(b)
+			}()
+
+			path := filepath.Join(t.TempDir(), "service.log")
+			options := []service.Option{service.WithoutGoogleCloud(), service.WithLogFile(logger.RotatingFileConfig{Path: path})}
+			if test.async != nil {
+				options = append(options, service.WithAsyncLogging(*test.async))
+			}
+			s, err := service.New("test-service", service.Config{Host: "localhost:0"}, options...)
+			if err != nil {
+				t.Fatalf("failed to create service: %s", err.Error())
+			}
+			for i := 0; i < entries; i++ {
+				s.Log.Info(fmt.Sprintf("entry %d", i))
+			}
+
+			// Every entry reaches both destinations once the service has shut down
+			terminated := make(chan struct{})
+			go s.Run(service.State{
+				Running:    s.Shutdown,
+				Terminated: func(service.ShutdownReport) { close(terminated) },
+			})
+			select {
+			case <-terminated:
+			case <-time.After(5 * time.Second):
+				t.Fatal("expected the service to terminate")
+			}
+			os.Stdout = stdout
+			writer.Close()
+			output := <-printed
+			file, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read log file: %s", err.Error())
+			}
+			for i := 0; i < entries; i++ {
+				message := fmt.Sprintf("entry %d", i)
+				if !strings.Contains(output, message+"\n") && !strings.Contains(output, message+" ") {
+					t.Fatalf("expected %q to be printed", message)
+				}
+				if !strings.Contains(string(file), `"message":"`+message+`"`) {
+					t.Fatalf("expected %q to be written to the log file", message)
+				}
+			}
+		})
+	}
+}
+
+// fakeCloudLogging is a Cloud Logging server that records the entries written to it.
+type fakeCloudLogging struct {
+	loggingpb.UnimplementedLoggingServiceV2Server
+	clientOptions []option.ClientOption // clientOptions connect a client to the server.
+	entries       []*loggingpb.LogEntry // entries are the entries written to the server.
+	mux           sync.Mutex            // mux protects entries.
+}
+
+// WriteLogEntries records the entries.
+func (f *fakeCloudLogging) WriteLogEntries(ctx context.Context, request *loggingpb.WriteLogEntriesRequest) (*loggingpb.WriteLogEntriesResponse, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.entries = append(f.entries, request.GetEntries()...)
+	return &loggingpb.WriteLogEntriesResponse{}, nil
+}
+
+// correlations returns how the entries written to the server were correlated, in the order
+// they were written.
+func (f *fakeCloudLogging) correlations() []correlation {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	correlations := make([]correlation, 0, len(f.entries))
+	for _, entry := range f.entries {
+		// Skip the diagnostic entry the client writes alongside the first entries
+		if strings.HasSuffix(entry.GetLogName(), "/diagnostic-log") {
+			continue
+		}
+		correlations = append(correlations, correlation{
+			Trace:   entry.GetTrace(),
+			SpanID:  entry.GetSpanId(),
+			Sampled: entry.GetTraceSampled(),
+		})
+	}
+	return correlations
+}
+
+// newFakeCloudLogging starts a fake Cloud Logging server. The server is stopped when the test
+// completes.
+func newFakeCloudLogging(t *testing.T) *fakeCloudLogging {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err.Error())
+	}
+	fake := &fakeCloudLogging{
+		clientOptions: []option.ClientOption{
+			option.WithEndpoint(listener.Addr().String()),
+			option.WithoutAuthentication(),
+			option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+		},
+	}
+	server := grpc.NewServer()
+	loggingpb.RegisterLoggingServiceV2Server(server, fake)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return fake
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/albeebe/service/pkg/logger"
+)
+
+const (
+	// DefaultLogLevelTTL is how long a level changed with the log level endpoint lasts when the
+	// request doesn't specify a TTL, so forgotten debug logging turns itself off.
+	DefaultLogLevelTTL = 15 * time.Minute
+
+	// LogLevelEnv is the environment variable whose levels, in the format accepted by
+	// logger.Levels.Apply (e.g. "info,pubsub=debug"), are applied when the service starts.
+	LogLevelEnv = "LOG_LEVEL"
+
+	// logLevelFileInterval is how often the file passed to WithLogLevelFile is checked for changes.
+	logLevelFileInterval = 10 * time.Second
+)
+
+// LogLevelChange is the request body of the PUT method of the log level endpoint.
+type LogLevelChange struct {
+	Name  string      `json:"name"`                      // Name of the loggers to change, or empty to change the default level
+	Level *slog.Level `json:"level" validate:"required"` // Level to change to (e.g. "DEBUG")
+	TTL   string      `json:"ttl"`                       // How long the change lasts (e.g. "30m"), "0" makes it permanent, defaults to DefaultLogLevelTTL
+}
+
+// logLevelReset is the request of the DELETE method of the log level endpoint.
+type logLevelReset struct {
+	Name string `query:"name" json:"-"` // Name of the loggers to reset, or empty to reset the default level
+}
+
+// AddLogLevelEndpoint registers an authenticated admin endpoint at the relativePath that changes the
+// service's log levels (see Service.LogLevels) at runtime, so debug logging can be turned on for a
+// misbehaving instance without redeploying it. Like AddAuthenticatedEndpoint, it requires an
+// AuthProvider, and the caller must have the permission. The endpoint supports three methods:
+//
+//   - GET returns the default level and the overrides of named loggers.
+//   - PUT changes a level, with a LogLevelChange body. Changes revert once their TTL elapses.
+//   - DELETE reverts the level of the loggers named by the "name" query parameter, or the default
+//     level if there isn't one.
+//
+// Every method responds with the levels after the request was handled. Changes are logged along
+// with the subject of the caller that made them.
+//
+// Example:
+//
+//	s.AddLogLevelEndpoint("/admin/log-levels", "admin.logging")
+//
+//	// curl -X PUT -d '{"name": "pubsub", "level": "DEBUG", "ttl": "30m"}' .../admin/log-levels
+func (s *Service) AddLogLevelEndpoint(relativePath, permission string, opts ...EndpointOption) error {
+	if err := AddTypedEndpoint(s, "GET", relativePath, permission, getLogLevels, opts...); err != nil {
+		return err
+	}
+	if err := AddTypedEndpoint(s, "PUT", relativePath, permission, changeLogLevel, opts...); err != nil {
+		return err
+	}
+	return AddTypedEndpoint(s, "DELETE", relativePath, permission, resetLogLevel, opts...)
+}
+
+// getLogLevels returns the service's log levels.
+func getLogLevels(_ context.Context, s *Service, _ struct{}) (logger.LevelsSnapshot, error) {
+	return s.LogLevels.Snapshot(), nil
+}
+
+// changeLogLevel changes one of the service's log levels, returning the levels after the change.
+func changeLogLevel(ctx context.Context, s *Service, req LogLevelChange) (logger.LevelsSnapshot, error) {
+	ttl := DefaultLogLevelTTL
+	if req.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl < 0 {
+			return logger.LevelsSnapshot{}, NewHTTPError(http.StatusBadRequest, CodeInvalidRequest, "ttl %q is invalid, it must be a non-negative duration (e.g. \"30m\")", req.TTL)
+		}
+	}
+	s.LogLevels.SetNamed(req.Name, *req.Level, ttl)
+	s.Log.WarnContext(ctx, "log level changed", slog.String("name", req.Name), slog.String("level", req.Level.String()), slog.Duration("ttl", ttl), slog.String("subject", subject(ctx)))
+	return s.LogLevels.Snapshot(), nil
+}
+
+// resetLogLevel reverts one of the service's log levels, returning the levels after reverting it.
+func resetLogLevel(ctx context.Context, s *Service, req logLevelReset) (logger.LevelsSnapshot, error) {
+	s.LogLevels.Unset(req.Name)
+	s.Log.WarnContext(ctx, "log level reset", slog.String("name", req.Name), slog.String("subject", subject(ctx)))
+	return s.LogLevels.Snapshot(), nil
+}
+
+// subject returns the subject of the caller whose request the context belongs to, if it was authenticated.
+func subject(ctx context.Context) string {
+	identity, _ := PrincipalFromContext(ctx)
+	return identity.Subject
+}
+
+// initializeLogLevels creates the service's log levels with the default level, and applies the
+// levels in the LOG_LEVEL environment variable, if it's set.
+func (s *Service) initializeLogLevels(level slog.Level) error {
+	s.LogLevels = logger.NewLevels(level)
+	if spec, ok := os.LookupEnv(LogLevelEnv); ok {
+		if err := s.LogLevels.Apply(spec, 0); err != nil {
+			return fmt.Errorf("failed to apply %s: %w", LogLevelEnv, err)
+		}
+	}
+	return nil
+}
+
+// watchLogLevels applies the levels in the file whenever it changes, until the service terminates.
+// The levels the file names replace those of the LOG_LEVEL environment variable, while the levels
+// it doesn't name, including changes made through the log level endpoint, are left alone.
+func (s *Service) watchLogLevels(path string) {
+	s.LogLevels.Watch(s.Context, path, logLevelFileInterval, func(err error) {
+		s.Log.Error("failed to watch log levels", slog.String("error", err.Error()), slog.String("path", path))
+	})
+}
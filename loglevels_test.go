@@ -0,0 +1,116 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service_test
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/albeebe/service/pkg/logger"
+	"github.com/albeebe/service/servicetest"
+)
+
+func TestLogLevelEndpoint(t *testing.T) {
+	var output lockedBuffer
+	h := servicetest.New(t, servicetest.Config{Logger: slog.New(slog.NewJSONHandler(&output, nil))})
+	if err := h.Service.AddLogLevelEndpoint("/admin/log-levels", "admin.logging"); err != nil {
+		t.Fatalf("failed to add endpoint: %s", err.Error())
+	}
+	admin := servicetest.Claims{Subject: "alice", Permissions: []string{"admin.logging"}}
+	request := func(t *testing.T, method, target, body string, claims *servicetest.Claims) (int, logger.LevelsSnapshot) {
+		t.Helper()
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		if claims != nil {
+			if err := h.Auth.Authorize(r, *claims); err != nil {
+				t.Fatalf("failed to authorize request: %s", err.Error())
+			}
+		}
+		resp := h.Do(r)
+		var snapshot logger.LevelsSnapshot
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&snapshot); err != nil {
+				t.Fatalf("failed to decode levels: %s", err.Error())
+			}
+		}
+		return resp.StatusCode, snapshot
+	}
+
+	t.Run("access", func(t *testing.T) {
+		if status, _ := request(t, "GET", "/admin/log-levels", "", nil); status != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, status)
+		}
+		if status, _ := request(t, "PUT", "/admin/log-levels", `{"level": "DEBUG"}`, &servicetest.Claims{Subject: "bob"}); status != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, status)
+		}
+	})
+
+	t.Run("change", func(t *testing.T) {
+		status, snapshot := request(t, "PUT", "/admin/log-levels", `{"name": "pubsub", "level": "DEBUG", "ttl": "30m"}`, &admin)
+		if status != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+		}
+		if len(snapshot.Overrides) != 1 || snapshot.Overrides[0].Name != "pubsub" || snapshot.Overrides[0].Level != slog.LevelDebug || snapshot.Overrides[0].Expires == nil {
+			t.Errorf("unexpected levels %+v", snapshot)
+		}
+
+		// Changes without a TTL revert after the default TTL, unless the TTL is zero
+		_, snapshot = request(t, "PUT", "/admin/log-levels", `{"level": "WARN"}`, &admin)
+		if snapshot.Level != slog.LevelWarn || snapshot.Expires == nil {
+			t.Errorf("expected a temporary default level, got %+v", snapshot)
+		}
+		_, snapshot = request(t, "PUT", "/admin/log-levels", `{"level": "ERROR", "ttl": "0"}`, &admin)
+		if snapshot.Level != slog.LevelError || snapshot.Expires != nil {
+			t.Errorf("expected a permanent default level, got %+v", snapshot)
+		}
+		if level := h.Service.LogLevels.Level(); level != slog.LevelError {
+			t.Errorf("expected the service's level to be %s, got %s", slog.LevelError, level)
+		}
+
+		// Changes are logged with the subject that made them
+		if !strings.Contains(output.String(), `"msg":"log level changed"`) || !strings.Contains(output.String(), `"subject":"alice"`) {
+			t.Errorf("expected the change to be logged with its subject, got %s", output.String())
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, body := range []string{`{"name": "pubsub"}`, `{"level": "VERBOSE"}`, `{"level": "DEBUG", "ttl": "soon"}`, `{"level": "DEBUG", "ttl": "-1m"}`} {
+			if status, _ := request(t, "PUT", "/admin/log-levels", body, &admin); status != http.StatusBadRequest {
+				t.Errorf("expected status %d for %s, got %d", http.StatusBadRequest, body, status)
+			}
+		}
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		status, snapshot := request(t, "DELETE", "/admin/log-levels?name=pubsub", "", &admin)
+		if status != http.StatusOK || len(snapshot.Overrides) != 0 {
+			t.Errorf("expected the override to be removed, got %d and %+v", status, snapshot)
+		}
+		if _, snapshot := request(t, "GET", "/admin/log-levels", "", &admin); len(snapshot.Overrides) != 0 {
+			t.Errorf("unexpected levels %+v", snapshot)
+		}
+	})
+}
@@ -43,10 +43,16 @@ import (
 	"github.com/albeebe/service/pkg/auth"
 	"github.com/albeebe/service/pkg/credentials"
 	"github.com/albeebe/service/pkg/environment"
+	"github.com/albeebe/service/pkg/metrics"
 	"github.com/albeebe/service/pkg/pubsub"
-	"github.com/albeebe/service/pkg/router"
+	"github.com/albeebe/service/pkg/tracing"
 	"github.com/golang-jwt/jwt"
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -61,47 +67,84 @@ func Initialize(spec interface{}) error {
 	return environment.Initialize(spec, runningInProduction())
 }
 
-// New initializes a new service instance with a service name, and configuration.
+// New initializes a new service instance with a service name, configuration and options.
 // It validates the configuration, sets up Google Cloud credentials,
 // and prepares the service for use. Returns a configured Service or an error on failure.
-func New(serviceName string, config Config) (*Service, error) {
+//
+// By default every Google Cloud client is created using the application default credentials.
+// Options can be used to skip creating clients the service doesn't use (e.g., WithoutCloudStorage),
+// to provide prebuilt or fake clients (e.g., WithCloudTasksClient), or to provide the credentials
+// and logger (WithCredentials, WithLogger). Credentials are only loaded when the service creates
+// at least one Google Cloud client itself, so a service can start without any access to Google Cloud.
+func New(serviceName string, config Config, opts ...Option) (*Service, error) {
+
+	// Apply the options
+	o := &options{trustedProxies: 1}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
 
 	// Validate the configuration
-	if err := config.validate(); err != nil {
+	if err := config.validate(o); err != nil {
 		return nil, fmt.Errorf("config is invalid: %w", err)
 	}
 
 	// Configure service
 	ctx, cancel := context.WithCancel(context.Background())
+	registry := metrics.NewRegistry()
 	s := &Service{
-		Context: ctx,
-		Name:    serviceName,
+		Context:           ctx,
+		GoogleCredentials: o.credentials,
+		Metrics:           registry,
+		Name:              serviceName,
 		internal: &internal{
-			cancel: cancel,
-			config: &config,
+			cancel:     cancel,
+			components: newComponentRegistry(),
+			metrics:    newServiceMetrics(registry),
+			routes:     &routeTable{},
+			config:     &config,
+			options:    o,
 		},
 	}
 
 	// Initialize the logger
 	if err := s.initializeLogger(); err != nil {
+		cancel()
 		return nil, fmt.Errorf("failed to initialize logger: %w", err)
 	}
 
-	// Load the credentials
-	var err error
-	s.GoogleCredentials, err = credentials.NewGoogleCredentials(ctx, credentials.Config{
-		Scopes: []string{
-			"https://www.googleapis.com/auth/cloud-platform",
-			"https://www.googleapis.com/auth/sqlservice.admin",
-			"https://www.googleapis.com/auth/devstorage.full_control",
-		},
-	})
-	if err != nil {
-		return nil, err
+	// Watch the file of log levels, if there is one
+	if o.logLevelFile != "" {
+		go s.watchLogLevels(o.logLevelFile)
+	}
+
+	// Create the tracer
+	if err := s.setupTracing(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to set up tracing: %w", err)
+	}
+
+	// Load the credentials, unless they were provided or no client needs them
+	if o.needsCredentials() {
+		var err error
+		s.GoogleCredentials, err = credentials.NewGoogleCredentials(ctx, credentials.Config{
+			Scopes: []string{
+				"https://www.googleapis.com/auth/cloud-platform",
+				"https://www.googleapis.com/auth/sqlservice.admin",
+				"https://www.googleapis.com/auth/devstorage.full_control",
+			},
+		})
+		if err != nil {
+			cancel()
+			return nil, err
+		}
 	}
 
 	// Set up the services components
 	if err := s.setup(); err != nil {
+		cancel()
 		return nil, fmt.Errorf("failed to set up the service: %w", err)
 	}
 
@@ -109,66 +152,83 @@ func New(serviceName string, config Config) (*Service, error) {
 }
 
 // Run starts the service and blocks, waiting for an OS signal, context cancellation, or an error.
+// Any components added with AddComponent are set up before the service begins accepting requests,
+// and every component is torn down when the service terminates, following the ShutdownPolicy
+// provided with WithShutdownPolicy.
+//
 // Lifecycle callbacks from the State struct are invoked at each stage:
 // - `Starting`: Called when the service starts.
 // - `Running`: Called when the service is running.
 // - `Terminating`: Called during shutdown, with an error if one triggered the termination.
+// - `Terminated`: Called once the service has shut down, with the outcome of tearing down each component.
 //
-// The function returns only after the service has gracefully shut down.
+// The function returns only after the service has gracefully shut down, or immediately if a
+// second terminate signal is received while shutting down.
 func (s *Service) Run(state State) {
+	defer s.internal.cancel()
 
 	if state.Starting != nil {
 		state.Starting()
 	}
 
-	// Start the auth service
-	if s.internal.auth != nil {
-		go s.startAuthService()
-	}
-
 	// Set up a channel to listen for the terminate signals from the OS
 	terminate := make(chan os.Signal, 1)
 	signal.Notify(terminate, os.Interrupt, syscall.SIGTERM)
 	defer signal.Stop(terminate)
 
-	// Block until we get a terminate signal, or the context is canceled
-	if state.Running != nil {
-		state.Running()
+	// Confirm every endpoint registered, then set up the components that were added after
+	// the service was created
+	drain := false
+	_, err := s.Routes()
+	if err == nil {
+		err = s.setupComponents(s.Context)
 	}
-	select {
-	case <-terminate:
+	if err != nil {
+		s.Log.Error("failed to start the service", slog.String("error", err.Error()))
+		s.internal.terminating.Store(true)
 		if state.Terminating != nil {
-			state.Terminating(nil)
+			state.Terminating(err)
 		}
-	case <-s.Context.Done():
-		if state.Terminating != nil {
-			state.Terminating(nil)
+	} else {
+		// Start the auth service
+		s.internal.running.Store(true)
+		if s.internal.auth != nil {
+			go s.startAuthService()
+		}
+
+		// Block until we get a terminate signal, or the context is canceled
+		if state.Running != nil {
+			state.Running()
+		}
+		select {
+		case <-terminate:
+			drain = true
+		case <-s.Context.Done():
+		case err = <-s.internal.router.ListenAndServe():
 		}
-	case err := <-s.internal.router.ListenAndServe():
+
+		// Fail the readiness endpoint before anything else happens
+		s.internal.terminating.Store(true)
 		if state.Terminating != nil {
 			state.Terminating(err)
 		}
 	}
 
-	// Cancel the context to initiate the graceful shutdown
-	s.internal.cancel()
-
 	// Create a channel to signal when teardown is complete
-	teardownComplete := make(chan error)
+	teardownComplete := make(chan ShutdownReport, 1)
 
-	// Begin teardown in a separate goroutine allowing up to 5 seconds to gracefully teardown
+	// Begin teardown in a separate goroutine
 	go func() {
-		defer close(teardownComplete)
-		teardownComplete <- s.teardown(5 * time.Second)
+		teardownComplete <- s.teardown(drain)
 	}()
 
 	// Wait for teardown to complete, or return immediately if a second signal is received
 	select {
 	case <-terminate:
 		return
-	case err := <-teardownComplete:
-		if err != nil {
-			s.Log.Error("teardown completed with an error", slog.String("error", err.Error()))
+	case report := <-teardownComplete:
+		if state.Terminated != nil {
+			state.Terminated(report)
 		}
 	}
 }
@@ -180,6 +240,12 @@ func (s *Service) Shutdown() {
 	s.internal.cancel()
 }
 
+// ServeHTTP dispatches the request to the service's router in-process, without going through
+// the network listener. This allows the service to be exercised with tools like httptest.
+func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.internal.router.ServeHTTP(w, r)
+}
+
 // Config returns the current configuration of the service.
 // It provides access to the internal configuration stored in the service.
 func (s *Service) Config() *Config {
@@ -187,12 +253,25 @@ func (s *Service) Config() *Config {
 }
 
 // SetAuthProvider initializes the authentication provider for the service.
+// The auth service is registered as a component, so readiness reports whether its keys have loaded.
 func (s *Service) SetAuthProvider(authProvider auth.AuthProvider) error {
 	var err error
 	s.internal.auth, err = auth.New(s.Context, auth.Config{
 		AuthProvider: authProvider,
+		Metrics:      s.Metrics,
 	})
-	return err
+	if err != nil {
+		return err
+	}
+
+	// Register the auth component, unless a previous auth provider already registered it
+	s.internal.components.mux.Lock()
+	_, registered := s.internal.components.byName[ComponentAuth]
+	s.internal.components.mux.Unlock()
+	if !registered {
+		return s.AddComponent(&builtinComponent{name: ComponentAuth, health: s.checkAuthHealth})
+	}
+	return nil
 }
 
 // AuthenticateRequest validates an HTTP request by performing both
@@ -240,341 +319,223 @@ func (s *Service) IsRequestFromService(r *http.Request) bool {
 // Optionally, a permission can be specified and is checked after authentication. If the permission requirements
 // is not met, a 403 Forbidden response is returned.
 //
-// If the service was initialized without an AuthProvider, it logs a fatal error and exits.
+// If the service was initialized without an AuthProvider, an error is returned and the endpoint is not registered.
 // If authentication fails, a 401 Unauthorized response is returned. If authorization
 // requirements are provided and the request fails authorization, a 403 Forbidden response is returned.
 // In case of an internal error during processing, a 500 Internal Server Error is returned.
-func (s *Service) AddAuthenticatedEndpoint(method, relativePath, permission string, handler EndpointHandler) {
+func (s *Service) AddAuthenticatedEndpoint(method, relativePath, permission string, handler EndpointHandler, opts ...EndpointOption) error {
 
 	// Sanitize the parameters
-	method = strings.TrimSpace(method)
-	relativePath = strings.TrimSpace(relativePath)
-	permission = strings.TrimSpace(permission)
-
-	// Confirm an AuthProvider exists
-	if s.internal.auth == nil {
-		s.Log.Error("AddAuthenticatedEndpoint requires the service to be initialized with an AuthProvider")
-		os.Exit(1)
-	}
+	route := newRoute(EndpointAuthenticated, method, relativePath, permission)
 
-	// Middleware to wrap the handler for request authentication. It authenticates the request,
-	// injects the relevant service into the handler, and manages the process of sending the response.
-	wrappedHandler := func(w http.ResponseWriter, r *http.Request) {
+	// Guard the handler with request authentication and authorization, so the handler is only
+	// invoked for requests that pass both.
+	guardedHandler := func(ctx context.Context, s *Service, r *http.Request) *HTTPResponse {
 		// Authenticate the request
-		authenticated, reason, err := s.internal.auth.Authenticate(r)
+		authenticated, claims, reason, err := s.internal.auth.AuthenticateClaims(r)
 		if err != nil {
 			s.Log.Error("failed to authenticated request", slog.String("error", err.Error()))
-			sendResponse(w, 500, "internal server error")
-			return
+			return internalError().Response()
 		}
 		if !authenticated {
-			message := "unauthorized"
+			message := "authentication is required"
 			if reason != "" {
-				message += ": " + reason
+				message = reason
 			}
-			sendResponse(w, 401, message)
-			return
+			return NewHTTPError(http.StatusUnauthorized, CodeUnauthorized, message).Response()
 		}
+		setPrincipal(r, claims, s.internal.auth.IsServiceRequest(r))
 
 		// Authorize the request
-		authorized, err := s.internal.auth.Authorize(r, permission)
+		authorized, err := s.internal.auth.Authorize(r, route.Permission)
 		if err != nil {
 			s.Log.Error("failed to authorize request", slog.String("error", err.Error()))
-			sendResponse(w, 500, "internal server error")
-			return
+			return internalError().Response()
 		}
 		if !authorized {
-			sendResponse(w, 403, fmt.Sprintf("Forbidden: Missing required permission '%s'", permission))
-			return
+			return NewHTTPError(http.StatusForbidden, CodeForbidden, "missing required permission '%s'", route.Permission).Response()
 		}
 
-		// Send the request to the handler and handle the response
-		resp := handler(s, r)
-		if resp == nil {
-			sendResponse(w, 500, "internal server error")
-			return
-		}
-		if err := router.SendResponse(w, resp.StatusCode, resp.Headers, resp.Body); err != nil {
-			s.Log.Error("failed to send response", slog.String("error", err.Error()))
-		}
+		// Send the request to the handler
+		return handler(ctx, s, r)
 	}
 
-	// Register the wrapped handler to the router to handle requests on the given relativePath.
-	// Log a fatal error if the handler registration fails.
-	if err := s.internal.router.RegisterHandler(method, relativePath, wrappedHandler); err != nil {
-		s.Log.Error("failed to register handler", slog.String("error", err.Error()), slog.String("method", method), slog.String("relative_path", relativePath))
-		os.Exit(1)
-	}
+	// Register the wrapped handler to the router to handle requests on the given relativePath
+	return s.registerEndpoint(route, guardedHandler, opts)
 }
 
 // AddCloudTaskEndpoint registers a new POST endpoint at the specified relativePath to handle
 // incoming Google Cloud Tasks. In production, it verifies the authenticity of the request,
 // while in local or non-production environments, request verification is skipped.
-func (s *Service) AddCloudTaskEndpoint(relativePath string, handler EndpointHandler) {
+func (s *Service) AddCloudTaskEndpoint(relativePath string, handler EndpointHandler, opts ...EndpointOption) error {
 
 	// Sanitize the parameters
-	relativePath = strings.TrimSpace(relativePath)
-
-	// wrappedHandler is the middleware that processes the incoming request.
-	wrappedHandler := func(w http.ResponseWriter, r *http.Request) {
-
-		// Verify the request if running in a production environment.
-		// This step ensures that the request comes from Google Cloud Tasks.
-		if runningInProduction() {
-			if err := verifyGoogleRequest(s.Context, r); err != nil {
-				// Respond with a 403 Forbidden status if verification fails.
-				sendResponse(w, http.StatusForbidden, "forbidden: failed to validate Google ID token")
-				return
-			}
-		}
+	route := newRoute(EndpointCloudTask, "POST", relativePath, "")
 
-		// Invoke the provided handler function with the request.
-		resp := handler(s, r)
-		if resp == nil {
-			resp = Text(500, "internal server error")
-		}
-		if err := router.SendResponse(w, resp.StatusCode, resp.Headers, resp.Body); err != nil {
-			s.Log.Error("failed to send response", slog.String("error", err.Error()))
-		}
-	}
-
-	// Register the wrapped handler to the router to handle POST requests on the given relativePath.
-	// Log a fatal error if the handler registration fails.
-	if err := s.internal.router.RegisterHandler("POST", relativePath, wrappedHandler); err != nil {
-		s.Log.Error("failed to add Cloud Task", slog.String("error", err.Error()), slog.String("relative_path", relativePath))
-	}
+	// Register the handler to the router to handle POST requests on the given relativePath, guarded
+	// by verification that the request comes from Google Cloud Tasks.
+	return s.registerEndpoint(route, verifiedGoogleHandler(handler), opts)
 }
 
 // AddCloudWorkflowEndpoint registers a new POST endpoint at the specified relativePath to handle
 // incoming Google Cloud Workflow requests. In production, it verifies the authenticity of the request,
 // while in local or non-production environments, request verification is skipped.
-func (s *Service) AddCloudWorkflowEndpoint(relativePath string, handler EndpointHandler) {
+func (s *Service) AddCloudWorkflowEndpoint(relativePath string, handler EndpointHandler, opts ...EndpointOption) error {
 
 	// Sanitize the parameters
-	relativePath = strings.TrimSpace(relativePath)
-
-	// wrappedHandler is the middleware that processes the incoming request.
-	wrappedHandler := func(w http.ResponseWriter, r *http.Request) {
-
-		// Verify the request if running in a production environment.
-		// This step ensures that the request comes from Google Cloud Workflows.
-		if runningInProduction() {
-			if err := verifyGoogleRequest(s.Context, r); err != nil {
-				// Respond with a 403 Forbidden status if verification fails.
-				sendResponse(w, http.StatusForbidden, "forbidden: failed to validate Google ID token")
-				return
-			}
-		}
-
-		// Invoke the provided handler function with the request.
-		resp := handler(s, r)
-		if resp == nil {
-			resp = Text(500, "internal server error")
-		}
-		if err := router.SendResponse(w, resp.StatusCode, resp.Headers, resp.Body); err != nil {
-			s.Log.Error("failed to send response", slog.String("error", err.Error()))
-		}
-	}
+	route := newRoute(EndpointCloudWorkflow, "POST", relativePath, "")
 
-	// Register the wrapped handler to the router to handle POST requests on the given relativePath.
-	// Log a fatal error if the handler registration fails.
-	if err := s.internal.router.RegisterHandler("POST", relativePath, wrappedHandler); err != nil {
-		s.Log.Error("failed to add Cloud Workflow", slog.String("error", err.Error()), slog.String("relative_path", relativePath))
-	}
+	// Register the handler to the router to handle POST requests on the given relativePath, guarded
+	// by verification that the request comes from Google Cloud Workflows.
+	return s.registerEndpoint(route, verifiedGoogleHandler(handler), opts)
 }
 
 // AddCloudSchedulerEndpoint registers a new POST endpoint at the specified relativePath to handle
 // incoming Google Cloud Scheduler requests. In production, it verifies the authenticity of the
 // request, while in local or non-production environments, request verification is skipped.
-func (s *Service) AddCloudSchedulerEndpoint(relativePath string, handler EndpointHandler) {
+func (s *Service) AddCloudSchedulerEndpoint(relativePath string, handler EndpointHandler, opts ...EndpointOption) error {
 
 	// Sanitize the parameters
-	relativePath = strings.TrimSpace(relativePath)
+	route := newRoute(EndpointCloudScheduler, "POST", relativePath, "")
 
-	// wrappedHandler is the middleware that processes the incoming request.
-	wrappedHandler := func(w http.ResponseWriter, r *http.Request) {
-
-		// Verify the request if running in a production environment.
-		// This step ensures that the request comes from Google Cloud Scheduler.
-		if runningInProduction() {
-			if err := verifyGoogleRequest(s.Context, r); err != nil {
-				// Respond with a 403 Forbidden status if verification fails.
-				sendResponse(w, http.StatusForbidden, "forbidden: failed to validate Google ID token")
-				return
-			}
-		}
-
-		// Invoke the provided handler function with the request.
-		resp := handler(s, r)
-		if resp == nil {
-			resp = Text(500, "internal server error")
-		}
-		if err := router.SendResponse(w, resp.StatusCode, resp.Headers, resp.Body); err != nil {
-			s.Log.Error("failed to send response", slog.String("error", err.Error()))
-		}
-	}
-
-	// Register the wrapped handler to the router to handle POST requests on the given relativePath.
-	// Log a fatal error if the handler registration fails.
-	if err := s.internal.router.RegisterHandler("POST", relativePath, wrappedHandler); err != nil {
-		s.Log.Error("failed to add Cronjob", slog.String("error", err.Error()), slog.String("relative_path", relativePath))
-		os.Exit(1)
-
-	}
+	// Register the handler to the router to handle POST requests on the given relativePath, guarded
+	// by verification that the request comes from Google Cloud Scheduler.
+	return s.registerEndpoint(route, verifiedGoogleHandler(handler), opts)
 }
 
 // AddPublicEndpoint registers a new HTTP endpoint with the specified method (e.g., "GET", "POST")
 // and relative path. It wraps the provided handler function so that the current Service
 // instance is passed into the handler when the endpoint is invoked.
 // This endpoint does not require authentication.
-// If an error occurs while registering the endpoint, the function will log the error
-// and terminate the program.
-func (s *Service) AddPublicEndpoint(method, relativePath string, handler EndpointHandler) {
+// An error is returned if the method or path is invalid, or if the path conflicts with an
+// endpoint that's already registered.
+func (s *Service) AddPublicEndpoint(method, relativePath string, handler EndpointHandler, opts ...EndpointOption) error {
 
 	// Sanitize the parameters
-	method = strings.TrimSpace(method)
-	relativePath = strings.TrimSpace(relativePath)
-
-	// Wrap the handler, so we can pass the service to it and handle sending the response
-	wrappedHandler := func(w http.ResponseWriter, r *http.Request) {
-		resp := handler(s, r)
-		if resp == nil {
-			resp = Text(500, "internal server error")
-		}
-		if err := router.SendResponse(w, resp.StatusCode, resp.Headers, resp.Body); err != nil {
-			s.Log.Error("failed to send response", slog.String("error", err.Error()))
-		}
-	}
+	route := newRoute(EndpointPublic, method, relativePath, "")
 
-	// Register the wrapped handler to the router to handle requests on the given relativePath.
-	// Log a fatal error if the handler registration fails.
-	if err := s.internal.router.RegisterHandler(method, relativePath, wrappedHandler); err != nil {
-		s.Log.Error("failed to register handler", slog.String("error", err.Error()), slog.String("method", method), slog.String("relative_path", relativePath))
-		os.Exit(1)
-	}
+	// Register the wrapped handler to the router to handle requests on the given relativePath
+	return s.registerEndpoint(route, handler, opts)
 }
 
 // AddServiceEndpoint registers an HTTP endpoint that requires authentication and is restricted to service requests only.
 // It first authenticates the request, ensuring that only valid credentials are allowed, and then verifies
 // that the request comes specifically from a trusted service.
 //
-// If the service was initialized without an AuthProvider, it logs a fatal error and exits.
+// If the service was initialized without an AuthProvider, an error is returned and the endpoint is not registered.
 // If authentication fails, a 401 Unauthorized response is returned. If the request is not verified as coming
 // from a service, a 403 Forbidden response is returned indicating that access is restricted to services.
 //
 // Optionally, a permission can be specified and is checked after authentication. If the permission requirements
 // is not met, a 403 Forbidden response is returned.
 //
-// The handler function receives the Service instance and the HTTP request, and returns an HTTPResponse.
+// The handler function receives the request's context, the Service instance and the HTTP request, and returns an HTTPResponse.
 // In case of an internal error during processing, a 500 Internal Server Error is returned.
 // This endpoint is intended for use by other services and ensures only authenticated and verified service requests
 // are permitted.
-func (s *Service) AddServiceEndpoint(method, relativePath, permission string, handler EndpointHandler) {
+func (s *Service) AddServiceEndpoint(method, relativePath, permission string, handler EndpointHandler, opts ...EndpointOption) error {
 
 	// Sanitize the parameters
-	method = strings.TrimSpace(method)
-	relativePath = strings.TrimSpace(relativePath)
-	permission = strings.TrimSpace(permission)
+	route := newRoute(EndpointService, method, relativePath, permission)
 
-	// Confirm an AuthProvider exists
-	if s.internal.auth == nil {
-		s.Log.Error("AddServiceEndpoint requires the service to be initialized with an AuthProvider")
-		os.Exit(1)
-	}
-
-	// Middleware to wrap the handler for request authentication. It authenticates the request,
-	// injects the relevant service into the handler, and manages the process of sending the response.
-	wrappedHandler := func(w http.ResponseWriter, r *http.Request) {
+	// Guard the handler with request authentication and authorization, so the handler is only
+	// invoked for requests from services that pass both.
+	guardedHandler := func(ctx context.Context, s *Service, r *http.Request) *HTTPResponse {
 		// Authenticate the request
-		authenticated, reason, err := s.internal.auth.Authenticate(r)
+		authenticated, claims, reason, err := s.internal.auth.AuthenticateClaims(r)
 		if err != nil {
 			s.Log.Error("failed to authenticated request", slog.String("error", err.Error()))
-			sendResponse(w, 500, "internal server error")
-			return
+			return internalError().Response()
 		}
 		if !authenticated {
-			message := "unauthorized"
+			message := "authentication is required"
 			if reason != "" {
-				message += ": " + reason
+				message = reason
 			}
-			sendResponse(w, 401, message)
-			return
+			return NewHTTPError(http.StatusUnauthorized, CodeUnauthorized, message).Response()
 		}
 
 		// Verify the request is from a service
 		if isVerified := s.internal.auth.IsServiceRequest(r); !isVerified {
-			sendResponse(w, 403, "forbidden: restricted to services")
-			return
+			return NewHTTPError(http.StatusForbidden, CodeForbidden, "restricted to services").Response()
 		}
+		setPrincipal(r, claims, true)
 
 		// Authorize the request
-		authorized, err := s.internal.auth.Authorize(r, permission)
+		authorized, err := s.internal.auth.Authorize(r, route.Permission)
 		if err != nil {
 			s.Log.Error("failed to authorize request", slog.String("error", err.Error()))
-			sendResponse(w, 500, "internal server error")
-			return
+			return internalError().Response()
 		}
 		if !authorized {
-			sendResponse(w, 403, fmt.Sprintf("Forbidden: Missing required permission '%s'", permission))
-			return
+			return NewHTTPError(http.StatusForbidden, CodeForbidden, "missing required permission '%s'", route.Permission).Response()
 		}
 
-		// Send the request to the handler and handle the response
-		resp := handler(s, r)
-		if resp == nil {
-			sendResponse(w, 501, "internal server error")
-			return
-		}
-		if err := router.SendResponse(w, resp.StatusCode, resp.Headers, resp.Body); err != nil {
-			s.Log.Error("failed to send response", slog.String("error", err.Error()))
-		}
+		// Send the request to the handler
+		return handler(ctx, s, r)
 	}
 
-	// Register the wrapped handler to the router to handle requests on the given relativePath.
-	// Log a fatal error if the handler registration fails.
-	if err := s.internal.router.RegisterHandler(method, relativePath, wrappedHandler); err != nil {
-		s.Log.Error("failed to register handler", slog.String("error", err.Error()), slog.String("method", method), slog.String("relative_path", relativePath))
-		os.Exit(1)
-	}
+	// Register the wrapped handler to the router to handle requests on the given relativePath
+	return s.registerEndpoint(route, guardedHandler, opts)
 }
 
 // AddPubSubEndpoint registers a new POST endpoint at the specified relativePath to handle incoming
 // Pub/Sub messages. In production, it verifies the authenticity of the request, while in
 // local or non-production environments, request verification is skipped.
-func (s *Service) AddPubSubEndpoint(relativePath string, handler EndpointHandler) {
+func (s *Service) AddPubSubEndpoint(relativePath string, handler EndpointHandler, opts ...EndpointOption) error {
 
 	// Sanitize the parameters
-	relativePath = strings.TrimSpace(relativePath)
+	route := newRoute(EndpointPubSub, "POST", relativePath, "")
 
-	// wrappedHandler is the middleware that processes the incoming request.
-	wrappedHandler := func(w http.ResponseWriter, r *http.Request) {
+	// Guard the handler with request verification
+	guardedHandler := func(ctx context.Context, s *Service, r *http.Request) *HTTPResponse {
 
 		// Verify the request if running in a production environment.
 		// This step ensures that the request comes from Google Pub/Sub.
-		if runningInProduction() {
-			if err := pubsub.ValidateGooglePubSubRequest(s.Context, r, ""); err != nil {
-				// Respond with a 403 Forbidden status if verification fails.
-				sendResponse(w, http.StatusForbidden, "forbidden: failed to validate Google ID token")
-				return
-			}
+		if err := s.verifyPubSubRequest(r); err != nil {
+			// Respond with a 403 Forbidden status if verification fails.
+			return NewHTTPError(http.StatusForbidden, CodeForbidden, "failed to validate Google ID token").Response()
 		}
 
-		// Send the request to the handler and handle the response
-		resp := handler(s, r)
-		if resp == nil {
-			sendResponse(w, 500, "internal server error")
-			return
-		}
-		if err := router.SendResponse(w, resp.StatusCode, resp.Headers, resp.Body); err != nil {
-			s.Log.Error("failed to send response", slog.String("error", err.Error()))
-		}
+		// Send the request to the handler
+		return handler(ctx, s, r)
+	}
+
+	// Register the wrapped handler to the router to handle POST requests on the given relativePath
+	return s.registerEndpoint(route, guardedHandler, opts)
+}
+
+// verifyPubSubRequest verifies that the request was pushed by Google Pub/Sub when running in
+// production, and returns nil without verifying it otherwise. The result is recorded in the
+// request's state, so the request is only verified once, however many times it's checked.
+func (s *Service) verifyPubSubRequest(r *http.Request) error {
+	if !runningInProduction() {
+		return nil
+	}
+	state := requestStateFromContext(r.Context())
+	if state != nil && state.pubSub.checked {
+		return state.pubSub.err
+	}
+	err := pubsub.ValidateGooglePubSubRequest(r.Context(), r, "")
+	if state != nil {
+		state.pubSub.err = err
+		state.pubSub.checked = true
 	}
+	return err
+}
 
-	// Register the wrapped handler to the router to handle POST requests on the given relativePath.
-	// Log a fatal error if the handler registration fails.
-	if err := s.internal.router.RegisterHandler("POST", relativePath, wrappedHandler); err != nil {
-		s.Log.Error("failed to register handler", slog.String("error", err.Error()), slog.String("relative_path", relativePath))
-		os.Exit(1)
+// verifiedGoogleHandler guards the handler so that, when running in production, it's only
+// invoked for requests carrying a valid Google ID token, such as those sent by Cloud Tasks,
+// Cloud Scheduler and Cloud Workflows. Verification is skipped in local or non-production environments.
+func verifiedGoogleHandler(handler EndpointHandler) EndpointHandler {
+	return func(ctx context.Context, s *Service, r *http.Request) *HTTPResponse {
+		if runningInProduction() {
+			if err := verifyGoogleRequest(ctx, r); err != nil {
+				// Respond with a 403 Forbidden status if verification fails.
+				return NewHTTPError(http.StatusForbidden, CodeForbidden, "failed to validate Google ID token").Response()
+			}
+		}
+		return handler(ctx, s, r)
 	}
 }
 
@@ -582,10 +543,10 @@ func (s *Service) AddPubSubEndpoint(relativePath string, handler EndpointHandler
 // upgrade process and connection lifecycle. It wraps the provided WebsocketHandler function with
 // middleware to upgrade HTTP requests to WebSocket connections, and automatically closes the connection
 // when the handler completes.
-func (s *Service) AddWebsocketEndpoint(relativePath string, handler WebsocketHandler) {
+func (s *Service) AddWebsocketEndpoint(relativePath string, handler WebsocketHandler) error {
 
 	// Sanitize the parameters
-	relativePath = strings.TrimSpace(relativePath)
+	route := newRoute(EndpointWebsocket, "GET", relativePath, "")
 
 	var upgrader = websocket.Upgrader{
 		ReadBufferSize:  1024,
@@ -599,23 +560,22 @@ func (s *Service) AddWebsocketEndpoint(relativePath string, handler WebsocketHan
 	wrappedHandler := func(w http.ResponseWriter, r *http.Request) {
 		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
-			s.Log.Error("failed to upgrade request to a websocket", slog.String("error", err.Error()), slog.String("relative_path", relativePath))
+			s.Log.Error("failed to upgrade request to a websocket", slog.String("error", err.Error()), slog.String("relative_path", route.Path))
 			return
 		}
 		defer conn.Close()
 		handler(s, conn)
 	}
 
-	// Register the wrapped handler to the router to handle GET requests on the given relativePath.
-	// Log a fatal error if the handler registration fails.
-	if err := s.internal.router.RegisterHandler("GET", relativePath, wrappedHandler); err != nil {
-		s.Log.Error("failed to register websocket handler", slog.String("error", err.Error()), slog.String("relative_path", relativePath))
-		os.Exit(1)
-	}
+	// Register the wrapped handler to the router to handle GET requests on the given relativePath
+	return s.registerRoute(route, wrappedHandler)
 }
 
 // AuthClient returns an *http.Client that automatically attaches JWT tokens to requests
 // and refreshes them as needed. It requires the service to have been initialized with an AuthProvider.
+// Requests created with the context of an inbound request (e.g. with http.NewRequestWithContext)
+// carry the inbound request's ID in their X-Request-ID header, and are sent within a client span
+// whose trace context is propagated in their traceparent and X-Cloud-Trace-Context headers.
 func (s *Service) AuthClient() (*http.Client, error) {
 
 	// Check that the service has an initialized AuthProvider
@@ -629,6 +589,10 @@ func (s *Service) AuthClient() (*http.Client, error) {
 		return nil, fmt.Errorf("failed to create auth client: %w", err)
 	}
 
+	// Propagate the ID and trace context of the request that outbound requests are made on behalf of
+	transport := tracing.NewTransport(client.Transport, s.tracerProvider(), s.internal.propagator)
+	client.Transport = &requestIDTransport{base: transport}
+
 	return client, nil
 }
 
@@ -653,6 +617,24 @@ func (s *Service) AuthClient() (*http.Client, error) {
 // The function uses the CloudTasksClient to create a new task with the specified parameters.
 // The task is authenticated using an OIDC token associated with the configured service account.
 func (s *Service) CreateCloudTask(queue, name, callbackURL string, body []byte, delay, timeout time.Duration) error {
+	return s.CreateCloudTaskContext(s.Context, queue, name, callbackURL, body, delay, timeout)
+}
+
+// CreateCloudTaskContext creates and schedules a new task, as described by CreateCloudTask, using
+// the context to create it. If the context is from an inbound request, the request's ID is sent
+// to the callback URL in the task's X-Request-ID header. The task is created within a producer
+// span, whose trace context is sent in the task's headers, so the task continues the trace.
+func (s *Service) CreateCloudTaskContext(ctx context.Context, queue, name, callbackURL string, body []byte, delay, timeout time.Duration) (err error) {
+	// Ensure the Cloud Tasks client is initialized
+	if s.CloudTasksClient == nil {
+		return errors.New("CloudTasksClient is not initialized")
+	}
+
+	// Ensure a service account is configured to sign the OIDC token
+	if len(s.internal.config.ServiceAccount) == 0 {
+		return errors.New("CreateCloudTask requires a service account to be configured")
+	}
+
 	// Configure the task
 	task := taskspb.Task{
 		MessageType: &taskspb.Task_HttpRequest{
@@ -673,12 +655,38 @@ func (s *Service) CreateCloudTask(queue, name, callbackURL string, body []byte,
 		Name:             name,
 	}
 
+	// Create the task within a span, sending the request's ID and the span's trace context in the task's headers
+	ctx, span := s.Tracer.Start(ctx, "cloudtasks create",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("cloudtasks.queue", queue)),
+	)
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+	headers := map[string]string{}
+	if id := RequestIDFromContext(ctx); id != "" {
+		headers[RequestIDHeader] = id
+	}
+	s.internal.propagator.Inject(ctx, propagation.MapCarrier(headers))
+	if len(headers) > 0 {
+		task.GetHttpRequest().Headers = headers
+	}
+
 	// Create the task
-	_, err := s.CloudTasksClient.CreateTask(s.Context, &taskspb.CreateTaskRequest{
+	_, err = s.CloudTasksClient.CreateTask(ctx, &taskspb.CreateTaskRequest{
 		Parent: queue,
 		Task:   &task,
 	})
-	return err
+	if err != nil {
+		s.internal.metrics.cloudTasksCreated.Inc(queue, "failure")
+		return err
+	}
+	s.internal.metrics.cloudTasksCreated.Inc(queue, "success")
+	return nil
 }
 
 // GenerateGoogleIDToken generates a Google ID token for a given audience.
@@ -759,6 +767,8 @@ func (s *Service) GenerateGoogleIDToken(audience string) (string, error) {
 
 // ParseClaimsFromRequest extracts the JWT from the Authorization header of the request,
 // decodes the payload, and unmarshals it into the provided claims struct WITHOUT VERIFYING THE SIGNATURE.
+// Handlers of authenticated and service endpoints should use Claims instead, which returns the
+// claims of the token that was verified when the request was authenticated.
 func ParseClaimsFromRequest(r *http.Request, claims interface{}) error {
 	// Extract the Bearer token
 	token, ok := auth.ExtractBearerToken(r)
@@ -828,5 +838,49 @@ func ParsePubSubEnvelope(r *http.Request) ([]byte, string, time.Time, error) {
 // PublishToPubSub sends a message to the specified Pub/Sub topic.
 // It returns the message ID or an error if the operation fails.
 func (s *Service) PublishToPubSub(topic string, message interface{}) (string, error) {
-	return s.internal.pubsub.Publish(topic, message)
+	return s.PublishToPubSubContext(s.Context, topic, message)
+}
+
+// PublishToPubSubContext sends a message to the specified Pub/Sub topic, as described by
+// PublishToPubSub. If the context is from an inbound request, the request's ID is published
+// in the message's request_id attribute, and becomes the ID of the request when the message
+// is delivered to an endpoint registered with AddPubSubEndpoint. The message is published
+// within a producer span, whose trace context is published in the message's attributes, so
+// the request the message is delivered in continues the trace.
+func (s *Service) PublishToPubSubContext(ctx context.Context, topic string, message interface{}) (string, error) {
+	if s.internal.pubsub == nil {
+		return "", errors.New("Pub/Sub is not initialized")
+	}
+
+	ctx, span := s.Tracer.Start(ctx, topic+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemGCPPubsub,
+			semconv.MessagingDestinationName(topic),
+			semconv.MessagingOperationTypePublish,
+		),
+	)
+	defer span.End()
+
+	// Publish the request's ID and trace context as attributes of the message
+	attributes := map[string]string{}
+	if id := RequestIDFromContext(ctx); id != "" {
+		attributes[RequestIDAttribute] = id
+	}
+	s.internal.propagator.Inject(ctx, propagation.MapCarrier(attributes))
+
+	var messageID string
+	var err error
+	if publisher, ok := s.internal.pubsub.(pubsub.AttributePublisher); ok && len(attributes) > 0 {
+		messageID, err = publisher.PublishWithAttributes(topic, message, attributes)
+	} else {
+		messageID, err = s.internal.pubsub.Publish(topic, message)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return "", err
+	}
+	span.SetAttributes(semconv.MessagingMessageID(messageID))
+	return messageID, nil
 }
@@ -0,0 +1,88 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/albeebe/service/pkg/metrics"
+)
+
+// MetricsPath is the path of the endpoint that serves the service's metrics in the Prometheus text format.
+const MetricsPath = "/metrics"
+
+// serviceMetrics holds the metrics the service records about itself.
+type serviceMetrics struct {
+	cloudTasksCreated *metrics.Counter   // Counts tasks created with CreateCloudTask, by queue and result
+	logEntriesDropped *metrics.Counter   // Counts log entries dropped because the async logging buffer was full, by level
+	recoveredPanics   *metrics.Counter   // Counts panics recovered from while handling requests
+	requestDuration   *metrics.Histogram // Observes how long requests take to handle, by method and route
+	requests          *metrics.Counter   // Counts requests handled, by method, route and status
+}
+
+// newServiceMetrics registers the metrics the service records about itself in the registry.
+func newServiceMetrics(registry *metrics.Registry) *serviceMetrics {
+	return &serviceMetrics{
+		cloudTasksCreated: registry.Counter("cloudtasks_tasks_created_total",
+			"Number of tasks created with Cloud Tasks, by queue and result.", "queue", "result"),
+		logEntriesDropped: registry.Counter("log_entries_dropped_total",
+			"Number of log entries dropped because the async logging buffer was full, by level.", "level"),
+		recoveredPanics: registry.Counter("http_recovered_panics_total",
+			"Number of panics recovered from while handling requests."),
+		requestDuration: registry.Histogram("http_request_duration_seconds",
+			"Time taken to handle requests, by method and route.", nil, "method", "route"),
+		requests: registry.Counter("http_requests_total",
+			"Number of requests handled, by method, route and status.", "method", "route", "status"),
+	}
+}
+
+// metricsMiddleware returns router middleware that counts every request, and observes how long it
+// takes to handle, by the route it matched. Requests that don't match a route are recorded with
+// the route "unmatched", so unknown paths can't create an unbounded number of series.
+func (s *Service) metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		r, state := withRequestState(r)
+		recorder := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		route := "unmatched"
+		if state.route != nil {
+			route = state.route.Path
+		}
+		s.internal.metrics.requests.Inc(r.Method, route, strconv.Itoa(recorder.Status()))
+		s.internal.metrics.requestDuration.ObserveDuration(time.Since(start), r.Method, route)
+	})
+}
+
+// registerMetricsEndpoint registers the endpoint that serves the service's metrics, unless it
+// was disabled with WithoutMetricsEndpoint. Its requests are excluded from the access log.
+func (s *Service) registerMetricsEndpoint() error {
+	if s.internal.options.withoutMetricsEndpoint {
+		return nil
+	}
+	handler := excludeFromAccessLog(s.Metrics.Handler().ServeHTTP)
+	return s.registerRoute(newRoute(EndpointMetrics, "GET", MetricsPath, ""), handler)
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/albeebe/service"
+	"github.com/albeebe/service/pkg/metrics"
+	"github.com/albeebe/service/servicetest"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	h := servicetest.New(t, servicetest.Config{})
+	orders := h.Service.Metrics.Counter("orders_created_total", "Number of orders created, by channel.", "channel")
+	err := h.Service.AddPublicEndpoint("GET", "/orders/:id", func(ctx context.Context, s *service.Service, r *http.Request) *service.HTTPResponse {
+		orders.Inc("web")
+		return service.Text(http.StatusOK, "ok")
+	})
+	if err != nil {
+		t.Fatalf("failed to add endpoint: %s", err.Error())
+	}
+	if err := h.Service.AddAuthenticatedEndpoint("GET", "/account", "", func(ctx context.Context, s *service.Service, r *http.Request) *service.HTTPResponse {
+		return service.Text(http.StatusOK, "ok")
+	}); err != nil {
+		t.Fatalf("failed to add endpoint: %s", err.Error())
+	}
+
+	h.Do(httptest.NewRequest("GET", "/orders/1", nil))
+	h.Do(httptest.NewRequest("GET", "/orders/2", nil))
+	h.Do(httptest.NewRequest("GET", "/missing/1", nil))
+	h.Do(httptest.NewRequest("GET", "/account", nil))
+	r := httptest.NewRequest("GET", "/account", nil)
+	if err := h.Auth.Authorize(r, servicetest.Claims{Subject: "user-1"}); err != nil {
+		t.Fatalf("failed to authorize request: %s", err.Error())
+	}
+	h.Do(r)
+
+	// Requests are counted by route rather than path, alongside the service's own metrics
+	resp := h.Do(httptest.NewRequest("GET", service.MetricsPath, nil))
+	if contentType := resp.Headers.Get("Content-Type"); resp.StatusCode != http.StatusOK || contentType != metrics.TextContentType {
+		t.Fatalf("expected status %d and content type %q, got %d and %q", http.StatusOK, metrics.TextContentType, resp.StatusCode, contentType)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	for _, line := range []string{
+		"# TYPE http_requests_total counter",
+		`http_requests_total{method="GET",route="/orders/:id",status="200"} 2`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_requests_total{method="GET",route="/account",status="401"} 1`,
+		"# TYPE http_request_duration_seconds histogram",
+		`http_request_duration_seconds_bucket{method="GET",route="/orders/:id",le="+Inf"} 2`,
+		`http_request_duration_seconds_count{method="GET",route="/orders/:id"} 2`,
+		`auth_token_validations_total{result="valid",reason=""} 1`,
+		`orders_created_total{channel="web"} 2`,
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("expected the metrics to contain %q, got:\n%s", line, body)
+		}
+	}
+}
+
+func TestWithoutMetricsEndpoint(t *testing.T) {
+	h := servicetest.New(t, servicetest.Config{Options: []service.Option{service.WithoutMetricsEndpoint()}})
+
+	// The metrics are still recorded, but aren't served
+	if resp := h.Do(httptest.NewRequest("GET", service.MetricsPath, nil)); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+	var text strings.Builder
+	if err := h.Service.Metrics.WriteText(&text); err != nil {
+		t.Fatalf("failed to write metrics: %s", err.Error())
+	}
+	if !strings.Contains(text.String(), `http_requests_total{method="GET",route="unmatched",status="404"} 1`) {
+		t.Errorf("expected the request to be counted, got:\n%s", text.String())
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"reflect"
+
+	"github.com/albeebe/service/pkg/router"
+)
+
+// Middleware wraps an EndpointHandler with additional behavior, such as auditing, recording
+// metrics, or setting response headers. A middleware typically performs some work, calls next,
+// and then inspects or modifies the response it returns.
+//
+// Middleware wraps authentication, so for authenticated, service, Pub/Sub, Cloud Task, Cloud
+// Scheduler and Cloud Workflow endpoints it runs before the caller is verified, and sees responses
+// such as 401 Unauthorized and 403 Forbidden like any other response. The caller's identity is
+// only available once next has been called.
+type Middleware func(next EndpointHandler) EndpointHandler
+
+// EndpointOption configures an individual endpoint when it's registered.
+type EndpointOption func(*endpointOptions)
+
+// endpointOptions holds the configuration applied by the EndpointOptions passed when registering an endpoint.
+type endpointOptions struct {
+	middleware       []Middleware
+	requestType      reflect.Type
+	responseType     reflect.Type
+	withoutAccessLog bool
+}
+
+// WithMiddleware adds middleware to a single endpoint. The middleware runs inside any middleware
+// added to the service with Use, in the order it's provided.
+func WithMiddleware(middleware ...Middleware) EndpointOption {
+	return func(o *endpointOptions) {
+		o.middleware = append(o.middleware, middleware...)
+	}
+}
+
+// Use adds middleware that runs for every endpoint registered with the service, other than
+// websocket and health endpoints, including endpoints that were registered before Use was called.
+// Middleware runs in the order it's added, with the first middleware being the outermost.
+func (s *Service) Use(middleware ...Middleware) {
+	s.internal.middlewareMux.Lock()
+	defer s.internal.middlewareMux.Unlock()
+	s.internal.middleware = append(s.internal.middleware, middleware...)
+}
+
+// registerEndpoint applies the endpoint's options to the route and handler, and registers the
+// resulting handler with the router.
+func (s *Service) registerEndpoint(route Route, handler EndpointHandler, opts []EndpointOption) error {
+	o := &endpointOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	route.Request = o.requestType
+	route.Response = o.responseType
+	handlerFunc := s.endpointHandler(chainMiddleware(handler, o.middleware))
+	if o.withoutAccessLog {
+		handlerFunc = excludeFromAccessLog(handlerFunc)
+	}
+	return s.registerRoute(route, handlerFunc)
+}
+
+// endpointHandler returns an http.HandlerFunc that wraps the handler with the service's middleware,
+// invokes the resulting chain, and sends the response. A nil response from the chain is sent as a
+// 500 Internal Server Error.
+func (s *Service) endpointHandler(handler EndpointHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r, _ = withRequestState(r)
+
+		s.internal.middlewareMux.RLock()
+		middleware := s.internal.middleware
+		s.internal.middlewareMux.RUnlock()
+
+		resp := chainMiddleware(handler, middleware)(r.Context(), s, r)
+		if resp == nil {
+			resp = internalError().Response()
+		}
+		if err := router.SendResponse(w, resp.StatusCode, resp.Headers, resp.Body); err != nil {
+			var p *panicError
+			if errors.As(err, &p) {
+				// The goroutine encoding the response panicked, so raise the panic on the request's
+				// goroutine to be handled like a panic in the handler
+				panic(p)
+			}
+			s.Log.Error("failed to send response", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// chainMiddleware wraps the handler with the middleware, so that the first middleware is the
+// outermost and is invoked first.
+func chainMiddleware(handler EndpointHandler, middleware []Middleware) EndpointHandler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		if middleware[i] != nil {
+			handler = middleware[i](handler)
+		}
+	}
+	return handler
+}
@@ -0,0 +1,198 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/albeebe/service"
+	"github.com/albeebe/service/servicetest"
+)
+
+// middlewareCalls records the order middleware and handlers are invoked in.
+type middlewareCalls struct {
+	mux   sync.Mutex
+	calls []string
+}
+
+// record appends a call to the record.
+func (c *middlewareCalls) record(format string, args ...any) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.calls = append(c.calls, fmt.Sprintf(format, args...))
+}
+
+// reset clears the record, returning the calls recorded since the last reset.
+func (c *middlewareCalls) reset() []string {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	calls := c.calls
+	c.calls = nil
+	return calls
+}
+
+// middleware returns middleware that records when it's entered, along with whether the caller's
+// identity is known at that point, and the status and caller it sees once next returns.
+func (c *middlewareCalls) middleware(name string) service.Middleware {
+	return func(next service.EndpointHandler) service.EndpointHandler {
+		return func(ctx context.Context, s *service.Service, r *http.Request) *service.HTTPResponse {
+			_, known := service.Principal(r)
+			c.record("> %s known=%t", name, known)
+			resp := next(ctx, s, r)
+			identity, _ := service.Principal(r)
+			c.record("< %s %d subject=%q", name, resp.StatusCode, identity.Subject)
+			return resp
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	h := servicetest.New(t, servicetest.Config{})
+	calls := &middlewareCalls{}
+	handler := func(ctx context.Context, s *service.Service, r *http.Request) *service.HTTPResponse {
+		calls.record("handler")
+		return service.Text(http.StatusOK, "ok")
+	}
+	typedHandler := func(ctx context.Context, s *service.Service, req struct{}) (struct{}, error) {
+		calls.record("handler")
+		return struct{}{}, nil
+	}
+	endpointMiddleware := service.WithMiddleware(calls.middleware("C"), calls.middleware("D"))
+
+	// Service middleware is added both before and after the endpoints are registered, and must
+	// apply to all of them either way
+	h.Service.Use(calls.middleware("A"))
+	if err := h.Service.AddPublicEndpoint("GET", "/public", handler, endpointMiddleware); err != nil {
+		t.Fatalf("failed to add public endpoint: %s", err.Error())
+	}
+	if err := h.Service.AddAuthenticatedEndpoint("GET", "/orders", "orders.read", handler, endpointMiddleware); err != nil {
+		t.Fatalf("failed to add authenticated endpoint: %s", err.Error())
+	}
+	if err := service.AddTypedPublicEndpoint(h.Service, "GET", "/typed", typedHandler, endpointMiddleware); err != nil {
+		t.Fatalf("failed to add typed endpoint: %s", err.Error())
+	}
+	if err := h.Service.AddPubSubEndpoint("/events", handler, endpointMiddleware); err != nil {
+		t.Fatalf("failed to add Pub/Sub endpoint: %s", err.Error())
+	}
+	h.Service.Use(calls.middleware("B"))
+
+	tests := []struct {
+		name    string
+		request func(t *testing.T) *http.Request
+		claims  *servicetest.Claims
+		status  int
+		calls   []string
+	}{
+		{
+			name:    "public endpoint",
+			request: func(t *testing.T) *http.Request { return httptest.NewRequest("GET", "/public", nil) },
+			status:  http.StatusOK,
+			calls: []string{
+				"> A known=false", "> B known=false", "> C known=false", "> D known=false",
+				"handler",
+				`< D 200 subject=""`, `< C 200 subject=""`, `< B 200 subject=""`, `< A 200 subject=""`,
+			},
+		},
+		{
+			name:    "authenticated endpoint",
+			request: func(t *testing.T) *http.Request { return httptest.NewRequest("GET", "/orders", nil) },
+			claims:  &servicetest.Claims{Subject: "alice", Permissions: []string{"orders.read"}},
+			status:  http.StatusOK,
+			calls: []string{
+				"> A known=false", "> B known=false", "> C known=false", "> D known=false",
+				"handler",
+				`< D 200 subject="alice"`, `< C 200 subject="alice"`, `< B 200 subject="alice"`, `< A 200 subject="alice"`,
+			},
+		},
+		{
+			name:    "unauthenticated request",
+			request: func(t *testing.T) *http.Request { return httptest.NewRequest("GET", "/orders", nil) },
+			status:  http.StatusUnauthorized,
+			calls: []string{
+				"> A known=false", "> B known=false", "> C known=false", "> D known=false",
+				`< D 401 subject=""`, `< C 401 subject=""`, `< B 401 subject=""`, `< A 401 subject=""`,
+			},
+		},
+		{
+			name:    "unauthorized request",
+			request: func(t *testing.T) *http.Request { return httptest.NewRequest("GET", "/orders", nil) },
+			claims:  &servicetest.Claims{Subject: "bob"},
+			status:  http.StatusForbidden,
+			calls: []string{
+				"> A known=false", "> B known=false", "> C known=false", "> D known=false",
+				`< D 403 subject="bob"`, `< C 403 subject="bob"`, `< B 403 subject="bob"`, `< A 403 subject="bob"`,
+			},
+		},
+		{
+			name:    "typed endpoint",
+			request: func(t *testing.T) *http.Request { return httptest.NewRequest("GET", "/typed", nil) },
+			status:  http.StatusOK,
+			calls: []string{
+				"> A known=false", "> B known=false", "> C known=false", "> D known=false",
+				"handler",
+				`< D 200 subject=""`, `< C 200 subject=""`, `< B 200 subject=""`, `< A 200 subject=""`,
+			},
+		},
+		{
+			name: "Pub/Sub endpoint",
+			request: func(t *testing.T) *http.Request {
+				r, err := servicetest.NewPubSubRequest("/events", map[string]string{"order": "1"})
+				if err != nil {
+					t.Fatalf("failed to create Pub/Sub request: %s", err.Error())
+				}
+				return r
+			},
+			status: http.StatusOK,
+			calls: []string{
+				"> A known=false", "> B known=false", "> C known=false", "> D known=false",
+				"handler",
+				`< D 200 subject=""`, `< C 200 subject=""`, `< B 200 subject=""`, `< A 200 subject=""`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls.reset()
+			r := tt.request(t)
+			if tt.claims != nil {
+				if err := h.Auth.Authorize(r, *tt.claims); err != nil {
+					t.Fatalf("failed to authorize request: %s", err.Error())
+				}
+			}
+			resp := h.Do(r)
+			if resp.StatusCode != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+			if got := calls.reset(); !reflect.DeepEqual(got, tt.calls) {
+				t.Errorf("expected calls %q, got %q", tt.calls, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service
+
+import (
+	"database/sql"
+	"log/slog"
+
+	cloudtasks "cloud.google.com/go/cloudtasks/apiv2"
+	credentials "cloud.google.com/go/iam/credentials/apiv1"
+	"cloud.google.com/go/storage"
+	"github.com/albeebe/service/pkg/logger"
+	"github.com/albeebe/service/pkg/pubsub"
+	"github.com/albeebe/service/pkg/tracing"
+	"golang.org/x/oauth2/google"
+)
+
+// Option configures optional behavior of a service created with New.
+type Option func(*options)
+
+// options holds the settings applied by the Option functions passed to New.
+type options struct {
+	accessLogPolicy        AccessLogPolicy                   // Policy controlling which requests are recorded in the access log
+	asyncLogging           *logger.AsyncPolicy               // Policy of the async logging buffer, entries are delivered by the caller when nil
+	cloudStorageClient     *storage.Client                   // Prebuilt Cloud Storage client to use
+	cloudTasksClient       *cloudtasks.Client                // Prebuilt Cloud Tasks client to use
+	credentials            *google.Credentials               // Credentials to use instead of loading the default credentials
+	db                     *sql.DB                           // Prebuilt database connection to use instead of Cloud SQL
+	iamClient              *credentials.IamCredentialsClient // Prebuilt IAM client to use
+	logger                 *slog.Logger                      // Logger to use instead of the environment's default logger
+	logFile                *logger.RotatingFileConfig        // Rotating file entries are also written to, when set
+	logLevelFile           string                            // File whose log levels are applied whenever it changes
+	publisher              pubsub.Publisher                  // Publisher to use instead of creating a Pub/Sub client
+	shutdownPolicy         ShutdownPolicy                    // Policy controlling how the service shuts down
+	tracing                *tracing.Config                   // Configuration of the tracer provider, spans aren't recorded when nil
+	trustedProxies         int                               // Number of proxies in front of the service whose X-Forwarded-For entries are trusted
+	withoutCloudStorage    bool                              // Skip creating the Cloud Storage client
+	withoutCloudTasks      bool                              // Skip creating the Cloud Tasks client
+	withoutIAMClient       bool                              // Skip creating the IAM client
+	withoutMetricsEndpoint bool                              // Skip serving the metrics endpoint
+	withoutPubSub          bool                              // Skip creating the Pub/Sub client
+}
+
+// WithCredentials uses the provided credentials for every Google Cloud client the service
+// creates, instead of loading the application default credentials.
+func WithCredentials(creds *google.Credentials) Option {
+	return func(o *options) {
+		o.credentials = creds
+	}
+}
+
+// WithLogger uses the provided logger instead of the logger the service would otherwise
+// create for the environment it's running in.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithAsyncLogging delivers the entries of the logger the service creates from a background
+// goroutine, so formatting and delivering them doesn't add to the latency of requests. Entries are
+// buffered as controlled by the policy, and the entries dropped because the buffer was full are
+// counted in the log_entries_dropped_total metric. Entries written to the file configured with
+// WithLogFile share the same buffer and goroutine. Buffered entries are delivered before the
+// service finishes shutting down. It has no effect on a logger provided with WithLogger.
+func WithAsyncLogging(policy logger.AsyncPolicy) Option {
+	return func(o *options) {
+		o.asyncLogging = &policy
+	}
+}
+
+// WithLogFile also writes the entries of the logger the service creates to a rotating file, as
+// structured JSON with the same level and redaction, such as to keep a local copy of the logs on
+// GKE, or a file that can be searched with grep in development. The file is rotated as controlled
+// by the config, and closed when the service shuts down. It has no effect on a logger provided
+// with WithLogger.
+func WithLogFile(config logger.RotatingFileConfig) Option {
+	return func(o *options) {
+		o.logFile = &config
+	}
+}
+
+// WithLogLevelFile watches the file for changes, applying the log levels it contains to the
+// service's LogLevels, so they can be changed by updating a mounted ConfigMap or secret rather than
+// redeploying. The file contains levels in the format accepted by logger.Levels.Apply, separated
+// by commas or newlines (e.g. "info,pubsub=debug"). Removing a level from the file, or removing
+// the file, reverts it. Levels the file doesn't name, such as those changed with the endpoint
+// registered by AddLogLevelEndpoint, are left alone.
+func WithLogLevelFile(path string) Option {
+	return func(o *options) {
+		o.logLevelFile = path
+	}
+}
+
+// WithDB uses the provided database connection instead of connecting to Cloud SQL. When set,
+// the Cloud SQL fields of the Config are ignored.
+func WithDB(db *sql.DB) Option {
+	return func(o *options) {
+		o.db = db
+	}
+}
+
+// WithCloudStorageClient uses the provided Cloud Storage client instead of creating one.
+func WithCloudStorageClient(c *storage.Client) Option {
+	return func(o *options) {
+		o.cloudStorageClient = c
+	}
+}
+
+// WithCloudTasksClient uses the provided Cloud Tasks client instead of creating one.
+func WithCloudTasksClient(c *cloudtasks.Client) Option {
+	return func(o *options) {
+		o.cloudTasksClient = c
+	}
+}
+
+// WithIAMClient uses the provided IAM client instead of creating one.
+func WithIAMClient(c *credentials.IamCredentialsClient) Option {
+	return func(o *options) {
+		o.iamClient = c
+	}
+}
+
+// WithPublisher uses the provided publisher for PublishToPubSub instead of creating a Pub/Sub client.
+func WithPublisher(p pubsub.Publisher) Option {
+	return func(o *options) {
+		o.publisher = p
+	}
+}
+
+// WithAccessLogPolicy controls which requests the service records in its access log, including
+// the fraction of requests that are sampled and the endpoints that are excluded.
+func WithAccessLogPolicy(policy AccessLogPolicy) Option {
+	return func(o *options) {
+		o.accessLogPolicy = policy
+	}
+}
+
+// WithTrustedProxies sets the number of proxies in front of the service that append the address
+// they received a request from to its X-Forwarded-For header. The client's address, as recorded
+// in the access log and on the request's span, is taken from the entry appended by the outermost
+// of them. Defaults to 1, which suits Cloud Run. An external Application Load Balancer appends
+// both the client's address and its own, so it counts as 2. Zero ignores the header, and uses the
+// address the request was received from.
+func WithTrustedProxies(proxies int) Option {
+	return func(o *options) {
+		o.trustedProxies = max(proxies, 0)
+	}
+}
+
+// WithShutdownPolicy controls how the service shuts down once it begins terminating, including
+// how long requests continue to be served after readiness starts failing, and how long the router
+// and the remaining components are given to shut down. Zero values use the defaults.
+func WithShutdownPolicy(policy ShutdownPolicy) Option {
+	return func(o *options) {
+		o.shutdownPolicy = policy
+	}
+}
+
+// WithTracing records a span for every request handled by the service, and for the outbound
+// requests, messages, tasks and database queries made while handling them, and exports them
+// with the configured exporter. The service's name is used when Config.ServiceName is empty.
+func WithTracing(config tracing.Config) Option {
+	return func(o *options) {
+		o.tracing = &config
+	}
+}
+
+// WithoutCloudStorage skips creating the Cloud Storage client.
+func WithoutCloudStorage() Option {
+	return func(o *options) {
+		o.withoutCloudStorage = true
+	}
+}
+
+// WithoutCloudTasks skips creating the Cloud Tasks client. CreateCloudTask returns an error
+// when the service has no Cloud Tasks client.
+func WithoutCloudTasks() Option {
+	return func(o *options) {
+		o.withoutCloudTasks = true
+	}
+}
+
+// WithoutIAMClient skips creating the IAM client. GenerateGoogleIDToken returns an error
+// outside of production when the service has no IAM client.
+func WithoutIAMClient() Option {
+	return func(o *options) {
+		o.withoutIAMClient = true
+	}
+}
+
+// WithoutMetricsEndpoint skips serving the service's metrics at MetricsPath. Metrics are still
+// recorded in the service's registry, so they can be exported in other ways.
+func WithoutMetricsEndpoint() Option {
+	return func(o *options) {
+		o.withoutMetricsEndpoint = true
+	}
+}
+
+// WithoutPubSub skips creating the Pub/Sub client. PublishToPubSub returns an error when
+// the service has no publisher.
+func WithoutPubSub() Option {
+	return func(o *options) {
+		o.withoutPubSub = true
+	}
+}
+
+// WithoutGoogleCloud skips creating every Google Cloud client, so the service can start without
+// any access to Google Cloud. It's equivalent to passing WithoutCloudStorage, WithoutCloudTasks,
+// WithoutIAMClient and WithoutPubSub. Clients provided with other options are still used.
+func WithoutGoogleCloud() Option {
+	return func(o *options) {
+		o.withoutCloudStorage = true
+		o.withoutCloudTasks = true
+		o.withoutIAMClient = true
+		o.withoutPubSub = true
+	}
+}
+
+// createsCloudStorage reports whether the service needs to create its own Cloud Storage client.
+func (o *options) createsCloudStorage() bool {
+	return !o.withoutCloudStorage && o.cloudStorageClient == nil
+}
+
+// createsCloudTasks reports whether the service needs to create its own Cloud Tasks client.
+func (o *options) createsCloudTasks() bool {
+	return !o.withoutCloudTasks && o.cloudTasksClient == nil
+}
+
+// createsIAMClient reports whether the service needs to create its own IAM client.
+func (o *options) createsIAMClient() bool {
+	return !o.withoutIAMClient && o.iamClient == nil
+}
+
+// createsPubSub reports whether the service needs to create its own Pub/Sub client.
+func (o *options) createsPubSub() bool {
+	return !o.withoutPubSub && o.publisher == nil
+}
+
+// needsCredentials reports whether credentials have to be loaded, which is only the
+// case when the service creates at least one Google Cloud client itself.
+func (o *options) needsCredentials() bool {
+	return o.credentials == nil && (o.createsCloudStorage() || o.createsCloudTasks() || o.createsIAMClient() || o.createsPubSub())
+}
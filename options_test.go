@@ -0,0 +1,101 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"github.com/albeebe/service"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+)
+
+func TestClientOptions(t *testing.T) {
+
+	// Default credentials can't be loaded, so the service fails to start if it tries to
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", filepath.Join(t.TempDir(), "missing.json"))
+	creds := &google.Credentials{
+		ProjectID:   "test-project",
+		TokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"}),
+	}
+	client, err := storage.NewClient(context.Background(), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err.Error())
+	}
+	defer client.Close()
+	onlyCloudStorage := []service.Option{service.WithoutCloudTasks(), service.WithoutIAMClient(), service.WithoutPubSub()}
+
+	tests := []struct {
+		name        string
+		options     []service.Option
+		cloudSQL    bool   // cloudSQL is whether the service is configured to connect to Cloud SQL
+		err         string // err is the error expected from New, if any
+		credentials *google.Credentials
+		storage     bool // storage is whether the service is expected to have a Cloud Storage client
+	}{
+		{name: "without Google Cloud", options: []service.Option{service.WithoutGoogleCloud()}},
+		{name: "default credentials", options: onlyCloudStorage, err: "unable to find default credentials"},
+		{name: "provided credentials", options: append([]service.Option{service.WithCredentials(creds)}, onlyCloudStorage...), credentials: creds, storage: true},
+		{name: "provided client", options: append([]service.Option{service.WithCloudStorageClient(client)}, onlyCloudStorage...), storage: true},
+		{name: "without Cloud Storage", options: append([]service.Option{service.WithoutCloudStorage()}, onlyCloudStorage...)},
+		{name: "Cloud SQL with default credentials", options: []service.Option{service.WithoutGoogleCloud()}, cloudSQL: true, err: "failed to register driver"},
+		{name: "credentials without clients", options: []service.Option{service.WithCredentials(creds), service.WithoutGoogleCloud()}, credentials: creds},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := append([]service.Option{service.WithLogger(slog.New(slog.NewJSONHandler(io.Discard, nil)))}, test.options...)
+			config := service.Config{GCPProjectID: "test-project", Host: "localhost:0"}
+			if test.cloudSQL {
+				config.CloudSQLConnection = "test-project:us-central1:orders"
+				config.CloudSQLDatabase = "orders"
+				config.CloudSQLUser = "orders"
+			}
+			s, err := service.New("test-service", config, options...)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected the error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to create service: %s", err.Error())
+			}
+			defer s.Shutdown()
+
+			// Credentials are only loaded when a client needs them, and the provided ones are used as is
+			if s.GoogleCredentials != test.credentials {
+				t.Errorf("expected the credentials %v, got %v", test.credentials, s.GoogleCredentials)
+			}
+			if (s.CloudStorageClient != nil) != test.storage {
+				t.Errorf("expected the service to have a Cloud Storage client: %t, got %v", test.storage, s.CloudStorageClient)
+			}
+		})
+	}
+}
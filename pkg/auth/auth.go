@@ -50,6 +50,10 @@ func New(ctx context.Context, config Config) (*Auth, error) {
 		authProvider: config.AuthProvider,
 		errorChan:    make(chan error),
 		keys:         map[string]*Key{},
+		keyRefreshes: config.Metrics.Counter("auth_key_refreshes_total",
+			"Number of times the keys used to validate tokens were refreshed, by result.", "result"),
+		tokenValidations: config.Metrics.Counter("auth_token_validations_total",
+			"Number of bearer tokens validated, by result and the reason invalid tokens were rejected.", "result", "reason"),
 	}
 
 	return &a, nil
@@ -104,32 +108,45 @@ func (a *Auth) Start() chan error {
 // The reason is only set when the request cannot be authenticated, and it is designed to be sent back
 // to the client to provide feedback on why authentication failed.
 func (a *Auth) Authenticate(r *http.Request) (authenticated bool, reason string, err error) {
+	authenticated, _, reason, err = a.AuthenticateClaims(r)
+	return authenticated, reason, err
+}
+
+// AuthenticateClaims authenticates the request exactly as Authenticate does, and also returns
+// the claims of the verified token when the request is authenticated.
+func (a *Auth) AuthenticateClaims(r *http.Request) (authenticated bool, claims map[string]interface{}, reason string, err error) {
 
 	// Ensure the request is not nil
 	if r == nil {
-		return false, "", errors.New("request is nil")
+		return false, nil, "", errors.New("request is nil")
 	}
 
 	// Extract the bearer token
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		return false, "missing authorization header", nil
+		return false, nil, "missing authorization header", nil
 	}
 	if !strings.HasPrefix(strings.ToLower(authHeader), "bearer ") {
-		return false, "malformed authorization header", nil
+		return false, nil, "malformed authorization header", nil
 	}
 	token := strings.TrimSpace(authHeader[7:])
 	if token == "" {
-		return false, "missing bearer token", nil
+		return false, nil, "missing bearer token", nil
 	}
 
 	// Validate the token
-	isValid, reason, err := a.validateJWT(token)
+	claims, isValid, reason, err := a.validateJWT(token)
 	if err != nil {
-		return false, "", fmt.Errorf("failed to validate jwt: %w", err)
+		a.tokenValidations.Inc("error", "")
+		return false, nil, "", fmt.Errorf("failed to validate jwt: %w", err)
+	}
+	if isValid {
+		a.tokenValidations.Inc("valid", "")
+	} else {
+		a.tokenValidations.Inc("invalid", reason)
 	}
 
-	return isValid, reason, nil
+	return isValid, claims, reason, nil
 }
 
 // Authorize checks if the request meets the given permission.
@@ -151,6 +168,14 @@ func (a *Auth) IsServiceRequest(r *http.Request) bool {
 	return a.authProvider.IsServiceRequest(r)
 }
 
+// KeysLoaded reports whether the keys used to validate tokens have been successfully
+// retrieved from the AuthProvider at least once.
+func (a *Auth) KeysLoaded() bool {
+	a.mux.RLock()
+	defer a.mux.RUnlock()
+	return a.keysLoaded
+}
+
 // ExtractBearerToken extracts the Bearer token from the Authorization header of an HTTP request.
 // It returns the token and a boolean indicating whether the token was successfully extracted.
 func ExtractBearerToken(r *http.Request) (string, bool) {
@@ -121,7 +121,16 @@ func (a *Auth) refreshAccessToken() (*AccessToken, error) {
 
 // refreshKeys fetches new keys from the auth provider, validates them,
 // and updates the internal key map and next refresh time in a thread-safe manner.
-func (a *Auth) refreshKeys() error {
+func (a *Auth) refreshKeys() (err error) {
+
+	// Record the result of the refresh
+	defer func() {
+		if err != nil {
+			a.keyRefreshes.Inc("failure")
+		} else {
+			a.keyRefreshes.Inc("success")
+		}
+	}()
 
 	// Fetch the keys from the auth provider
 	keys, nextRefresh, err := a.authProvider.RefreshKeys()
@@ -144,6 +153,7 @@ func (a *Auth) refreshKeys() error {
 	defer a.mux.Unlock()
 	a.nextKeyRefresh = nextRefresh
 	a.keys = keyMap
+	a.keysLoaded = true
 
 	return nil
 }
@@ -171,11 +181,12 @@ func (a *Auth) shutdown() error {
 
 // validateJWT parses and validates a JWT, ensuring it has the required headers,
 // verifies the token signature against a stored key, and checks for common JWT-related
-// errors such as expiration, malformed tokens, and invalid signatures.
+// errors such as expiration, malformed tokens, and invalid signatures. The token's claims
+// are returned when it's valid.
 // The 'reason' returned is designed to be safe for returning to the client, providing
 // informative yet non-sensitive details about validation failures without exposing
 // internal errors or sensitive information that could assist an attacker.
-func (a *Auth) validateJWT(tokenString string) (isValid bool, reason string, err error) {
+func (a *Auth) validateJWT(tokenString string) (claims map[string]interface{}, isValid bool, reason string, err error) {
 
 	// Parse, validate, and verify the tokens signature
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
@@ -214,32 +225,37 @@ func (a *Auth) validateJWT(tokenString string) (isValid bool, reason string, err
 	if err != nil {
 		switch {
 		case errors.Is(err, jwt.ErrTokenExpired):
-			return false, "token is expired", nil
+			return nil, false, "token is expired", nil
 		case errors.Is(err, jwt.ErrTokenMalformed):
-			return false, "token is malformed", nil
+			return nil, false, "token is malformed", nil
 		case errors.Is(err, jwt.ErrTokenNotValidYet):
-			return false, "token is not valid yet", nil
+			return nil, false, "token is not valid yet", nil
 		case errors.Is(err, jwt.ErrTokenSignatureInvalid):
-			return false, "token signature is invalid", nil
+			return nil, false, "token signature is invalid", nil
 		case errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
-			return false, "token used before being issued", nil
+			return nil, false, "token used before being issued", nil
 		case errors.Is(err, errorAlgInvalid):
-			return false, "value for 'alg' header is invalid", nil
+			return nil, false, "value for 'alg' header is invalid", nil
 		case errors.Is(err, errorAlgMissing):
-			return false, "token header is missing an 'alg' value", nil
+			return nil, false, "token header is missing an 'alg' value", nil
 		case errors.Is(err, errorKidMissing):
-			return false, "token header is missing a 'kid' value", nil
+			return nil, false, "token header is missing a 'kid' value", nil
 		case errors.Is(err, errorKeyNotFound):
-			return false, "key not found", nil
+			return nil, false, "key not found", nil
 		default:
-			return false, "", err
+			return nil, false, "", err
 		}
 	}
 
 	// Check if the token is not valid
 	if !token.Valid {
-		return false, "token is not valid", nil
+		return nil, false, "token is not valid", nil
 	}
 
-	return true, "", nil
+	// Return the verified claims
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, false, "", errors.New("token claims are not a map")
+	}
+	return mapClaims, true, "", nil
 }
@@ -29,6 +29,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/albeebe/service/pkg/metrics"
 	"golang.org/x/sync/singleflight"
 )
 
@@ -37,7 +38,9 @@ type Auth struct {
 	accessToken              *AccessToken       // The current access token
 	authProvider             AuthProvider       // Provider for handling authentication logic
 	errorChan                chan error         // Channel for reporting errors during operations
+	keyRefreshes             *metrics.Counter   // Counts key refreshes by result
 	keys                     map[string]*Key    // Cached keys used for authentication
+	keysLoaded               bool               // Whether keys have been retrieved from the provider at least once
 	mux                      sync.RWMutex       // Mutex for synchronizing access to shared resources
 	nextAccessTokenRefresh   *time.Time         // Time for the next access token refresh
 	nextKeyRefresh           time.Time          // Time for the next key refresh
@@ -45,6 +48,7 @@ type Auth struct {
 	refreshKeysTicker        *time.Ticker       // Ticker for periodic key refresh
 	start                    sync.Once          // Ensures the start logic is executed only once
 	tokenRefresher           singleflight.Group // Group to manage single access token refresh in-flight
+	tokenValidations         *metrics.Counter   // Counts token validations by result and reason
 }
 
 type AuthClient struct {
@@ -53,7 +57,8 @@ type AuthClient struct {
 }
 
 type Config struct {
-	AuthProvider AuthProvider // Provider for authentication logic configuration
+	AuthProvider AuthProvider      // Provider for authentication logic configuration
+	Metrics      *metrics.Registry // Optional registry that key refreshes and token validations are recorded in
 }
 
 type AccessToken struct {
@@ -0,0 +1,280 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+const (
+	// defaultBufferSize is the number of entries an AsyncHandler buffers when its policy doesn't say.
+	defaultBufferSize = 1024
+
+	// defaultBatchSize is the number of entries an AsyncHandler delivers at once when its policy doesn't say.
+	defaultBatchSize = 64
+
+	// maxStackDepth is the number of frames captured for the stack trace of an error logged asynchronously.
+	maxStackDepth = 64
+)
+
+// NewAsyncHandler returns a handler that buffers entries and passes them to the handler in batches
+// from a background goroutine, so logging doesn't add the time taken to format and deliver entries
+// to the caller's latency. The policy must be valid. When the buffer is full, entries are dropped
+// or the caller blocks, as described by the policy's Overflow. Flush waits for every buffered entry
+// to be delivered before flushing the handler.
+//
+// Records are cloned before being buffered, but attribute values that reference mutable data, such
+// as maps or pointers, should not be modified after they're logged. The stack trace of an entry at
+// ERROR level or higher is captured when it's logged, so it describes the caller rather than the
+// goroutine delivering the entry. The goroutine runs until the handler, or any handler derived from
+// it, is closed with Close.
+func NewAsyncHandler(handler slog.Handler, policy AsyncPolicy) *AsyncHandler {
+	if policy.BufferSize <= 0 {
+		policy.BufferSize = defaultBufferSize
+	}
+	if policy.BatchSize <= 0 {
+		policy.BatchSize = defaultBatchSize
+	}
+	if policy.Overflow == "" {
+		policy.Overflow = OverflowDropNewest
+	}
+	state := &asyncState{
+		buffer: make([]asyncEntry, policy.BufferSize),
+		policy: policy,
+	}
+	state.notEmpty = sync.NewCond(&state.mux)
+	state.notFull = sync.NewCond(&state.mux)
+	state.idle = sync.NewCond(&state.mux)
+	state.done = make(chan struct{})
+	go state.deliver()
+	return &AsyncHandler{handler: handler, state: state}
+}
+
+// Enabled reports whether the wrapped handler handles entries at the level.
+func (h *AsyncHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle buffers the entry to be passed to the wrapped handler, capturing the caller's stack if
+// it's an error. When the buffer is full, the entry is handled as described by the policy's Overflow.
+// Once the handler has been closed, the entry is passed to the wrapped handler by the caller.
+func (h *AsyncHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelError && stackFromContext(ctx) == nil {
+		var pcs [maxStackDepth]uintptr
+		n := runtime.Callers(2, pcs[:]) // skip runtime.Callers + Handle
+		ctx = ContextWithStack(ctx, pcs[:n])
+	}
+	if !h.state.enqueue(asyncEntry{ctx: ctx, handler: h.handler, record: r.Clone()}) {
+		return h.handler.Handle(ctx, r)
+	}
+	return nil
+}
+
+// WithAttrs returns a handler that shares the buffer, wrapping the handler with the attributes.
+func (h *AsyncHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &AsyncHandler{handler: h.handler.WithAttrs(attrs), state: h.state}
+}
+
+// WithGroup returns a handler that shares the buffer, wrapping the handler with the group.
+func (h *AsyncHandler) WithGroup(name string) slog.Handler {
+	return &AsyncHandler{handler: h.handler.WithGroup(name), state: h.state}
+}
+
+// Flush waits until every buffered entry has been passed to the wrapped handler, then flushes it.
+func (h *AsyncHandler) Flush() error {
+	h.state.mux.Lock()
+	for h.state.count > 0 || h.state.delivering > 0 {
+		h.state.idle.Wait()
+	}
+	h.state.mux.Unlock()
+	return flushHandler(h.handler)
+}
+
+// Close delivers the buffered entries and stops the goroutine delivering them, returning once it
+// has stopped. It's shared with the handlers derived from this one, and doesn't flush the wrapped
+// handler. Entries logged after the handler has been closed are passed to the wrapped handler by
+// the caller. Closing a handler that has already been closed does nothing.
+func (h *AsyncHandler) Close() error {
+	h.state.mux.Lock()
+	h.state.closed = true
+	h.state.notEmpty.Broadcast()
+	h.state.notFull.Broadcast()
+	h.state.mux.Unlock()
+	<-h.state.done
+	return nil
+}
+
+// Stats returns the number of entries that are buffered, and that have been delivered and dropped.
+func (h *AsyncHandler) Stats() AsyncStats {
+	h.state.mux.Lock()
+	defer h.state.mux.Unlock()
+	return AsyncStats{
+		Buffered:  h.state.count + h.state.delivering,
+		Delivered: h.state.delivered,
+		Dropped:   h.state.dropped,
+	}
+}
+
+// enqueue adds the entry to the buffer, dropping an entry or waiting for room when it's full. It
+// returns false without adding the entry when the handler has been closed.
+func (s *asyncState) enqueue(entry asyncEntry) bool {
+	s.mux.Lock()
+	if s.closed {
+		s.mux.Unlock()
+		return false
+	}
+	var dropped *slog.Level
+	if s.count == len(s.buffer) {
+		switch s.policy.Overflow {
+		case OverflowBlock:
+			for s.count == len(s.buffer) && !s.closed {
+				s.notFull.Wait()
+			}
+			if s.closed {
+				s.mux.Unlock()
+				return false
+			}
+		case OverflowDropOldest:
+			level := s.buffer[s.head].record.Level
+			dropped = &level
+			s.buffer[s.head] = asyncEntry{}
+			s.head = (s.head + 1) % len(s.buffer)
+			s.count--
+		default:
+			level := entry.record.Level
+			s.dropped++
+			s.mux.Unlock()
+			s.drop(level)
+			return true
+		}
+	}
+	if dropped != nil {
+		s.dropped++
+	}
+	s.buffer[(s.head+s.count)%len(s.buffer)] = entry
+	s.count++
+	s.notEmpty.Signal()
+	s.mux.Unlock()
+
+	if dropped != nil {
+		s.drop(*dropped)
+	}
+	return true
+}
+
+// drop reports an entry at the level that was dropped to the policy's OnDrop, if it has one.
+func (s *asyncState) drop(level slog.Level) {
+	if s.policy.OnDrop != nil {
+		s.policy.OnDrop(level)
+	}
+}
+
+// deliver passes the buffered entries to their handlers in batches, waiting for entries when the
+// buffer is empty. It returns once the handler has been closed and the buffer is empty.
+func (s *asyncState) deliver() {
+	defer close(s.done)
+	batch := make([]asyncEntry, 0, s.policy.BatchSize)
+	for {
+		// Take the next batch from the buffer, making room for the callers waiting for it
+		s.mux.Lock()
+		for s.count == 0 && !s.closed {
+			s.notEmpty.Wait()
+		}
+		if s.count == 0 {
+			s.mux.Unlock()
+			return
+		}
+		for s.count > 0 && len(batch) < s.policy.BatchSize {
+			batch = append(batch, s.buffer[s.head])
+			s.buffer[s.head] = asyncEntry{}
+			s.head = (s.head + 1) % len(s.buffer)
+			s.count--
+		}
+		s.delivering = len(batch)
+		s.notFull.Broadcast()
+		s.mux.Unlock()
+
+		// Deliver the batch. Errors are discarded, as slog discards the errors of synchronous handlers.
+		for _, entry := range batch {
+			_ = entry.handler.Handle(entry.ctx, entry.record)
+		}
+
+		s.mux.Lock()
+		s.delivered += uint64(len(batch))
+		s.delivering = 0
+		if s.count == 0 {
+			s.idle.Broadcast()
+		}
+		s.mux.Unlock()
+		clear(batch)
+		batch = batch[:0]
+	}
+}
+
+// stackKey is the key of the stack attached to a context with ContextWithStack.
+type stackKey struct{}
+
+// ContextWithStack returns a copy of the context carrying the program counters of a stack, such as
+// those returned by runtime.Callers. Handlers use it as the stack trace of errors logged with the
+// context, instead of the stack of the goroutine handling the entry. Handlers that log entries on
+// another goroutine, such as AsyncHandler, use it to retain the caller's stack.
+func ContextWithStack(ctx context.Context, pcs []uintptr) context.Context {
+	return context.WithValue(ctx, stackKey{}, pcs)
+}
+
+// stackFromContext returns the stack attached to the context with ContextWithStack, if any.
+func stackFromContext(ctx context.Context) []uintptr {
+	pcs, _ := ctx.Value(stackKey{}).([]uintptr)
+	return pcs
+}
+
+// appFrames returns the frames of the stack, excluding those of slog and this logger package.
+func appFrames(pcs []uintptr) []runtime.Frame {
+	var frames []runtime.Frame
+	iter := runtime.CallersFrames(pcs)
+	for {
+		frame, more := iter.Next()
+		if frame.Function != "" && !strings.Contains(frame.Function, "log/slog") &&
+			!strings.Contains(frame.Function, "github.com/albeebe/service/pkg/logger") {
+			frames = append(frames, frame)
+		}
+		if !more {
+			return frames
+		}
+	}
+}
+
+// formatStack formats the stack in the format of debug.Stack, excluding the frames of slog and
+// this logger package.
+func formatStack(pcs []uintptr) string {
+	var b strings.Builder
+	for _, frame := range appFrames(pcs) {
+		fmt.Fprintf(&b, "%s(...)\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+	}
+	return b.String()
+}
@@ -0,0 +1,280 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAsyncHandlerOverflow(t *testing.T) {
+	tests := []struct {
+		overflow OverflowPolicy
+		expected []string
+	}{
+		{overflow: OverflowDropNewest, expected: []string{"1", "2", "3"}},
+		{overflow: OverflowDropOldest, expected: []string{"1", "3", "4"}},
+	}
+	for _, test := range tests {
+		t.Run(string(test.overflow), func(t *testing.T) {
+			inner := newGateHandler()
+			var dropped []slog.Level
+			handler := NewAsyncHandler(inner, AsyncPolicy{
+				BufferSize: 2,
+				BatchSize:  1,
+				Overflow:   test.overflow,
+				OnDrop:     func(level slog.Level) { dropped = append(dropped, level) },
+			})
+			defer handler.Close()
+			log := slog.New(handler)
+
+			// Hold the first entry in the wrapped handler, then log one more than the buffer holds
+			log.Info("1")
+			<-inner.started
+			log.Info("2")
+			log.Warn("3")
+			log.Warn("4")
+
+			if stats := handler.Stats(); stats.Buffered != 3 || stats.Dropped != 1 {
+				t.Errorf("expected 3 entries buffered and 1 dropped, got %+v", stats)
+			}
+			expectedLevel := slog.LevelWarn
+			if test.overflow == OverflowDropOldest {
+				expectedLevel = slog.LevelInfo
+			}
+			if !slices.Equal(dropped, []slog.Level{expectedLevel}) {
+				t.Errorf("expected OnDrop to be called with %v, got %v", expectedLevel, dropped)
+			}
+
+			close(inner.gate)
+			if err := handler.Flush(); err != nil {
+				t.Fatalf("failed to flush: %s", err.Error())
+			}
+			if messages := inner.Messages(); !slices.Equal(messages, test.expected) {
+				t.Errorf("expected %v to be delivered, got %v", test.expected, messages)
+			}
+			if stats := handler.Stats(); stats.Buffered != 0 || stats.Delivered != 3 || stats.Dropped != 1 {
+				t.Errorf("expected 3 entries delivered and 1 dropped, got %+v", stats)
+			}
+		})
+	}
+}
+
+func TestAsyncHandlerBlock(t *testing.T) {
+	inner := newGateHandler()
+	dropped := 0
+	handler := NewAsyncHandler(inner, AsyncPolicy{
+		BufferSize: 1,
+		BatchSize:  1,
+		Overflow:   OverflowBlock,
+		OnDrop:     func(slog.Level) { dropped++ },
+	})
+	defer handler.Close()
+	log := slog.New(handler)
+
+	log.Info("1")
+	<-inner.started
+	log.Info("2")
+
+	// The buffer is full, so the caller waits for the wrapped handler to make room
+	logged := make(chan struct{})
+	go func() {
+		log.Info("3")
+		close(logged)
+	}()
+	select {
+	case <-logged:
+		t.Fatal("expected the caller to block while the buffer is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(inner.gate)
+	<-logged
+	if err := handler.Flush(); err != nil {
+		t.Fatalf("failed to flush: %s", err.Error())
+	}
+	if messages := inner.Messages(); !slices.Equal(messages, []string{"1", "2", "3"}) {
+		t.Errorf("expected every entry to be delivered in order, got %v", messages)
+	}
+	if stats := handler.Stats(); stats.Dropped != 0 || dropped != 0 {
+		t.Errorf("expected no entries to be dropped, got %+v and %d calls to OnDrop", stats, dropped)
+	}
+}
+
+func TestAsyncHandlerFlush(t *testing.T) {
+	inner := newGateHandler()
+	close(inner.gate)
+	handler := NewAsyncHandler(inner, AsyncPolicy{BufferSize: 100, BatchSize: 8})
+	defer handler.Close()
+	log := slog.New(handler).With("key", "value")
+
+	for range 50 {
+		log.Info("entry")
+	}
+	if err := FlushLogger(log); err != nil {
+		t.Fatalf("failed to flush: %s", err.Error())
+	}
+
+	// Flush returns once every entry has been delivered, and the wrapped handler has been flushed
+	if messages := inner.Messages(); len(messages) != 50 {
+		t.Errorf("expected 50 entries to be delivered, got %d", len(messages))
+	}
+	if stats := handler.Stats(); stats.Buffered != 0 || stats.Delivered != 50 {
+		t.Errorf("expected 50 entries delivered and none buffered, got %+v", stats)
+	}
+	if flushed := inner.Flushed(); flushed != 1 {
+		t.Errorf("expected the wrapped handler to be flushed once, got %d", flushed)
+	}
+}
+
+func TestAsyncHandlerClose(t *testing.T) {
+	inner := newGateHandler()
+	close(inner.gate)
+	handler := NewAsyncHandler(inner, AsyncPolicy{BufferSize: 100, BatchSize: 8})
+	log := slog.New(handler.WithAttrs([]slog.Attr{slog.String("key", "value")}))
+
+	for range 20 {
+		log.Info("before")
+	}
+
+	// Closing delivers the buffered entries and stops the goroutine
+	if err := CloseLogger(log); err != nil {
+		t.Fatalf("failed to close: %s", err.Error())
+	}
+	select {
+	case <-handler.state.done:
+	default:
+		t.Fatal("expected the goroutine to have stopped")
+	}
+	if messages := inner.Messages(); len(messages) != 20 {
+		t.Errorf("expected 20 entries to be delivered, got %d", len(messages))
+	}
+
+	// Entries logged afterwards are delivered by the caller
+	log.Info("after")
+	if messages := inner.Messages(); len(messages) != 21 || messages[20] != "after" {
+		t.Errorf("expected the entry to be delivered by the caller, got %v", messages)
+	}
+	if err := handler.Close(); err != nil {
+		t.Errorf("expected closing again to do nothing, got %s", err.Error())
+	}
+}
+
+func TestCloseLoggerMultiHandler(t *testing.T) {
+	first, second := newGateHandler(), newGateHandler()
+	close(first.gate)
+	close(second.gate)
+	firstAsync := NewAsyncHandler(first, AsyncPolicy{})
+	secondAsync := NewAsyncHandler(second, AsyncPolicy{})
+	log := slog.New(NewMultiHandler(firstAsync, secondAsync, slog.NewJSONHandler(io.Discard, nil)))
+
+	log.Info("entry")
+	if err := CloseLogger(log); err != nil {
+		t.Fatalf("failed to close: %s", err.Error())
+	}
+	for _, handler := range []*AsyncHandler{firstAsync, secondAsync} {
+		select {
+		case <-handler.state.done:
+		default:
+			t.Error("expected each async handler to be closed")
+		}
+	}
+	if len(first.Messages()) != 1 || len(second.Messages()) != 1 {
+		t.Errorf("expected the entry to be delivered to each handler, got %v and %v", first.Messages(), second.Messages())
+	}
+
+	// Handlers that don't implement io.Closer have nothing to close
+	if err := CloseLogger(slog.New(slog.NewJSONHandler(io.Discard, nil))); err != nil {
+		t.Errorf("expected no error, got %s", err.Error())
+	}
+}
+
+// gateHandler is a slog.Handler recording the messages of the entries it handles, which waits for
+// its gate to be closed before handling each one.
+type gateHandler struct {
+	flushed  int           // flushed is the number of times the handler has been flushed.
+	gate     chan struct{} // gate is closed to let entries be handled.
+	messages []string      // messages are the messages of the entries handled.
+	mux      sync.Mutex    // mux protects flushed and messages.
+	started  chan struct{} // started receives a value when the handler starts handling an entry.
+}
+
+// newGateHandler returns a gateHandler that holds entries until its gate is closed.
+func newGateHandler() *gateHandler {
+	return &gateHandler{gate: make(chan struct{}), started: make(chan struct{}, 1)}
+}
+
+// Enabled reports that the handler handles entries at every level.
+func (h *gateHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle records the message of the entry once the gate has been closed.
+func (h *gateHandler) Handle(ctx context.Context, r slog.Record) error {
+	select {
+	case h.started <- struct{}{}:
+	default:
+	}
+	<-h.gate
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	h.messages = append(h.messages, r.Message)
+	return nil
+}
+
+// WithAttrs returns the handler, as the attributes aren't recorded.
+func (h *gateHandler) WithAttrs([]slog.Attr) slog.Handler {
+	return h
+}
+
+// WithGroup returns the handler, as the groups aren't recorded.
+func (h *gateHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// Flush counts the times the handler has been flushed.
+func (h *gateHandler) Flush() error {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	h.flushed++
+	return nil
+}
+
+// Messages returns the messages of the entries handled so far.
+func (h *gateHandler) Messages() []string {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return slices.Clone(h.messages)
+}
+
+// Flushed returns the number of times the handler has been flushed.
+func (h *gateHandler) Flushed() int {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return h.flushed
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package logger
+
+import (
+	"log/slog"
+	"slices"
+)
+
+// attrs holds the attributes and groups accumulated by a handler's WithAttrs and WithGroup
+// methods, and visits them along with a record's attributes once the record is handled.
+type attrs struct {
+	bound  []boundAttr // Attributes added with WithAttrs, in the order they were added
+	groups []string    // Names of the groups opened with WithGroup, outermost first
+}
+
+// boundAttr is an attribute added with WithAttrs, along with the groups that were open when it was added.
+type boundAttr struct {
+	groups []string
+	attr   slog.Attr
+}
+
+// withAttrs returns a copy of the state with the attributes added in the currently open groups.
+func (s attrs) withAttrs(as []slog.Attr) attrs {
+	bound := slices.Clip(s.bound)
+	for _, a := range as {
+		bound = append(bound, boundAttr{groups: s.groups, attr: a})
+	}
+	return attrs{bound: bound, groups: s.groups}
+}
+
+// withGroup returns a copy of the state with the group opened, so that attributes added
+// afterwards are nested within it.
+func (s attrs) withGroup(name string) attrs {
+	return attrs{bound: s.bound, groups: append(slices.Clip(s.groups), name)}
+}
+
+// visit calls fn with every attribute bound to the handler followed by every attribute of
+// the record, along with the names of the groups each is nested in, outermost first. Values
+// are resolved and redacted, groups are flattened, and empty attributes and groups are skipped,
+// so fn is only called for attributes that should be output. The groups passed to fn must not
+// be retained.
+func (s attrs) visit(r slog.Record, rd *redactor, fn func(groups []string, a slog.Attr)) {
+	for _, b := range s.bound {
+		visitAttr(b.groups, b.attr, rd, fn)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		visitAttr(s.groups, a, rd, fn)
+		return true
+	})
+}
+
+// visitAttr calls fn with the attribute, or with each attribute within it if it's a group.
+// Attributes whose keys are redacted are masked without being resolved, including groups.
+func visitAttr(groups []string, a slog.Attr, rd *redactor, fn func(groups []string, a slog.Attr)) {
+	if rd.masksKey(a.Key) {
+		fn(groups, slog.String(a.Key, rd.mask))
+		return
+	}
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() != slog.KindGroup {
+		a.Value = rd.value(a.Value)
+		fn(groups, a)
+		return
+	}
+
+	// Attributes of a group without a key are inlined into the enclosing group
+	if a.Key != "" {
+		groups = append(slices.Clip(groups), a.Key)
+	}
+	for _, ga := range a.Value.Group() {
+		visitAttr(groups, ga, rd, fn)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package logger
+
+import (
+	"context"
+	"log/slog"
+
+	"cloud.google.com/go/logging"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// httpRequestKey is the context key for the request attached with ContextWithHTTPRequest.
+type httpRequestKey struct{}
+
+// ContextWithHTTPRequest returns a copy of the context carrying the request, which
+// GoogleCloudLoggingHandler sends as the httpRequest field of entries logged with the context.
+func ContextWithHTTPRequest(ctx context.Context, req *logging.HTTPRequest) context.Context {
+	return context.WithValue(ctx, httpRequestKey{}, req)
+}
+
+// HTTPRequestFromContext returns the request attached to the context with ContextWithHTTPRequest,
+// or nil if there isn't one.
+func HTTPRequestFromContext(ctx context.Context) *logging.HTTPRequest {
+	req, _ := ctx.Value(httpRequestKey{}).(*logging.HTTPRequest)
+	return req
+}
+
+// WithContext returns a logger that logs entries with the context, unless they're logged with a
+// context carrying a span or request of its own. This binds the trace, span and request of the
+// context to entries logged without one, such as those logged with Info rather than InfoContext.
+func WithContext(l *slog.Logger, ctx context.Context) *slog.Logger {
+	return slog.New(&contextHandler{handler: l.Handler(), ctx: ctx})
+}
+
+// contextHandler is a slog.Handler that handles entries with a bound context, unless they're
+// logged with a context carrying a span or request of its own.
+type contextHandler struct {
+	handler slog.Handler    // Handler the entries are passed to
+	ctx     context.Context // Context bound to the entries
+}
+
+// Enabled reports whether the wrapped handler handles entries at the level.
+func (h *contextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(h.context(ctx), level)
+}
+
+// Handle passes the entry to the wrapped handler, with the bound context unless the entry's
+// context carries a span or request of its own.
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handler.Handle(h.context(ctx), r)
+}
+
+// WithAttrs returns a handler binding the same context to the wrapped handler with the attributes.
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{handler: h.handler.WithAttrs(attrs), ctx: h.ctx}
+}
+
+// WithGroup returns a handler binding the same context to the wrapped handler with the group.
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{handler: h.handler.WithGroup(name), ctx: h.ctx}
+}
+
+// Flush flushes the wrapped handler.
+func (h *contextHandler) Flush() error {
+	return flushHandler(h.handler)
+}
+
+// context returns the context the entry is handled with. The bound context keeps the mark of an
+// entry whose level has been checked by a named logger.
+func (h *contextHandler) context(ctx context.Context) context.Context {
+	if ctx == nil || (!trace.SpanContextFromContext(ctx).IsValid() && HTTPRequestFromContext(ctx) == nil) {
+		if ctx != nil && levelChecked(ctx) {
+			return withLevelChecked(h.ctx)
+		}
+		return h.ctx
+	}
+	return ctx
+}
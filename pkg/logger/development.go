@@ -24,11 +24,17 @@ package logger
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
+	"os"
+	"reflect"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
-	"time"
+	"unicode"
 )
 
 // ANSI color codes for different log levels
@@ -40,78 +46,107 @@ const (
 	ResetColor = "\033[0m"  // Reset to default terminal color
 )
 
+const (
+	// indent is the indentation of each level of the attributes printed beneath an entry.
+	indent = "    "
+
+	// maxDevelopmentFrames is the number of frames of the stack printed beneath an error.
+	maxDevelopmentFrames = 7
+)
+
 // Enabled reports whether the provided log level is enabled for this handler.
 func (h *DevelopmentHandler) Enabled(_ context.Context, level slog.Level) bool {
 	// Returns true if the log level is equal to or higher than the handler's log level.
-	return level >= h.level
+	return level >= minLevel(h.level)
 }
 
-// Handle processes log records for development use, printing them to the console with a timestamp,
-// the appropriate color based on log level, and a reset color afterward. It also includes any
-// structured key-value data associated with the log record. For error logs, it attempts to append
-// the relevant file and line number where the log was generated.
+// Handle processes log records for development use, printing them to the handler's writer with the
+// record's time, in a color based on the log level when the writer is a terminal. Attributes with
+// simple values are printed on the entry's first line (e.g. "order_id=123"), while groups, values
+// spanning several lines, and structs, maps and slices are printed beneath it, indented, with
+// structs, maps and slices formatted as JSON. This includes the attributes added with WithAttrs.
+// For errors, it prints the location the entry was logged from, followed by its callers.
 func (h *DevelopmentHandler) Handle(ctx context.Context, r slog.Record) error {
-	// Format the current time with millisecond precision and include log level in the message
-	timeStamp := time.Now().Format("15:04:05.000")
-	var messageBuilder strings.Builder
-	messageBuilder.WriteString(fmt.Sprintf("[%s] [%s] %s", timeStamp, r.Level.String(), r.Message))
-
-	// Collect structured data from slog.Record using strings.Builder for efficiency
-	var attrsBuilder strings.Builder
-	r.Attrs(func(a slog.Attr) bool {
-		attrsBuilder.WriteString(fmt.Sprintf("%s=%v ", a.Key, a.Value))
-		return true // Continue iterating over all attributes
-	})
-
-	// Combine message with structured data if available
-	attrs := attrsBuilder.String()
-	if attrs != "" {
-		messageBuilder.WriteString(" | " + attrs) // Append structured data to the message
+	// Format the record's time with millisecond precision, if it has one, and include log level in the message
+	var b strings.Builder
+	if !r.Time.IsZero() {
+		b.WriteString(fmt.Sprintf("[%s] ", r.Time.Format("15:04:05.000")))
 	}
+	b.WriteString(fmt.Sprintf("[%s] %s", r.Level.String(), h.redactor.message(r.Message)))
 
-	// If the log level is an error, print the call stack starting from the first frame outside of the logger
-	// This captures and prints multiple stack frames, providing deeper context for where the error originated
-	if r.Level == slog.LevelError {
-		for x := 3; x < 10; x++ { // Limit stack frame search to a reasonable depth
-			_, file, line, ok := runtime.Caller(x)
-			if ok {
-				messageBuilder.WriteString(fmt.Sprintf("\n   └── (file: %s, line: %d)", file, line))
-			}
+	// Print attributes with simple values on the first line, and the rest beneath it, with the
+	// headers of the groups they're nested in
+	var inline []string
+	var block strings.Builder
+	var open []string
+	h.attrs.visit(r, h.redactor, func(groups []string, a slog.Attr) {
+		if len(groups) == 0 && !multiline(a.Value) {
+			inline = append(inline, a.Key+"="+formatInline(a.Value))
+			return
 		}
+		common := 0
+		for common < len(open) && common < len(groups) && open[common] == groups[common] {
+			common++
+		}
+		for i := common; i < len(groups); i++ {
+			block.WriteString("\n" + strings.Repeat(indent, i+1) + groups[i] + ":")
+		}
+		open = append(open[:0], groups...)
+		prefix := strings.Repeat(indent, len(groups)+1)
+		value := formatBlock(a.Value, prefix)
+		if !strings.HasPrefix(value, "\n") {
+			value = " " + value
+		}
+		block.WriteString("\n" + prefix + a.Key + ":" + value)
+	})
+	if len(inline) > 0 {
+		b.WriteString(" | " + strings.Join(inline, " "))
 	}
+	b.WriteString(block.String())
 
-	// Print the log message with the appropriate color based on log level
-	message := messageBuilder.String() // Final message built
-	switch r.Level {
-	case slog.LevelDebug:
-		fmt.Println(DebugColor + message + ResetColor)
-	case slog.LevelInfo:
-		fmt.Println(InfoColor + message + ResetColor)
-	case slog.LevelWarn:
-		fmt.Println(WarnColor + message + ResetColor)
-	case slog.LevelError:
-		fmt.Println(ErrorColor + message + ResetColor)
-	default:
-		fmt.Println(ResetColor + message + ResetColor) // Handle unknown log levels gracefully
+	// If the log level is an error, print the location the entry was logged from and its callers
+	if r.Level >= slog.LevelError {
+		for _, frame := range developmentStack(ctx, r) {
+			b.WriteString(fmt.Sprintf("\n   └── (file: %s, line: %d)", frame.File, frame.Line))
+		}
 	}
 
-	return nil // Return nil as there are no errors to handle in this context
+	// Print the log message with the appropriate color based on log level, unless colors are disabled,
+	// with a single write, so concurrent entries aren't interleaved
+	message := b.String()
+	if h.color {
+		message = levelColor(r.Level) + message + ResetColor
+	}
+	writer := h.writer
+	if writer == nil {
+		writer = os.Stdout
+	}
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	_, err := io.WriteString(writer, message+"\n")
+	return err
 }
 
-// WithAttrs is required to satisfy the slog.Handler interface.
-// This method would typically return a new handler with additional attributes,
-// but since attribute handling is not needed, it returns the original handler unchanged.
+// WithAttrs returns a new handler that prints the attributes with every entry, nested within
+// the groups opened with WithGroup so far.
 func (h *DevelopmentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	// Returning the handler unchanged, as attribute handling is not required.
-	return h
+	if len(attrs) == 0 {
+		return h
+	}
+	clone := *h
+	clone.attrs = h.attrs.withAttrs(attrs)
+	return &clone
 }
 
-// WithGroup is required to satisfy the slog.Handler interface.
-// This method would typically return a new handler that groups log attributes,
-// but since grouping is not needed, it returns the original handler unchanged.
+// WithGroup returns a new handler that nests the attributes added afterwards within the group,
+// both with WithAttrs and with each entry. Groups without any attributes aren't printed.
 func (h *DevelopmentHandler) WithGroup(name string) slog.Handler {
-	// Returning the handler unchanged, as log grouping is not required.
-	return h
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.attrs = h.attrs.withGroup(name)
+	return &clone
 }
 
 // Flush is a required handler method for the slog.Handler interface.
@@ -119,3 +154,120 @@ func (h *DevelopmentHandler) WithGroup(name string) slog.Handler {
 func (h *DevelopmentHandler) Flush() error {
 	return nil
 }
+
+// levelColor returns the color entries at the level are printed in.
+func levelColor(level slog.Level) string {
+	switch {
+	case level < slog.LevelInfo:
+		return DebugColor
+	case level < slog.LevelWarn:
+		return InfoColor
+	case level < slog.LevelError:
+		return WarnColor
+	default:
+		return ErrorColor
+	}
+}
+
+// colorEnabled reports whether entries printed to the writer should be colored, which they are
+// when the writer is a terminal, unless the NO_COLOR environment variable is set (see no-color.org).
+func colorEnabled(writer io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	file, ok := writer.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// multiline reports whether the value is printed beneath the entry rather than on its first line,
+// which strings and errors spanning several lines (such as those joined by errors.Join) are, along
+// with structs, maps and slices that don't format themselves.
+func multiline(v slog.Value) bool {
+	if v.Kind() == slog.KindString {
+		return strings.Contains(v.String(), "\n")
+	}
+	if v.Kind() != slog.KindAny {
+		return false
+	}
+	value := v.Any()
+	if err, ok := value.(error); ok {
+		return strings.Contains(err.Error(), "\n")
+	}
+	if _, ok := value.(fmt.Stringer); ok {
+		return false
+	}
+	switch reflect.Indirect(reflect.ValueOf(value)).Kind() {
+	case reflect.Struct, reflect.Map, reflect.Array:
+		return true
+	case reflect.Slice:
+		_, isBytes := value.([]byte)
+		return !isBytes
+	}
+	return false
+}
+
+// formatInline formats a value printed on an entry's first line, quoting strings and errors that
+// are empty or contain spaces, quotes, equals signs or unprintable characters, so they can't be
+// confused with the attributes around them.
+func formatInline(v slog.Value) string {
+	s := v.String()
+	if v.Kind() == slog.KindString || v.Kind() == slog.KindAny {
+		if s == "" || strings.IndexFunc(s, func(r rune) bool {
+			return unicode.IsSpace(r) || r == '"' || r == '=' || !unicode.IsPrint(r)
+		}) >= 0 {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
+
+// formatBlock formats a value printed beneath an entry, with its lines after the first indented by
+// the prefix. Strings and errors spanning several lines start on a line of their own, with each of
+// their lines indented. Structs, maps and slices are formatted as indented JSON, or with fmt if they
+// can't be. Other values are formatted as they are on the first line.
+func formatBlock(v slog.Value, prefix string) string {
+	if !multiline(v) {
+		return formatInline(v)
+	}
+	value := v.Any()
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+	if s, ok := value.(string); ok {
+		return "\n" + prefix + indent + strings.ReplaceAll(s, "\n", "\n"+prefix+indent)
+	}
+	data, err := json.MarshalIndent(value, prefix, indent)
+	if err != nil {
+		return fmt.Sprintf("%+v", value)
+	}
+	return string(data)
+}
+
+// developmentStack returns the frames printed beneath an error: the frame the entry was logged
+// from, followed by its callers. The stack captured when the entry was logged is used, if it's
+// being handled on another goroutine, otherwise the stack of the goroutine handling it.
+func developmentStack(ctx context.Context, r slog.Record) []runtime.Frame {
+	pcs := stackFromContext(ctx)
+	if pcs == nil {
+		var buf [maxStackDepth]uintptr
+		n := runtime.Callers(3, buf[:]) // skip runtime.Callers + developmentStack + Handle
+		pcs = buf[:n]
+	}
+	frames := appFrames(pcs)
+
+	// Start from the frame the entry was logged from, as recorded by slog
+	if r.PC != 0 {
+		origin, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		i := slices.IndexFunc(frames, func(f runtime.Frame) bool { return f.File == origin.File && f.Line == origin.Line })
+		if i >= 0 {
+			frames = frames[i:]
+		} else {
+			frames = append([]runtime.Frame{origin}, frames...)
+		}
+	}
+	return frames[:min(len(frames), maxDevelopmentFrames)]
+}
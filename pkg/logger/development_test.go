@@ -0,0 +1,230 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDevelopmentHandlerMultiline(t *testing.T) {
+	type order struct {
+		ID    int      `json:"id"`
+		Items []string `json:"items"`
+	}
+	tests := []struct {
+		name     string
+		handler  func(h slog.Handler) slog.Handler
+		attrs    []slog.Attr
+		expected string
+	}{
+		{
+			name:     "inline",
+			attrs:    []slog.Attr{slog.Int("id", 7), slog.String("note", "two words"), slog.String("empty", "")},
+			expected: `[INFO] created | id=7 note="two words" empty=""`,
+		},
+		{
+			name:  "multi-line string",
+			attrs: []slog.Attr{slog.Int("id", 7), slog.String("query", "SELECT *\nFROM orders")},
+			expected: "[INFO] created | id=7\n" +
+				"    query:\n" +
+				"        SELECT *\n" +
+				"        FROM orders",
+		},
+		{
+			name:  "joined errors",
+			attrs: []slog.Attr{slog.Any("error", errors.Join(errors.New("first"), errors.New("second")))},
+			expected: "[INFO] created\n" +
+				"    error:\n" +
+				"        first\n" +
+				"        second",
+		},
+		{
+			name:  "struct",
+			attrs: []slog.Attr{slog.Any("order", order{ID: 7, Items: []string{"a"}})},
+			expected: "[INFO] created\n" +
+				"    order: {\n" +
+				"        \"id\": 7,\n" +
+				"        \"items\": [\n" +
+				"            \"a\"\n" +
+				"        ]\n" +
+				"    }",
+		},
+		{
+			name: "groups",
+			handler: func(h slog.Handler) slog.Handler {
+				return h.WithAttrs([]slog.Attr{slog.String("service", "orders")}).WithGroup("request")
+			},
+			attrs: []slog.Attr{slog.String("method", "GET"), slog.Group("user", slog.String("id", "u1"))},
+			expected: "[INFO] created | service=orders\n" +
+				"    request:\n" +
+				"        method: GET\n" +
+				"        user:\n" +
+				"            id: u1",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			var handler slog.Handler = &DevelopmentHandler{level: slog.LevelInfo, mux: &sync.Mutex{}, writer: &buf}
+			if test.handler != nil {
+				handler = test.handler(handler)
+			}
+			r := slog.NewRecord(time.Time{}, slog.LevelInfo, "created", 0)
+			r.AddAttrs(test.attrs...)
+			if err := handler.Handle(context.Background(), r); err != nil {
+				t.Fatalf("failed to handle entry: %s", err.Error())
+			}
+			if output := strings.TrimSuffix(buf.String(), "\n"); output != test.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", test.expected, output)
+			}
+		})
+	}
+}
+
+func TestDevelopmentHandlerSource(t *testing.T) {
+	var buf bytes.Buffer
+	handler := &DevelopmentHandler{level: slog.LevelInfo, mux: &sync.Mutex{}, writer: &buf}
+	async := NewAsyncHandler(handler, AsyncPolicy{})
+
+	tests := []struct {
+		name    string
+		handler slog.Handler
+	}{
+		{name: "synchronous", handler: handler},
+		{name: "asynchronous", handler: async},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buf.Reset()
+			log := slog.New(test.handler)
+			_, file, line, _ := runtime.Caller(0)
+			log.Error("failed")
+			if err := FlushLogger(log); err != nil {
+				t.Fatalf("failed to flush logger: %s", err.Error())
+			}
+
+			// The first frame is the location the entry was logged from, as recorded by slog
+			lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			expected := fmt.Sprintf("   └── (file: %s, line: %d)", file, line+1)
+			if len(lines) < 2 || lines[1] != expected {
+				t.Errorf("expected the entry's second line to be %q, got:\n%s", expected, buf.String())
+			}
+		})
+	}
+
+	// Entries below the error level don't print their location
+	buf.Reset()
+	slog.New(handler).Warn("warning")
+	if lines := strings.Count(buf.String(), "\n"); lines != 1 {
+		t.Errorf("expected a single line, got:\n%s", buf.String())
+	}
+}
+
+func TestDevelopmentHandlerConcurrency(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(&DevelopmentHandler{level: slog.LevelInfo, mux: &sync.Mutex{}, writer: &buf})
+
+	// Derived loggers share the handler's lock, so their entries aren't interleaved
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			derived := log.With("worker", i)
+			for range 100 {
+				derived.Info("working", "lines", "first\nsecond")
+			}
+		}()
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 4000 {
+		t.Fatalf("expected 4000 lines, got %d", len(lines))
+	}
+	for i := 0; i < len(lines); i += 4 {
+		if !strings.Contains(lines[i], "[INFO] working | worker=") || lines[i+1] != "    lines:" || lines[i+2] != "        first" || lines[i+3] != "        second" {
+			t.Fatalf("entry interleaved with another:\n%s", strings.Join(lines[i:i+4], "\n"))
+		}
+	}
+}
+
+func TestColorEnabled(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "service.log"))
+	if err != nil {
+		t.Fatalf("failed to create file: %s", err.Error())
+	}
+	defer file.Close()
+	terminal, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Skipf("failed to open %s: %s", os.DevNull, err.Error())
+	}
+	defer terminal.Close()
+	if info, err := terminal.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		t.Skipf("%s isn't a character device", os.DevNull)
+	}
+
+	tests := []struct {
+		name     string
+		noColor  string
+		writer   io.Writer
+		expected bool
+	}{
+		{name: "terminal", writer: terminal, expected: true},
+		{name: "terminal with NO_COLOR", noColor: "1", writer: terminal, expected: false},
+		{name: "file", writer: file, expected: false},
+		{name: "buffer", writer: &bytes.Buffer{}, expected: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", test.noColor)
+			if enabled := colorEnabled(test.writer); enabled != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, enabled)
+			}
+		})
+	}
+
+	// Loggers writing to anything other than a terminal aren't colored
+	t.Setenv("NO_COLOR", "")
+	var buf bytes.Buffer
+	log, err := NewDevelopmentLogger(context.Background(), Config{Level: slog.LevelInfo, Writer: &buf})
+	if err != nil {
+		t.Fatalf("failed to create logger: %s", err.Error())
+	}
+	log.Info("uncolored")
+	if strings.Contains(buf.String(), "\x1b[") {
+		t.Errorf("expected no color codes, got %q", buf.String())
+	}
+}
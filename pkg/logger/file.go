@@ -0,0 +1,191 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultMaxFileSize is the size a RotatingFile is rotated at when its config doesn't say.
+	defaultMaxFileSize = 100 << 20
+
+	// defaultMaxBackups is the number of rotated files a RotatingFile keeps when its config doesn't say.
+	defaultMaxBackups = 5
+
+	// backupTimeFormat is the format of the time in the names of rotated files, which sorts chronologically.
+	backupTimeFormat = "20060102T150405.000000000"
+)
+
+// NewRotatingFile opens the file at the config's path for appending, creating it and its directory
+// if they don't exist. The config must be valid. It's typically used as the Writer of a structured
+// logger, or of a slog.JSONHandler, and passed to NewMultiHandler alongside the logger of the
+// environment.
+func NewRotatingFile(config RotatingFileConfig) (*RotatingFile, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid rotating file config: %w", err)
+	}
+	if config.MaxSize == 0 {
+		config.MaxSize = defaultMaxFileSize
+	}
+	if config.MaxBackups == 0 {
+		config.MaxBackups = defaultMaxBackups
+	}
+	if err := os.MkdirAll(filepath.Dir(config.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	f := &RotatingFile{config: config}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write appends the bytes to the file, first rotating it if they would take it beyond its
+// maximum size, or it's older than its maximum age. If the file can't be rotated, the bytes are
+// still appended to it and the error is returned, and rotation is retried by the next write. If
+// the file couldn't be reopened, it's reopened by the next write.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	var rotateErr error
+	tooBig := f.size > 0 && f.size+int64(len(p)) > f.config.MaxSize
+	tooOld := f.config.MaxAge > 0 && time.Since(f.opened) >= f.config.MaxAge
+	if tooBig || tooOld {
+		rotateErr = f.rotate()
+		if f.file == nil {
+			return 0, rotateErr
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, errors.Join(rotateErr, err)
+}
+
+// Flush commits the file's contents to stable storage.
+func (f *RotatingFile) Flush() error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Sync()
+}
+
+// Close closes the file. Writes after it's closed fail with os.ErrClosed.
+func (f *RotatingFile) Close() error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	f.closed = true
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// open opens the file at the config's path for appending. The caller must hold the lock.
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	f.file = file
+	f.opened = time.Now()
+	f.size = info.Size()
+	return nil
+}
+
+// rotate renames the file after the current time, opens a new file in its place, and removes the
+// oldest rotated files beyond the maximum number of backups. If the file can't be renamed, it's
+// reopened so that writes continue to be appended to it. The file is only left closed if it can't
+// be reopened. The caller must hold the lock.
+func (f *RotatingFile) rotate() error {
+	var errs []error
+	if err := f.file.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to close log file: %w", err))
+	}
+	f.file = nil
+	prefix, ext := f.backupPrefix()
+	backup := prefix + time.Now().UTC().Format(backupTimeFormat) + ext
+	if err := os.Rename(f.config.Path, backup); err != nil {
+		errs = append(errs, fmt.Errorf("failed to rotate log file: %w", err))
+	}
+	if err := f.open(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	// Remove the oldest backups, whose names sort first
+	backups, err := filepath.Glob(prefix + "*" + ext)
+	if err != nil {
+		return fmt.Errorf("failed to list rotated log files: %w", err)
+	}
+	backups = slices.DeleteFunc(backups, func(name string) bool {
+		_, err := time.Parse(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext))
+		return err != nil
+	})
+	slices.Sort(backups)
+	for len(backups) > f.config.MaxBackups {
+		if err := os.Remove(backups[0]); err != nil {
+			return fmt.Errorf("failed to remove rotated log file: %w", err)
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// backupPrefix returns the prefix and extension of the names of rotated files, which are named
+// after the file with the time they were rotated before the extension (e.g. "service-<time>.log").
+func (f *RotatingFile) backupPrefix() (prefix, ext string) {
+	ext = filepath.Ext(f.config.Path)
+	return strings.TrimSuffix(f.config.Path, ext) + "-", ext
+}
@@ -0,0 +1,185 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package logger
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFileSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "service.log")
+	f := newTestRotatingFile(t, RotatingFileConfig{Path: path, MaxSize: 10})
+
+	// The file is rotated before a write that would take it beyond its maximum size
+	writeEntries(t, f, "first\n", "second\n", "third\n")
+	backups := rotatedFiles(t, path)
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 rotated files, got %v", backups)
+	}
+	expectContents(t, backups[0], "first\n")
+	expectContents(t, backups[1], "second\n")
+	expectContents(t, path, "third\n")
+
+	// Writes larger than the maximum size are written to a file of their own
+	writeEntries(t, f, "a very long entry\n", "fourth\n")
+	expectContents(t, path, "fourth\n")
+}
+
+func TestRotatingFileAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "service.log")
+	f := newTestRotatingFile(t, RotatingFileConfig{Path: path, MaxAge: time.Hour})
+
+	writeEntries(t, f, "first\n", "second\n")
+	if backups := rotatedFiles(t, path); len(backups) != 0 {
+		t.Fatalf("expected no rotated files, got %v", backups)
+	}
+
+	// The file is rotated once it's older than its maximum age
+	f.opened = f.opened.Add(-time.Hour)
+	writeEntries(t, f, "third\n")
+	backups := rotatedFiles(t, path)
+	if len(backups) != 1 {
+		t.Fatalf("expected 1 rotated file, got %v", backups)
+	}
+	expectContents(t, backups[0], "first\nsecond\n")
+	expectContents(t, path, "third\n")
+}
+
+func TestRotatingFileMaxBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "service.log")
+	f := newTestRotatingFile(t, RotatingFileConfig{Path: path, MaxSize: 4, MaxBackups: 2})
+
+	// Files that aren't rotated files are never removed
+	unrelated := filepath.Join(dir, "service-unrelated.log")
+	if err := os.WriteFile(unrelated, nil, 0o644); err != nil {
+		t.Fatalf("failed to write file: %s", err.Error())
+	}
+
+	// Only the newest rotated files are kept
+	writeEntries(t, f, "one\n", "two\n", "six\n", "ten\n", "end\n")
+	backups := rotatedFiles(t, path)
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 rotated files, got %v", backups)
+	}
+	expectContents(t, backups[0], "six\n")
+	expectContents(t, backups[1], "ten\n")
+	expectContents(t, path, "end\n")
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Errorf("expected %s to be kept: %s", unrelated, err.Error())
+	}
+}
+
+func TestRotatingFileRecovers(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	path := filepath.Join(dir, "service.log")
+	f := newTestRotatingFile(t, RotatingFileConfig{Path: path, MaxSize: 10})
+
+	// The file can't be renamed once it's been removed, so writes continue to a new file
+	writeEntries(t, f, "first\n")
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("failed to remove file: %s", err.Error())
+	}
+	if _, err := f.Write([]byte("second\n")); err == nil {
+		t.Error("expected an error rotating the removed file")
+	}
+	writeEntries(t, f, "third\n")
+	backups := rotatedFiles(t, path)
+	if len(backups) != 1 {
+		t.Fatalf("expected 1 rotated file, got %v", backups)
+	}
+	expectContents(t, backups[0], "second\n")
+	expectContents(t, path, "third\n")
+
+	// The file can't be reopened once its directory has been removed, but is reopened by a later
+	// write once the directory is back
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatalf("failed to remove directory: %s", err.Error())
+	}
+	if _, err := f.Write([]byte("fourth\n")); err == nil {
+		t.Error("expected an error writing to the removed directory")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("failed to create directory: %s", err.Error())
+	}
+	writeEntries(t, f, "fifth\n")
+	expectContents(t, path, "fifth\n")
+
+	// Writes fail once the file is closed
+	if err := f.Close(); err != nil {
+		t.Fatalf("failed to close file: %s", err.Error())
+	}
+	if _, err := f.Write([]byte("sixth\n")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("expected %v, got %v", os.ErrClosed, err)
+	}
+}
+
+// newTestRotatingFile creates a RotatingFile with the config, closing it when the test completes.
+func newTestRotatingFile(t *testing.T, config RotatingFileConfig) *RotatingFile {
+	t.Helper()
+	f, err := NewRotatingFile(config)
+	if err != nil {
+		t.Fatalf("failed to create rotating file: %s", err.Error())
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+// writeEntries writes each of the entries to the file.
+func writeEntries(t *testing.T, f *RotatingFile, entries ...string) {
+	t.Helper()
+	for _, entry := range entries {
+		if _, err := f.Write([]byte(entry)); err != nil {
+			t.Fatalf("failed to write %q: %s", entry, err.Error())
+		}
+	}
+}
+
+// rotatedFiles returns the names of the files rotated from the path, oldest first.
+func rotatedFiles(t *testing.T, path string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(strings.TrimSuffix(path, ".log") + "-2*.log")
+	if err != nil {
+		t.Fatalf("failed to list rotated files: %s", err.Error())
+	}
+	slices.Sort(matches)
+	return matches
+}
+
+// expectContents fails the test if the file doesn't contain exactly the contents.
+func expectContents(t *testing.T, path, contents string) {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", path, err.Error())
+	}
+	if string(b) != contents {
+		t.Errorf("expected %s to contain %q, got %q", filepath.Base(path), contents, b)
+	}
+}
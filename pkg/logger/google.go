@@ -24,58 +24,106 @@ package logger
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"runtime"
 	"runtime/debug"
 	"strings"
 
 	"cloud.google.com/go/logging"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Enabled reports whether the provided log level is enabled for this handler.
 func (h *GoogleCloudLoggingHandler) Enabled(_ context.Context, level slog.Level) bool {
 	// Returns true if the log level is equal to or higher than the handler's log level.
-	return level >= h.level
+	return level >= minLevel(h.level)
 }
 
 // Handle processes a slog.Record by converting it into a Google Cloud Logging entry.
 // It extracts the log message and any associated structured attributes (key-value pairs),
-// maps the slog log level to Google Cloud Logging severity, and forwards the log entry
-// to Google Cloud Logging.
+// including those added with WithAttrs, maps the slog log level to Google Cloud Logging severity,
+// and forwards the log entry to Google Cloud Logging. Groups are sent as nested objects.
+//
+// An attribute created with HTTPRequest outside of any group is sent as the entry's httpRequest field, rather than
+// as one of its attributes. Otherwise, the request attached to the context with
+// ContextWithHTTPRequest is sent. The trace and span of the span in the context, if any, are
+// sent as the entry's trace and spanId fields, so Cloud Logging groups the entries of a trace.
 //
 // NOTE: For Error Reporting ingestion, we add `serviceContext` and `context.reportLocation`
 // when severity is ERROR or higher. We also set Entry.SourceLocation.
 func (h *GoogleCloudLoggingHandler) Handle(ctx context.Context, r slog.Record) error {
+	// Log (omit SourceLocation for broad compatibility)
+	entry := newEntry(ctx, r, h.attrs, h.redactor, h.projectID, h.serviceName, h.serviceVersion)
+	entry.Labels = h.labels
+	h.logger.Log(entry)
+	return nil
+}
+
+// newEntry converts a record, along with the attributes added to the handler, into a Google Cloud
+// Logging entry, as described by GoogleCloudLoggingHandler.Handle. It's shared by the handlers
+// that deliver entries through the API and as structured JSON, so entries are identical either way.
+func newEntry(ctx context.Context, r slog.Record, a attrs, rd *redactor, projectID, serviceName, serviceVersion string) logging.Entry {
 	// 1) attributes
 	attributes := make(map[string]any)
-	r.Attrs(func(a slog.Attr) bool {
-		attributes[a.Key] = a.Value.Any()
-		return true
+	httpRequest := a.httpRequest(r)
+	a.visit(r, rd, func(groups []string, a slog.Attr) {
+		// The request is sent as the entry's httpRequest field instead
+		if httpRequest != nil && len(groups) > 0 && groups[0] == HTTPRequestKey {
+			return
+		}
+		group := attributes
+		for _, name := range groups {
+			nested, ok := group[name].(map[string]any)
+			if !ok {
+				nested = make(map[string]any)
+				group[name] = nested
+			}
+			group = nested
+		}
+		value := a.Value.Any()
+		if err, ok := value.(error); ok {
+			value = err.Error() // Errors are sent as their message, as they're rarely serializable
+		}
+		group[a.Key] = value
 	})
+	if httpRequest == nil {
+		httpRequest = HTTPRequestFromContext(ctx)
+	}
 
-	// 2) source info (Go-version-safe)
+	// 2) source info (Go-version-safe), preferring the location the record was logged from, which
+	// is retained when the record is logged later, such as by a SamplingHandler
 	file, line, function := firstAppFrame()
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		file, line, function = frame.File, frame.Line, frame.Function
+	}
 
-	// 3) stack for errors
+	// 3) stack for errors, preferring the stack captured when the record was logged, if it's
+	// being handled on another goroutine
 	if r.Level >= slog.LevelError {
 		if _, ok := attributes["stack_trace"]; !ok {
-			attributes["stack_trace"] = string(debug.Stack())
+			if pcs := stackFromContext(ctx); pcs != nil {
+				attributes["stack_trace"] = formatStack(pcs)
+			} else {
+				attributes["stack_trace"] = string(debug.Stack())
+			}
 		}
 	}
 
 	// 4) payload
 	payload := map[string]any{
-		"message":    r.Message,
+		"message":    rd.message(r.Message),
 		"attributes": attributes,
 	}
 
 	// 5) serviceContext + reportLocation for Error Reporting
 	if r.Level >= slog.LevelError {
-		service := h.serviceName
+		service := serviceName
 		if service == "" {
 			service = "unknown-service"
 		}
-		version := h.serviceVersion
+		version := serviceVersion
 		if version == "" {
 			version = "0"
 		}
@@ -93,29 +141,44 @@ func (h *GoogleCloudLoggingHandler) Handle(ctx context.Context, r slog.Record) e
 		}
 	}
 
-	// 6) log (omit SourceLocation for broad compatibility)
+	// 6) entry
 	entry := logging.Entry{
-		Severity: h.mapSeverity(r.Level),
-		Payload:  payload,
+		Timestamp:   r.Time,
+		Severity:    mapSeverity(r.Level),
+		Payload:     payload,
+		HTTPRequest: httpRequest,
 	}
-	h.logger.Log(entry)
-	return nil
+
+	// 7) correlate the entry with the trace and span it was logged in, whose name is qualified
+	// with the project ID
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() && projectID != "" {
+		entry.Trace = fmt.Sprintf("projects/%s/traces/%s", projectID, spanContext.TraceID())
+		entry.SpanID = spanContext.SpanID().String()
+		entry.TraceSampled = spanContext.IsSampled()
+	}
+	return entry
 }
 
-// WithAttrs is required to satisfy the slog.Handler interface.
-// This method would typically return a new handler with additional attributes,
-// but since attribute handling is not needed, it returns the original handler unchanged.
+// WithAttrs returns a new handler that sends the attributes with every entry, nested within
+// the groups opened with WithGroup so far.
 func (h *GoogleCloudLoggingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	// Returning the handler unchanged, as attribute handling is not required.
-	return h
+	if len(attrs) == 0 {
+		return h
+	}
+	clone := *h
+	clone.attrs = h.attrs.withAttrs(attrs)
+	return &clone
 }
 
-// WithGroup is required to satisfy the slog.Handler interface.
-// This method would typically return a new handler that groups log attributes,
-// but since grouping is not needed, it returns the original handler unchanged.
+// WithGroup returns a new handler that nests the attributes added afterwards within the group,
+// both with WithAttrs and with each entry. Groups without any attributes aren't sent.
 func (h *GoogleCloudLoggingHandler) WithGroup(name string) slog.Handler {
-	// Returning the handler unchanged, as log grouping is not required.
-	return h
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.attrs = h.attrs.withGroup(name)
+	return &clone
 }
 
 // Flush sends any buffered log entries to Google Cloud Logging and waits for all logs
@@ -126,7 +189,7 @@ func (h *GoogleCloudLoggingHandler) Flush() error {
 }
 
 // mapSeverity maps slog levels to Google Cloud Logging severity levels
-func (h *GoogleCloudLoggingHandler) mapSeverity(level slog.Level) logging.Severity {
+func mapSeverity(level slog.Level) logging.Severity {
 	switch level {
 	case slog.LevelDebug:
 		return logging.Debug
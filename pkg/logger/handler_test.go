@@ -0,0 +1,271 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/slogtest"
+
+	"cloud.google.com/go/logging"
+)
+
+// developmentEntry matches an entry printed by the DevelopmentHandler.
+var developmentEntry = regexp.MustCompile(`^(?:\[([0-9:.]+)\] )?\[([A-Z]+)\] (.*?)(?: \| (.*))?$`)
+
+func TestDevelopmentHandler(t *testing.T) {
+	var buf bytes.Buffer
+	newHandler := func(t *testing.T) slog.Handler {
+		buf.Reset()
+		return &DevelopmentHandler{level: slog.LevelInfo, mux: &sync.Mutex{}, writer: &buf}
+	}
+	result := func(t *testing.T) map[string]any {
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		match := developmentEntry.FindStringSubmatch(lines[0])
+		if match == nil {
+			t.Fatalf("failed to parse entry %q", buf.String())
+		}
+		m := map[string]any{
+			slog.LevelKey:   match[2],
+			slog.MessageKey: match[3],
+		}
+		if match[1] != "" {
+			m[slog.TimeKey] = match[1]
+		}
+
+		// The attributes on the first line aren't in any group
+		for _, field := range strings.Fields(match[4]) {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				t.Fatalf("failed to parse attribute %q", field)
+			}
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			}
+			m[key] = value
+		}
+
+		// The attributes beneath it are nested within the groups whose headers they're indented under
+		groups := []map[string]any{m}
+		for _, line := range lines[1:] {
+			text := strings.TrimLeft(line, " ")
+			depth := (len(line) - len(text)) / len(indent)
+			if depth < 1 || depth > len(groups) {
+				t.Fatalf("unexpected indentation of %q", line)
+			}
+			groups = groups[:depth]
+			if name, ok := strings.CutSuffix(text, ":"); ok {
+				nested, ok := groups[depth-1][name].(map[string]any)
+				if !ok {
+					nested = map[string]any{}
+					groups[depth-1][name] = nested
+				}
+				groups = append(groups, nested)
+				continue
+			}
+			key, value, ok := strings.Cut(text, ": ")
+			if !ok {
+				t.Fatalf("failed to parse attribute %q", line)
+			}
+			groups[depth-1][key] = value
+		}
+		return m
+	}
+	slogtest.Run(t, newHandler, result)
+}
+
+func TestGoogleCloudLoggingHandler(t *testing.T) {
+	logger := &entryRecorder{}
+	newHandler := func(t *testing.T) slog.Handler {
+		logger.reset()
+		return &GoogleCloudLoggingHandler{level: slog.LevelInfo, logger: logger}
+	}
+	result := func(t *testing.T) map[string]any {
+		entries := logger.get()
+		if len(entries) != 1 {
+			t.Fatalf("expected 1 entry, got %d", len(entries))
+		}
+		entry := entries[0]
+		payload := entry.Payload.(map[string]any)
+		m := map[string]any{
+			slog.LevelKey:   entry.Severity.String(),
+			slog.MessageKey: payload["message"],
+		}
+		if !entry.Timestamp.IsZero() {
+			m[slog.TimeKey] = entry.Timestamp
+		}
+		for key, value := range payload["attributes"].(map[string]any) {
+			m[key] = value
+		}
+		return m
+	}
+	slogtest.Run(t, newHandler, result)
+}
+
+func TestStructuredHandler(t *testing.T) {
+	var buf bytes.Buffer
+	newHandler := func(t *testing.T) slog.Handler {
+		buf.Reset()
+		return &StructuredHandler{level: slog.LevelInfo, mux: &sync.Mutex{}, writer: &buf}
+	}
+	result := func(t *testing.T) map[string]any {
+		return structuredResult(t, buf.Bytes())
+	}
+	slogtest.Run(t, newHandler, result)
+}
+
+func TestAsyncHandler(t *testing.T) {
+	var buf bytes.Buffer
+	var handler *AsyncHandler
+	newHandler := func(t *testing.T) slog.Handler {
+		buf.Reset()
+		handler = NewAsyncHandler(&StructuredHandler{level: slog.LevelInfo, mux: &sync.Mutex{}, writer: &buf}, AsyncPolicy{})
+		return handler
+	}
+	result := func(t *testing.T) map[string]any {
+		if err := handler.Flush(); err != nil {
+			t.Fatalf("failed to flush: %s", err.Error())
+		}
+		return structuredResult(t, buf.Bytes())
+	}
+	slogtest.Run(t, newHandler, result)
+}
+
+// credentials is embedded in loginEvent under a name, so it can't be read as a whole.
+type credentials struct {
+	User   string `json:"user"`
+	Secret string `json:"secret" log:"redact"`
+}
+
+type loginEvent struct {
+	credentials `json:"credentials"`
+	Token       string `json:"token" log:"redact"`
+}
+
+type sessionNode struct {
+	ID     string       `json:"id"`
+	Secret string       `json:"secret" log:"redact"`
+	Next   *sessionNode `json:"next"`
+}
+
+func TestHandlerRedactsUnreadableValues(t *testing.T) {
+	cycle := &sessionNode{ID: "a", Secret: "s1", Next: &sessionNode{ID: "b", Secret: "s2"}}
+	cycle.Next.Next = cycle
+	tests := []struct {
+		name     string
+		value    any
+		expected any
+	}{
+		{
+			name:     "embedded unexported struct with a name",
+			value:    loginEvent{credentials: credentials{User: "alice", Secret: "hunter2"}, Token: "abc"},
+			expected: map[string]any{"credentials": map[string]any{"user": "alice", "secret": DefaultRedactionMask}, "token": DefaultRedactionMask},
+		},
+		{
+			name:     "self-referential struct",
+			value:    cycle,
+			expected: map[string]any{"id": "a", "secret": DefaultRedactionMask, "next": map[string]any{"id": "b", "secret": DefaultRedactionMask, "next": nil}},
+		},
+	}
+	newLoggers := map[string]func(config Config) (*slog.Logger, error){
+		"development": func(config Config) (*slog.Logger, error) { return NewDevelopmentLogger(context.Background(), config) },
+		"structured":  func(config Config) (*slog.Logger, error) { return NewStructuredLogger(context.Background(), config) },
+	}
+	for _, test := range tests {
+		for handler, newLogger := range newLoggers {
+			t.Run(test.name+"/"+handler, func(t *testing.T) {
+				var buf bytes.Buffer
+				log, err := newLogger(Config{Writer: &buf, Redaction: &RedactionPolicy{Keys: []string{}}})
+				if err != nil {
+					t.Fatalf("failed to create logger: %s", err.Error())
+				}
+				log.Info("entry", slog.Any("value", test.value))
+				for _, secret := range []string{"hunter2", "abc", "s1", "s2"} {
+					if strings.Contains(buf.String(), secret) {
+						t.Errorf("expected %q to be masked, got %s", secret, buf.String())
+					}
+				}
+				if handler == "structured" {
+					if value := structuredResult(t, buf.Bytes())["value"]; !reflect.DeepEqual(value, test.expected) {
+						t.Errorf("expected %#v, got %#v", test.expected, value)
+					}
+				}
+			})
+		}
+	}
+}
+
+// structuredResult parses an entry written by the StructuredHandler into the map slogtest expects.
+func structuredResult(t *testing.T, entry []byte) map[string]any {
+	var fields map[string]any
+	if err := json.Unmarshal(entry, &fields); err != nil {
+		t.Fatalf("failed to parse entry %q: %s", entry, err.Error())
+	}
+	m := map[string]any{
+		slog.LevelKey:   fields["severity"],
+		slog.MessageKey: fields["message"],
+	}
+	if timestamp, ok := fields["time"]; ok {
+		m[slog.TimeKey] = timestamp
+	}
+	for key, value := range fields["attributes"].(map[string]any) {
+		m[key] = value
+	}
+	return m
+}
+
+// entryRecorder is a cloudLogger that records the entries logged to it.
+type entryRecorder struct {
+	entries []logging.Entry
+	mux     sync.Mutex
+}
+
+func (r *entryRecorder) Log(e logging.Entry) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.entries = append(r.entries, e)
+}
+
+func (r *entryRecorder) Flush() error {
+	return nil
+}
+
+func (r *entryRecorder) get() []logging.Entry {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return append([]logging.Entry(nil), r.entries...)
+}
+
+func (r *entryRecorder) reset() {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.entries = nil
+}
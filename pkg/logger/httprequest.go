@@ -0,0 +1,132 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package logger
+
+import (
+	"log/slog"
+	"strconv"
+
+	"cloud.google.com/go/logging"
+)
+
+// HTTPRequestKey is the key of the attribute created by HTTPRequest.
+const HTTPRequestKey = "httpRequest"
+
+// HTTPRequest returns an attribute describing an HTTP request that was handled or made. The
+// GoogleCloudLoggingHandler sends it as the entry's httpRequest field, so the entry is shown
+// in the Cloud Logging request view. Other handlers log it as a group of the request's method,
+// URL, status, response size, latency, user agent and remote IP.
+func HTTPRequest(req *logging.HTTPRequest) slog.Attr {
+	return slog.Any(HTTPRequestKey, httpRequestValue{req})
+}
+
+// httpRequestValue wraps a logging.HTTPRequest so it can be identified by the
+// GoogleCloudLoggingHandler, and resolved to a group by any other handler.
+type httpRequestValue struct {
+	req *logging.HTTPRequest
+}
+
+// LogValue resolves the request to a group of its most useful fields.
+func (v httpRequestValue) LogValue() slog.Value {
+	if v.req == nil {
+		return slog.GroupValue()
+	}
+	attrs := []slog.Attr{
+		slog.Int("status", v.req.Status),
+		slog.Int64("responseSize", v.req.ResponseSize),
+		slog.Duration("latency", v.req.Latency),
+	}
+	if r := v.req.Request; r != nil {
+		url := ""
+		if r.URL != nil {
+			url = r.URL.String()
+		}
+		attrs = append([]slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("url", url),
+		}, attrs...)
+		attrs = append(attrs, slog.String("userAgent", r.UserAgent()))
+	}
+	if v.req.RemoteIP != "" {
+		attrs = append(attrs, slog.String("remoteIp", v.req.RemoteIP))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// httpRequest returns the request of the HTTPRequest attribute outside of any group, either
+// added with WithAttrs or with the record, or nil if there isn't one.
+func (s attrs) httpRequest(r slog.Record) (req *logging.HTTPRequest) {
+	find := func(a slog.Attr) bool {
+		if v, ok := a.Value.Any().(httpRequestValue); ok {
+			req = v.req
+		}
+		return true
+	}
+	for _, b := range s.bound {
+		if len(b.groups) == 0 {
+			find(b.attr)
+		}
+	}
+	if len(s.groups) == 0 {
+		r.Attrs(find)
+	}
+	return req
+}
+
+// httpRequestFields converts the request to the httpRequest field of a structured log entry,
+// in the JSON representation of the Cloud Logging HttpRequest type.
+func httpRequestFields(req *logging.HTTPRequest) map[string]any {
+	fields := map[string]any{}
+	if r := req.Request; r != nil {
+		fields["requestMethod"] = r.Method
+		if r.URL != nil {
+			fields["requestUrl"] = r.URL.String()
+		}
+		if ua := r.UserAgent(); ua != "" {
+			fields["userAgent"] = ua
+		}
+		if referer := r.Referer(); referer != "" {
+			fields["referer"] = referer
+		}
+		fields["protocol"] = r.Proto
+	}
+	if req.RequestSize > 0 {
+		fields["requestSize"] = strconv.FormatInt(req.RequestSize, 10)
+	}
+	if req.Status > 0 {
+		fields["status"] = req.Status
+	}
+	if req.ResponseSize > 0 {
+		fields["responseSize"] = strconv.FormatInt(req.ResponseSize, 10)
+	}
+	if req.Latency > 0 {
+		fields["latency"] = strconv.FormatFloat(req.Latency.Seconds(), 'f', -1, 64) + "s"
+	}
+	if req.RemoteIP != "" {
+		fields["remoteIp"] = req.RemoteIP
+	}
+	if req.LocalIP != "" {
+		fields["serverIp"] = req.LocalIP
+	}
+	return fields
+}
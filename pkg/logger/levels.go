@@ -0,0 +1,365 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// LoggerKey is the key of the attribute carrying the name of a logger created with Levels.Named.
+const LoggerKey = "logger"
+
+// NewLevels returns log levels whose default level is the level, and that have no overrides.
+func NewLevels(level slog.Level) *Levels {
+	l := &Levels{
+		configured: level,
+		overrides:  make(map[string]*levelOverride),
+	}
+	l.level.Set(level)
+	return l
+}
+
+// Level returns the default level, implementing slog.Leveler.
+func (l *Levels) Level() slog.Level {
+	return l.level.Level()
+}
+
+// Set changes the default level. If the ttl is positive, the default level reverts to the level
+// it was last set to permanently once the ttl elapses, otherwise the change is permanent.
+func (l *Levels) Set(level slog.Level, ttl time.Duration) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.revert != nil {
+		l.revert.Stop()
+		l.revert = nil
+		l.expires = time.Time{}
+	}
+	l.level.Set(level)
+	if ttl <= 0 {
+		l.configured = level
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(ttl, func() {
+		l.mux.Lock()
+		defer l.mux.Unlock()
+		if l.revert == timer {
+			l.level.Set(l.configured)
+			l.revert = nil
+			l.expires = time.Time{}
+		}
+	})
+	l.revert = timer
+	l.expires = time.Now().Add(ttl)
+}
+
+// SetNamed overrides the level of the loggers with the name, and of loggers whose names are
+// nested within it (e.g. "pubsub" overrides "pubsub.publisher" and "pubsub/publisher"). If the ttl
+// is positive, the override is removed once the ttl elapses, otherwise it's permanent.
+func (l *Levels) SetNamed(name string, level slog.Level, ttl time.Duration) {
+	if name == "" {
+		l.Set(level, ttl)
+		return
+	}
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if existing, ok := l.overrides[name]; ok && existing.timer != nil {
+		existing.timer.Stop()
+	}
+	override := &levelOverride{level: level}
+	if ttl > 0 {
+		override.expires = time.Now().Add(ttl)
+		override.timer = time.AfterFunc(ttl, func() {
+			l.mux.Lock()
+			defer l.mux.Unlock()
+			if l.overrides[name] == override {
+				delete(l.overrides, name)
+			}
+		})
+	}
+	l.overrides[name] = override
+}
+
+// Unset removes the override of the loggers with the name. An empty name reverts the default
+// level to the level it was last set to permanently.
+func (l *Levels) Unset(name string) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if name == "" {
+		l.revertDefault()
+		return
+	}
+	if override, ok := l.overrides[name]; ok {
+		if override.timer != nil {
+			override.timer.Stop()
+		}
+		delete(l.overrides, name)
+	}
+}
+
+// Reset reverts the default level to the level it was last set to permanently, and removes
+// every override.
+func (l *Levels) Reset() {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.revertDefault()
+	for name, override := range l.overrides {
+		if override.timer != nil {
+			override.timer.Stop()
+		}
+		delete(l.overrides, name)
+	}
+}
+
+// revertDefault reverts the default level to the level it was last set to permanently. The
+// caller must hold the lock.
+func (l *Levels) revertDefault() {
+	if l.revert != nil {
+		l.revert.Stop()
+		l.revert = nil
+		l.expires = time.Time{}
+	}
+	l.level.Set(l.configured)
+}
+
+// Apply changes the levels as described by the spec, a comma separated list of levels, each
+// optionally prefixed with the name of the loggers it overrides (e.g. "info,pubsub=debug"). A
+// level without a name changes the default level. Levels are parsed by slog.Level.UnmarshalText,
+// so they're case-insensitive and may have an offset (e.g. "debug-2"). The changes revert once
+// the ttl elapses, if it's positive.
+func (l *Levels) Apply(spec string, ttl time.Duration) error {
+	changes, err := parseLevels(spec)
+	if err != nil {
+		return err
+	}
+	for _, c := range changes {
+		l.SetNamed(c.name, c.level, ttl)
+	}
+	return nil
+}
+
+// levelChange is a change to a level, parsed from a spec.
+type levelChange struct {
+	name  string
+	level slog.Level
+}
+
+// parseLevels parses the changes described by the spec, as described by Apply.
+func parseLevels(spec string) ([]levelChange, error) {
+	var changes []levelChange
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		name, text, found := strings.Cut(field, "=")
+		if !found {
+			name, text = "", field
+		}
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(strings.TrimSpace(text))); err != nil {
+			return nil, fmt.Errorf("invalid level %q: %w", field, err)
+		}
+		changes = append(changes, levelChange{name: strings.TrimSpace(name), level: level})
+	}
+	return changes, nil
+}
+
+// Watch applies the spec in the file, as described by Apply, whenever its contents change,
+// checking every interval until the context is canceled. Only the levels named in the file are
+// changed, so overrides made by other means, such as with SetNamed, are left alone unless the file
+// names them too. Removing a level from the file reverts it, as does removing the file, with the
+// default level reverting to the level it had when watching began. Levels in the file may be
+// separated by newlines as well as commas. Errors reading or parsing the file are reported to the
+// callback, if any, and leave the levels unchanged.
+func (l *Levels) Watch(ctx context.Context, path string, interval time.Duration, onError func(error)) {
+	l.mux.RLock()
+	base := l.configured
+	l.mux.RUnlock()
+
+	var last []byte
+	applied := map[string]slog.Level{} // Levels applied from the file, keyed by name
+	check := func() {
+		contents, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			contents, err = nil, nil
+		}
+		if err != nil {
+			if onError != nil {
+				onError(fmt.Errorf("failed to read log levels: %w", err))
+			}
+			return
+		}
+		if bytes.Equal(contents, last) {
+			return
+		}
+		last = contents
+		changes, err := parseLevels(strings.ReplaceAll(string(contents), "\n", ","))
+		if err != nil {
+			if onError != nil {
+				onError(fmt.Errorf("failed to parse log levels in %s: %w", path, err))
+			}
+			return
+		}
+		levels := make(map[string]slog.Level, len(changes))
+		for _, c := range changes {
+			levels[c.name] = c.level
+		}
+
+		// Revert the levels that have been removed from the file, and apply those that changed
+		for name := range applied {
+			if _, ok := levels[name]; ok {
+				continue
+			}
+			if name == "" {
+				l.Set(base, 0)
+			} else {
+				l.Unset(name)
+			}
+		}
+		for name, level := range levels {
+			if previous, ok := applied[name]; !ok || previous != level {
+				l.SetNamed(name, level, 0)
+			}
+		}
+		applied = levels
+	}
+
+	check()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			check()
+		}
+	}
+}
+
+// Snapshot returns the current levels.
+func (l *Levels) Snapshot() LevelsSnapshot {
+	l.mux.RLock()
+	defer l.mux.RUnlock()
+	snapshot := LevelsSnapshot{
+		Level:     l.level.Level(),
+		Overrides: []LevelOverride{},
+	}
+	if !l.expires.IsZero() {
+		expires := l.expires
+		snapshot.Expires = &expires
+	}
+	for name, override := range l.overrides {
+		o := LevelOverride{Name: name, Level: override.level}
+		if !override.expires.IsZero() {
+			expires := override.expires
+			o.Expires = &expires
+		}
+		snapshot.Overrides = append(snapshot.Overrides, o)
+	}
+	slices.SortFunc(snapshot.Overrides, func(a, b LevelOverride) int { return strings.Compare(a.Name, b.Name) })
+	return snapshot
+}
+
+// Named returns a logger whose entries are enabled by the level of the name, which is the level
+// of the most specific override the name is nested within, or the default level if there isn't
+// one. Entries are logged with the name in their logger attribute. The level of the logger's
+// handler is bypassed, so the levels apply to loggers whose handlers have a fixed level, including
+// each of the handlers of a MultiHandler.
+func (l *Levels) Named(logger *slog.Logger, name string) *slog.Logger {
+	handler := logger.Handler().WithAttrs([]slog.Attr{slog.String(LoggerKey, name)})
+	return slog.New(&namedHandler{handler: handler, levels: l, name: name})
+}
+
+// levelOf returns the level of the loggers with the name.
+func (l *Levels) levelOf(name string) slog.Level {
+	l.mux.RLock()
+	defer l.mux.RUnlock()
+	for len(l.overrides) > 0 {
+		if override, ok := l.overrides[name]; ok {
+			return override.level
+		}
+		i := strings.LastIndexAny(name, "./")
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return l.level.Level()
+}
+
+// levelCheckedKey is the context key marking entries whose level has been checked by a named
+// logger, so they're handled whatever the level of the handlers they're passed to.
+type levelCheckedKey struct{}
+
+// withLevelChecked returns a copy of the context marking the entry's level as checked.
+func withLevelChecked(ctx context.Context) context.Context {
+	return context.WithValue(ctx, levelCheckedKey{}, true)
+}
+
+// levelChecked reports whether the context marks the entry's level as checked.
+func levelChecked(ctx context.Context) bool {
+	checked, _ := ctx.Value(levelCheckedKey{}).(bool)
+	return checked
+}
+
+// namedHandler is a slog.Handler that enables entries with the level of its name.
+type namedHandler struct {
+	handler slog.Handler // Handler the entries are passed to
+	levels  *Levels      // Levels the level of the name is looked up in
+	name    string       // Name of the logger
+}
+
+// Enabled reports whether the level is at or above the level of the handler's name.
+func (h *namedHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.levels.levelOf(h.name)
+}
+
+// Handle passes the entry to the wrapped handler, marking its context so that handlers checking
+// the level of each entry again, such as MultiHandler, don't drop it.
+func (h *namedHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handler.Handle(withLevelChecked(ctx), r)
+}
+
+// WithAttrs returns a handler with the same name, wrapping the handler with the attributes.
+func (h *namedHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &namedHandler{handler: h.handler.WithAttrs(attrs), levels: h.levels, name: h.name}
+}
+
+// WithGroup returns a handler with the same name, wrapping the handler with the group.
+func (h *namedHandler) WithGroup(name string) slog.Handler {
+	return &namedHandler{handler: h.handler.WithGroup(name), levels: h.levels, name: h.name}
+}
+
+// Flush flushes the wrapped handler.
+func (h *namedHandler) Flush() error {
+	return flushHandler(h.handler)
+}
@@ -0,0 +1,211 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLevelsNestedNames(t *testing.T) {
+	levels := NewLevels(slog.LevelInfo)
+	levels.SetNamed("pubsub", slog.LevelDebug, 0)
+	levels.SetNamed("pubsub.publisher", slog.LevelWarn, 0)
+
+	tests := []struct {
+		name     string
+		expected slog.Level
+	}{
+		{name: "pubsub", expected: slog.LevelDebug},
+		{name: "pubsub.subscriber", expected: slog.LevelDebug},
+		{name: "pubsub/subscriber/ack", expected: slog.LevelDebug},
+		{name: "pubsub.publisher", expected: slog.LevelWarn},
+		{name: "pubsub.publisher.batch", expected: slog.LevelWarn},
+		{name: "pubsubx", expected: slog.LevelInfo},
+		{name: "storage", expected: slog.LevelInfo},
+		{name: "", expected: slog.LevelInfo},
+	}
+	for _, test := range tests {
+		if level := levels.levelOf(test.name); level != test.expected {
+			t.Errorf("expected %q to have the level %s, got %s", test.name, test.expected, level)
+		}
+	}
+
+	// Named loggers are enabled by the level of their name
+	log := levels.Named(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError})), "pubsub.subscriber")
+	if !log.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("expected the named logger to be enabled at debug")
+	}
+	levels.Unset("pubsub")
+	if log.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("expected the named logger not to be enabled at debug once the override is removed")
+	}
+}
+
+func TestLevelsTTL(t *testing.T) {
+	levels := NewLevels(slog.LevelInfo)
+
+	// Temporary changes revert once their TTL elapses
+	levels.Set(slog.LevelDebug, 20*time.Millisecond)
+	levels.SetNamed("pubsub", slog.LevelDebug, 20*time.Millisecond)
+	levels.SetNamed("storage", slog.LevelWarn, 0)
+	snapshot := levels.Snapshot()
+	if snapshot.Level != slog.LevelDebug || snapshot.Expires == nil || len(snapshot.Overrides) != 2 || snapshot.Overrides[0].Expires == nil || snapshot.Overrides[1].Expires != nil {
+		t.Fatalf("unexpected snapshot %+v", snapshot)
+	}
+	eventually(t, func() bool {
+		snapshot := levels.Snapshot()
+		return snapshot.Level == slog.LevelInfo && len(snapshot.Overrides) == 1
+	})
+	snapshot = levels.Snapshot()
+	if snapshot.Expires != nil || snapshot.Overrides[0].Name != "storage" {
+		t.Errorf("unexpected snapshot %+v", snapshot)
+	}
+
+	// Changing a level again replaces its TTL
+	levels.SetNamed("pubsub", slog.LevelDebug, 20*time.Millisecond)
+	levels.SetNamed("pubsub", slog.LevelDebug, 0)
+	time.Sleep(50 * time.Millisecond)
+	if level := levels.levelOf("pubsub"); level != slog.LevelDebug {
+		t.Errorf("expected the permanent override to remain, got %s", level)
+	}
+
+	// Permanent changes become the level temporary changes revert to
+	levels.Set(slog.LevelWarn, 0)
+	levels.Set(slog.LevelDebug, time.Hour)
+	levels.Reset()
+	if snapshot := levels.Snapshot(); snapshot.Level != slog.LevelWarn || len(snapshot.Overrides) != 0 {
+		t.Errorf("unexpected snapshot after reset %+v", snapshot)
+	}
+}
+
+func TestLevelsApply(t *testing.T) {
+	tests := []struct {
+		spec      string
+		level     slog.Level
+		overrides map[string]slog.Level
+		invalid   bool
+	}{
+		{spec: "", level: slog.LevelInfo, overrides: map[string]slog.Level{}},
+		{spec: "warn", level: slog.LevelWarn, overrides: map[string]slog.Level{}},
+		{spec: " ERROR , pubsub = debug,", level: slog.LevelError, overrides: map[string]slog.Level{"pubsub": slog.LevelDebug}},
+		{spec: "pubsub=debug-2,storage=warn+1", level: slog.LevelInfo, overrides: map[string]slog.Level{"pubsub": slog.LevelDebug - 2, "storage": slog.LevelWarn + 1}},
+		{spec: "pubsub=verbose", invalid: true},
+		{spec: "info,pubsub", invalid: true},
+	}
+	for _, test := range tests {
+		levels := NewLevels(slog.LevelInfo)
+		err := levels.Apply(test.spec, 0)
+		if test.invalid {
+			if err == nil {
+				t.Errorf("expected %q to be invalid", test.spec)
+			}
+			if snapshot := levels.Snapshot(); snapshot.Level != slog.LevelInfo || len(snapshot.Overrides) != 0 {
+				t.Errorf("expected the invalid spec %q not to change the levels, got %+v", test.spec, snapshot)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("failed to apply %q: %s", test.spec, err.Error())
+			continue
+		}
+		snapshot := levels.Snapshot()
+		overrides := map[string]slog.Level{}
+		for _, override := range snapshot.Overrides {
+			overrides[override.Name] = override.Level
+		}
+		if snapshot.Level != test.level || len(overrides) != len(test.overrides) {
+			t.Errorf("unexpected levels for %q: %+v", test.spec, snapshot)
+		}
+		for name, level := range test.overrides {
+			if overrides[name] != level {
+				t.Errorf("expected %q to set %s to %s, got %s", test.spec, name, level, overrides[name])
+			}
+		}
+	}
+}
+
+func TestLevelsWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "levels")
+	levels := NewLevels(slog.LevelInfo)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 10)
+	writeSpec := func(spec string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(spec), 0o644); err != nil {
+			t.Fatalf("failed to write levels: %s", err.Error())
+		}
+	}
+
+	writeSpec("warn\npubsub=debug")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		levels.Watch(ctx, path, 5*time.Millisecond, func(err error) { errs <- err })
+	}()
+	eventually(t, func() bool { return levels.Level() == slog.LevelWarn && levels.levelOf("pubsub") == slog.LevelDebug })
+
+	// Changes made by other means survive the file changing, unless the file names the same level
+	levels.SetNamed("storage", slog.LevelDebug, time.Hour)
+	writeSpec("warn\npubsub=error")
+	eventually(t, func() bool { return levels.levelOf("pubsub") == slog.LevelError })
+	if level := levels.levelOf("storage"); level != slog.LevelDebug {
+		t.Errorf("expected the storage override to survive the file changing, got %s", level)
+	}
+
+	// Invalid files are reported, and leave the levels unchanged
+	writeSpec("warn\npubsub=verbose")
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("expected an error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the invalid file to be reported")
+	}
+	if levels.Level() != slog.LevelWarn || levels.levelOf("pubsub") != slog.LevelError {
+		t.Errorf("expected the levels to be unchanged, got %+v", levels.Snapshot())
+	}
+
+	// Removing the file reverts the levels it named
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("failed to remove levels: %s", err.Error())
+	}
+	eventually(t, func() bool { return levels.Level() == slog.LevelInfo && levels.levelOf("pubsub") == slog.LevelInfo })
+	if level := levels.levelOf("storage"); level != slog.LevelDebug {
+		t.Errorf("expected the storage override to survive the file being removed, got %s", level)
+	}
+
+	// Watching stops once the context is canceled
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected watching to stop once the context was canceled")
+	}
+}
@@ -26,28 +26,47 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"os"
+	"sync"
 
 	"cloud.google.com/go/logging"
 )
 
 // NewDevelopmentLogger sets up a logger for development environments using a custom slog handler.
-// This logger prints log entries to the console in a human-readable format. It includes:
-// - Timestamps with millisecond precision.
+// This logger prints log entries to the configured writer, or the console, in a human-readable format. It includes:
+// - Timestamps with millisecond precision, from the time the entry was logged.
 // - Log levels (DEBUG, INFO, WARN, ERROR) in brackets for easy identification.
 // - The log message itself.
-// - Structured key-value data (attributes) when provided, appended after the message.
-// - For ERROR-level logs, a stack trace is included, showing the file and line number of the error origin.
+// - Structured key-value data (attributes) when provided, with simple values appended after the message, and groups, multi-line values, structs, maps and slices printed beneath it.
+// - For ERROR-level logs, a stack trace is included, starting from the file and line number the entry was logged from.
+// - Colors by log level when printing to a terminal, unless the NO_COLOR environment variable is set.
 // This logging setup is useful for local development as it makes it easier to spot issues,
 // read structured data, and debug errors directly from the console output.
 func NewDevelopmentLogger(ctx context.Context, config Config) (*slog.Logger, error) {
-	// Create a custom slog handler for development logging
+	// Validate the provided configuration
+	if err := validatePolicies(config); err != nil {
+		return nil, err
+	}
+
+	// Print to standard output unless a writer was provided
+	writer := config.Writer
+	if writer == nil {
+		writer = os.Stdout
+	}
+
+	// Create a custom slog handler for development logging, colored when printing to a terminal
 	handler := &DevelopmentHandler{
-		level: config.Level, // Set the logging level based on the provided config
+		color:    colorEnabled(writer),
+		level:    config.Level, // Set the logging level based on the provided config
+		mux:      &sync.Mutex{},
+		redactor: newRedactor(config.Redaction),
+		writer:   writer,
 	}
 
 	// Return a new slog.Logger using the custom development handler
-	return slog.New(handler), nil
+	return newLogger(handler, config), nil
 }
 
 // NewGoogleCloudLogger sets up a logger for Google Cloud Logging.
@@ -61,9 +80,12 @@ func NewGoogleCloudLogger(ctx context.Context, config Config) (*slog.Logger, err
 	if config.LogName == "" {
 		return nil, errors.New("log name is missing in config")
 	}
+	if err := validatePolicies(config); err != nil {
+		return nil, err
+	}
 
 	// Initialize Google Cloud Logging client with the provided context
-	client, err := logging.NewClient(ctx, config.GCPProjectID)
+	client, err := logging.NewClient(ctx, config.GCPProjectID, config.ClientOptions...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Google Cloud Logging client: %w", err)
 	}
@@ -75,32 +97,127 @@ func NewGoogleCloudLogger(ctx context.Context, config Config) (*slog.Logger, err
 	handler := &GoogleCloudLoggingHandler{
 		logger:         googleLogger,
 		level:          config.Level, // Set the logging level based on the provided config
+		labels:         config.Labels,
+		projectID:      config.GCPProjectID,
+		redactor:       newRedactor(config.Redaction),
 		serviceName:    config.ServiceName,
 		serviceVersion: config.ServiceVersion,
 	}
 
 	// Return a new slog.Logger using the custom Google Cloud Logging handler
-	return slog.New(handler), nil
+	return newLogger(handler, config), nil
+}
+
+// NewStructuredLogger sets up a logger that writes entries as JSON in the structured logging format
+// to the configured writer, or standard output. On Cloud Run, GKE and Compute Engine, the logging agent
+// ingests these entries into Google Cloud Logging, without the network calls, buffering and flushing
+// of the Google Cloud Logging client. Entries are only correlated with traces when the configuration
+// has a GCP project ID, which trace names are qualified with.
+func NewStructuredLogger(ctx context.Context, config Config) (*slog.Logger, error) {
+	// Validate the provided configuration
+	if err := validatePolicies(config); err != nil {
+		return nil, err
+	}
+
+	// Write to standard output unless a writer was provided
+	writer := config.Writer
+	if writer == nil {
+		writer = os.Stdout
+	}
+
+	// Create a custom slog handler for structured logging
+	handler := &StructuredHandler{
+		labels:         config.Labels,
+		level:          config.Level, // Set the logging level based on the provided config
+		mux:            &sync.Mutex{},
+		projectID:      config.GCPProjectID,
+		redactor:       newRedactor(config.Redaction),
+		serviceName:    config.ServiceName,
+		serviceVersion: config.ServiceVersion,
+		writer:         writer,
+	}
+
+	// Return a new slog.Logger using the custom structured logging handler
+	return newLogger(handler, config), nil
 }
 
 // FlushLogger attempts to flush the logs for the provided slog.Logger.
-// It supports flushing for loggers using either GoogleCloudLoggingHandler or DevelopmentHandler.
+// It supports flushing for loggers whose handler implements Flusher, which includes every handler
+// in this package, and the loggers returned by WithContext and Levels.Named.
 // If the logger does not support flushing, an error is returned.
 func FlushLogger(l *slog.Logger) error {
 	if l == nil {
 		return errors.New("logger is nil")
 	}
+	return flushHandler(l.Handler())
+}
 
-	// Attempt to flush if the handler is GoogleCloudLoggingHandler
-	if handler, ok := l.Handler().(*GoogleCloudLoggingHandler); ok {
-		return handler.Flush()
-	}
-
-	// Attempt to flush if the handler is DevelopmentHandler
-	if handler, ok := l.Handler().(*DevelopmentHandler); ok {
-		return handler.Flush()
+// flushHandler flushes the handler, if it implements Flusher.
+func flushHandler(handler slog.Handler) error {
+	if flusher, ok := handler.(Flusher); ok {
+		return flusher.Flush()
 	}
 
 	// Return an error because the logger does not support flushing
 	return errors.New("logger does not support flushing")
 }
+
+// CloseLogger releases the resources of the provided slog.Logger, such as the goroutine delivering
+// the entries of an AsyncHandler, once it's no longer used. Entries that are still buffered are
+// delivered first. Loggers whose handler doesn't implement io.Closer have nothing to release, and
+// are left as they are.
+func CloseLogger(l *slog.Logger) error {
+	if l == nil {
+		return errors.New("logger is nil")
+	}
+	return closeHandler(l.Handler())
+}
+
+// closeHandler closes the handler, if it implements io.Closer.
+func closeHandler(handler slog.Handler) error {
+	if closer, ok := handler.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// newLogger returns a logger using the handler, wrapped in a SamplingHandler when the
+// configuration has a sampling policy, and in an AsyncHandler when it has an async policy.
+// Entries are sampled before they're buffered, so those that won't be logged don't take up room.
+func newLogger(handler slog.Handler, config Config) *slog.Logger {
+	if config.Sampling != nil {
+		handler = NewSamplingHandler(handler, *config.Sampling)
+	}
+	if config.Async != nil {
+		handler = NewAsyncHandler(handler, *config.Async)
+	}
+	return slog.New(handler)
+}
+
+// validatePolicies validates the sampling, redaction and async policies of the configuration, if it has them.
+func validatePolicies(config Config) error {
+	if config.Sampling != nil {
+		if err := config.Sampling.Validate(); err != nil {
+			return fmt.Errorf("invalid sampling policy: %w", err)
+		}
+	}
+	if config.Redaction != nil {
+		if err := config.Redaction.Validate(); err != nil {
+			return fmt.Errorf("invalid redaction policy: %w", err)
+		}
+	}
+	if config.Async != nil {
+		if err := config.Async.Validate(); err != nil {
+			return fmt.Errorf("invalid async policy: %w", err)
+		}
+	}
+	return nil
+}
+
+// minLevel returns the minimum level of a handler, which is INFO when the handler has no level.
+func minLevel(level slog.Leveler) slog.Level {
+	if level == nil {
+		return slog.LevelInfo
+	}
+	return level.Level()
+}
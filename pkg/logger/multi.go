@@ -0,0 +1,114 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package logger
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// NewMultiHandler returns a handler that passes every entry to each of the handlers whose level
+// it's at or above, such as to log to Google Cloud Logging and to a file at the same time. Each
+// handler keeps its own level, attributes and groups.
+//
+// Example:
+//
+//	file, _ := logger.NewRotatingFile(logger.RotatingFileConfig{Path: "/var/log/service/service.log"})
+//	console, _ := logger.NewDevelopmentLogger(ctx, logger.Config{Level: slog.LevelDebug})
+//	log := slog.New(logger.NewMultiHandler(console.Handler(), slog.NewJSONHandler(file, nil)))
+func NewMultiHandler(handlers ...slog.Handler) *MultiHandler {
+	return &MultiHandler{handlers: handlers}
+}
+
+// Enabled reports whether any of the handlers handles entries at the level.
+func (h *MultiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle passes the entry to each of the handlers that handles entries at its level, returning
+// the errors of any that failed. A handler failing doesn't stop the entry being passed to the rest.
+// Entries logged with a logger returned by Levels.Named are passed to every handler, since their
+// level has already been checked against the level of the logger's name.
+func (h *MultiHandler) Handle(ctx context.Context, r slog.Record) error {
+	checked := levelChecked(ctx)
+	var errs []error
+	for _, handler := range h.handlers {
+		if !checked && !handler.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := handler.Handle(ctx, r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs returns a handler that passes entries to each of the handlers with the attributes.
+func (h *MultiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return &MultiHandler{handlers: handlers}
+}
+
+// WithGroup returns a handler that passes entries to each of the handlers with the group.
+func (h *MultiHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return &MultiHandler{handlers: handlers}
+}
+
+// Flush flushes each of the handlers that implements Flusher, returning the errors of any that
+// failed. Handlers that don't implement it, such as slog.JSONHandler, are skipped.
+func (h *MultiHandler) Flush() error {
+	var errs []error
+	for _, handler := range h.handlers {
+		if flusher, ok := handler.(Flusher); ok {
+			if err := flusher.Flush(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes each of the handlers that implements io.Closer, such as AsyncHandler, returning the
+// errors of any that failed. Handlers that don't implement it are skipped.
+func (h *MultiHandler) Close() error {
+	var errs []error
+	for _, handler := range h.handlers {
+		if err := closeHandler(handler); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestMultiHandler(t *testing.T) {
+	var info, warn bytes.Buffer
+	log := slog.New(NewMultiHandler(
+		slog.NewJSONHandler(&info, &slog.HandlerOptions{Level: slog.LevelInfo}),
+		slog.NewJSONHandler(&warn, &slog.HandlerOptions{Level: slog.LevelWarn}),
+	)).With("component", "test")
+
+	log.Debug("debug")
+	log.Info("info")
+	log.Warn("warn")
+
+	if lines := strings.Count(info.String(), "\n"); lines != 2 {
+		t.Errorf("expected 2 entries at info, got %d: %s", lines, info.String())
+	}
+	if lines := strings.Count(warn.String(), "\n"); lines != 1 {
+		t.Errorf("expected 1 entry at warn, got %d: %s", lines, warn.String())
+	}
+	if entry := jsonEntry(t, &warn); entry[slog.MessageKey] != "warn" || entry["component"] != "test" {
+		t.Errorf("unexpected entry %v", entry)
+	}
+}
+
+func TestMultiHandlerNamedLogger(t *testing.T) {
+	var first, second bytes.Buffer
+	levels := NewLevels(slog.LevelInfo)
+	log := slog.New(NewMultiHandler(
+		slog.NewJSONHandler(&first, &slog.HandlerOptions{Level: slog.LevelInfo}),
+		slog.NewJSONHandler(&second, &slog.HandlerOptions{Level: slog.LevelInfo}),
+	))
+	levels.SetNamed("pubsub", slog.LevelDebug, 0)
+
+	tests := []struct {
+		name   string
+		logger *slog.Logger
+	}{
+		{name: "named", logger: levels.Named(log, "pubsub.publisher")},
+		{name: "with context", logger: levels.Named(WithContext(log, context.Background()), "pubsub.publisher")},
+		{name: "with attributes", logger: levels.Named(log, "pubsub.publisher").With("topic", "orders")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			first.Reset()
+			second.Reset()
+
+			// The override enables debug entries, even though each handler's level is info
+			test.logger.Debug("debug")
+			for _, buf := range []*bytes.Buffer{&first, &second} {
+				entry := jsonEntry(t, buf)
+				if entry[slog.MessageKey] != "debug" || entry[LoggerKey] != "pubsub.publisher" {
+					t.Errorf("unexpected entry %v", entry)
+				}
+			}
+		})
+	}
+
+	// Loggers without an override keep each handler's level
+	first.Reset()
+	second.Reset()
+	levels.Named(log, "storage").Debug("debug")
+	log.Debug("debug")
+	if first.Len() != 0 || second.Len() != 0 {
+		t.Errorf("expected no entries, got %q and %q", first.String(), second.String())
+	}
+}
+
+func TestMultiHandlerFlush(t *testing.T) {
+	flushed := 0
+	failing := errors.New("flush failed")
+	handler := NewMultiHandler(
+		&flushRecorder{Handler: slog.NewJSONHandler(io.Discard, nil), flush: func() error { flushed++; return nil }},
+		slog.NewJSONHandler(&bytes.Buffer{}, nil),
+		&flushRecorder{Handler: slog.NewJSONHandler(io.Discard, nil), flush: func() error { flushed++; return failing }},
+	)
+
+	// Every Flusher is flushed, even after one fails, and handlers that aren't one are skipped
+	if err := handler.Flush(); !errors.Is(err, failing) {
+		t.Errorf("expected the error %v, got %v", failing, err)
+	}
+	if flushed != 2 {
+		t.Errorf("expected 2 handlers to be flushed, got %d", flushed)
+	}
+	if err := FlushLogger(slog.New(handler.WithAttrs([]slog.Attr{slog.String("key", "value")}))); !errors.Is(err, failing) {
+		t.Errorf("expected the error %v flushing a derived logger, got %v", failing, err)
+	}
+}
+
+// jsonEntry parses the entry written to the buffer by a slog.JSONHandler.
+func jsonEntry(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to parse entry %q: %s", buf.String(), err.Error())
+	}
+	return entry
+}
+
+// flushRecorder is a slog.Handler implementing Flusher with the function.
+type flushRecorder struct {
+	slog.Handler
+	flush func() error
+}
+
+// Flush calls the recorder's function.
+func (r *flushRecorder) Flush() error {
+	return r.flush()
+}
+
+// WithAttrs returns a recorder with the same function, wrapping the handler with the attributes.
+func (r *flushRecorder) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &flushRecorder{Handler: r.Handler.WithAttrs(attrs), flush: r.flush}
+}
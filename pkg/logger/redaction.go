@@ -0,0 +1,315 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package logger
+
+import (
+	"encoding"
+	"fmt"
+	"log/slog"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// DefaultRedactionMask replaces redacted values when the RedactionPolicy doesn't set a Mask.
+const DefaultRedactionMask = "[REDACTED]"
+
+// DefaultRedactedKeys are the patterns of the keys whose values are masked when the RedactionPolicy
+// doesn't set any Keys.
+var DefaultRedactedKeys = []string{
+	"authorization",
+	"proxy-authorization",
+	"cookie",
+	"set-cookie",
+	"password",
+	"passwd",
+	"secret",
+	"*_secret",
+	"token",
+	"*_token",
+	"api_key",
+	"apikey",
+	"private_key",
+}
+
+// secretPatterns match secrets that are scrubbed from messages and string values regardless of
+// their key: bearer tokens, such as those in Authorization headers, and JWT-shaped strings.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\bbearer\s+[a-z0-9\-._~+/]+=*`),
+	regexp.MustCompile(`\beyJ[a-zA-Z0-9_-]+\.[a-zA-Z0-9_-]+\.[a-zA-Z0-9_-]*`),
+}
+
+// redactTags caches whether each type contains a struct field with a log:"redact" tag, directly
+// or within a nested struct, slice, array or map.
+var redactTags sync.Map // map[reflect.Type]bool
+
+// pointerVisit identifies a pointer being converted by redactValue. The type is part of it, as a
+// pointer to a struct and a pointer to its first field share an address.
+type pointerVisit struct {
+	pointer uintptr
+	typ     reflect.Type
+}
+
+// redactor masks secrets and PII in entries, as controlled by a RedactionPolicy. The methods of a
+// nil redactor leave entries unchanged.
+type redactor struct {
+	keys     []string         // Lowercase patterns of the keys whose values are masked
+	mask     string           // Value that replaces masked values
+	patterns []*regexp.Regexp // Patterns whose matches are masked in messages and string values
+}
+
+// newRedactor returns a redactor applying the policy, which must be valid, or nil if there's no policy.
+func newRedactor(policy *RedactionPolicy) *redactor {
+	if policy == nil {
+		return nil
+	}
+	keys := policy.Keys
+	if keys == nil {
+		keys = DefaultRedactedKeys
+	}
+	r := &redactor{
+		mask:     policy.Mask,
+		patterns: append(append([]*regexp.Regexp{}, secretPatterns...), policy.Patterns...),
+	}
+	if r.mask == "" {
+		r.mask = DefaultRedactionMask
+	}
+	for _, key := range keys {
+		r.keys = append(r.keys, strings.ToLower(key))
+	}
+	return r
+}
+
+// message returns the message with any secrets it contains masked.
+func (r *redactor) message(message string) string {
+	if r == nil {
+		return message
+	}
+	for _, pattern := range r.patterns {
+		message = pattern.ReplaceAllString(message, r.mask)
+	}
+	return message
+}
+
+// masksKey reports whether the value of an attribute with the key is masked.
+func (r *redactor) masksKey(key string) bool {
+	if r == nil || key == "" {
+		return false
+	}
+	key = strings.ToLower(key)
+	for _, pattern := range r.keys {
+		if matched, _ := path.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// value returns the resolved value with any secrets it contains masked. Strings and errors have
+// their secrets scrubbed, and structs with fields tagged log:"redact" are converted to maps with
+// those fields masked, as are such structs within slices, arrays and maps.
+func (r *redactor) value(v slog.Value) slog.Value {
+	if r == nil {
+		return v
+	}
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.StringValue(r.message(v.String()))
+	case slog.KindAny:
+		switch value := v.Any().(type) {
+		case error:
+			return slog.StringValue(r.message(value.Error()))
+		default:
+			if masked, ok := r.redactValue(reflect.ValueOf(value), nil); ok {
+				return slog.AnyValue(masked)
+			}
+		}
+	}
+	return v
+}
+
+// redactValue converts a struct with fields tagged log:"redact", or a pointer to one, into a map
+// keyed by the fields' JSON names with those fields masked. Slices, arrays and maps containing
+// such structs are converted to []any and map[string]any with each of the structs converted. It
+// returns false if the value doesn't contain any tagged fields, in which case it's logged as is.
+// The pointers being converted are tracked in visiting, which is allocated when it's first needed,
+// and a pointer that refers back to one of them is converted to nil rather than followed forever.
+func (r *redactor) redactValue(v reflect.Value, visiting map[pointerVisit]bool) (any, bool) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, false
+		}
+		if v.Kind() == reflect.Pointer && hasRedactTag(v.Type()) {
+			visit := pointerVisit{pointer: v.Pointer(), typ: v.Type()}
+			if visiting[visit] {
+				return nil, true
+			}
+			if visiting == nil {
+				visiting = make(map[pointerVisit]bool)
+			}
+			visiting[visit] = true
+			defer delete(visiting, visit)
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if !hasRedactTag(v.Type()) {
+			return nil, false
+		}
+		m := make(map[string]any)
+		r.redactFields(v, m, visiting)
+		return m, true
+	case reflect.Slice, reflect.Array:
+		if !mayHaveRedactTag(v.Type().Elem()) {
+			return nil, false
+		}
+		redacted := false
+		elems := make([]any, v.Len())
+		for i := range elems {
+			if masked, ok := r.redactValue(v.Index(i), visiting); ok {
+				elems[i], redacted = masked, true
+			} else {
+				elems[i] = v.Index(i).Interface()
+			}
+		}
+		return elems, redacted
+	case reflect.Map:
+		if !mayHaveRedactTag(v.Type().Elem()) {
+			return nil, false
+		}
+		redacted := false
+		m := make(map[string]any, v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			key := mapKey(iter.Key())
+			if masked, ok := r.redactValue(iter.Value(), visiting); ok {
+				m[key], redacted = masked, true
+			} else {
+				m[key] = iter.Value().Interface()
+			}
+		}
+		return m, redacted
+	}
+	return nil, false
+}
+
+// redactFields adds the exported fields of the struct to the map, following the naming rules of
+// encoding/json, with the fields tagged log:"redact" masked. Embedded structs of unexported types
+// can't be read as a whole, so they're added through their exported fields.
+func (r *redactor) redactFields(v reflect.Value, m map[string]any, visiting map[pointerVisit]bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+
+		// Embedded structs without a name have their fields promoted, as they are by encoding/json
+		value := v.Field(i)
+		if field.Anonymous && name == "" {
+			for value.Kind() == reflect.Pointer && !value.IsNil() {
+				value = value.Elem()
+			}
+			if value.Kind() == reflect.Struct {
+				r.redactFields(value, m, visiting)
+				continue
+			}
+			if !field.IsExported() {
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		switch {
+		case field.Tag.Get("log") == "redact":
+			m[name] = r.mask
+		case !value.CanInterface():
+			// An embedded struct of an unexported type with a name is only readable field by field
+			for value.Kind() == reflect.Pointer && !value.IsNil() {
+				value = value.Elem()
+			}
+			if value.Kind() == reflect.Struct {
+				fields := make(map[string]any)
+				r.redactFields(value, fields, visiting)
+				m[name] = fields
+			}
+		default:
+			if masked, ok := r.redactValue(value, visiting); ok {
+				m[name] = masked
+			} else {
+				m[name] = value.Interface()
+			}
+		}
+	}
+}
+
+// hasRedactTag reports whether the type contains a struct field tagged log:"redact", directly or
+// within a nested struct, slice, array or map.
+func hasRedactTag(t reflect.Type) bool {
+	if cached, ok := redactTags.Load(t); ok {
+		return cached.(bool)
+	}
+	found := findRedactTag(t, map[reflect.Type]bool{})
+	redactTags.Store(t, found)
+	return found
+}
+
+// mayHaveRedactTag reports whether values of the type may contain a struct field tagged
+// log:"redact", which values of interface types may, depending on their dynamic type.
+func mayHaveRedactTag(t reflect.Type) bool {
+	return t.Kind() == reflect.Interface || hasRedactTag(t)
+}
+
+// findRedactTag reports whether the type contains a struct field tagged log:"redact", skipping
+// the struct types that have already been visited, so recursive types terminate.
+func findRedactTag(t reflect.Type, visited map[reflect.Type]bool) bool {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || visited[t] {
+		return false
+	}
+	visited[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Tag.Get("log") == "redact" || findRedactTag(field.Type, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// mapKey returns the key of a map entry as encoding/json would encode it.
+func mapKey(key reflect.Value) string {
+	if marshaler, ok := key.Interface().(encoding.TextMarshaler); ok {
+		if text, err := marshaler.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+	return fmt.Sprint(key.Interface())
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// SuppressedCountKey is the key of the attribute carrying the number of duplicate entries that
+// were suppressed, added to the entry that summarizes them.
+const SuppressedCountKey = "suppressed_count"
+
+// maxBuckets is the number of messages whose rate is limited before idle buckets are discarded.
+const maxBuckets = 10000
+
+// NewSamplingHandler returns a handler that passes entries to the handler as permitted by the
+// policy, which must be valid. Entries are first sampled by level, then duplicates are suppressed,
+// and finally the rate of each message is limited.
+func NewSamplingHandler(handler slog.Handler, policy SamplingPolicy) *SamplingHandler {
+	return &SamplingHandler{
+		handler: handler,
+		state: &samplingState{
+			buckets: make(map[string]*tokenBucket),
+			policy:  policy,
+			windows: make(map[dedupKey]*dedupWindow),
+		},
+	}
+}
+
+// Enabled reports whether the wrapped handler handles entries at the level.
+func (h *SamplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle passes the entry to the wrapped handler, unless it isn't sampled, it duplicates an entry
+// logged within the policy's DedupWindow, or its message has exceeded the policy's RateLimit.
+func (h *SamplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if !h.state.sampled(r.Level) || h.state.suppress(ctx, h.handler, r) || !h.state.allow(r.Message) {
+		return nil
+	}
+	return h.handler.Handle(ctx, r)
+}
+
+// WithAttrs returns a handler that shares the sampling state, wrapping the handler with the attributes.
+func (h *SamplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &SamplingHandler{handler: h.handler.WithAttrs(attrs), state: h.state}
+}
+
+// WithGroup returns a handler that shares the sampling state, wrapping the handler with the group.
+func (h *SamplingHandler) WithGroup(name string) slog.Handler {
+	return &SamplingHandler{handler: h.handler.WithGroup(name), state: h.state}
+}
+
+// Flush logs an entry summarizing the duplicates suppressed in every open window, then flushes
+// the wrapped handler.
+func (h *SamplingHandler) Flush() error {
+	h.state.mux.Lock()
+	windows := h.state.windows
+	h.state.windows = make(map[dedupKey]*dedupWindow)
+	h.state.mux.Unlock()
+
+	for _, w := range windows {
+		w.timer.Stop()
+		if w.suppressed > 0 {
+			w.summarize()
+		}
+	}
+	return flushHandler(h.handler)
+}
+
+// sampled reports whether an entry at the level is sampled. Levels without a rate are always sampled.
+func (s *samplingState) sampled(level slog.Level) bool {
+	rate, ok := s.policy.SampleRates[level]
+	return !ok || rand.Float64() < rate
+}
+
+// suppress reports whether the entry duplicates one logged within the policy's DedupWindow, in
+// which case it's counted so it can be summarized once the window closes. Otherwise a window is
+// opened for the entry.
+func (s *samplingState) suppress(ctx context.Context, handler slog.Handler, r slog.Record) bool {
+	if s.policy.DedupWindow <= 0 {
+		return false
+	}
+	key := dedupKey{level: r.Level, message: r.Message}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if w, ok := s.windows[key]; ok {
+		w.ctx = context.WithoutCancel(ctx)
+		w.handler = handler
+		w.record = r.Clone()
+		w.suppressed++
+		return true
+	}
+	w := &dedupWindow{}
+	w.timer = time.AfterFunc(s.policy.DedupWindow, func() { s.closeWindow(key, w) })
+	s.windows[key] = w
+	return false
+}
+
+// closeWindow closes the window once it has elapsed. If duplicates were suppressed, an entry
+// summarizing them is logged, and the window is reopened, as the summary is the first entry of
+// the next window.
+func (s *samplingState) closeWindow(key dedupKey, w *dedupWindow) {
+	s.mux.Lock()
+	if s.windows[key] != w {
+		s.mux.Unlock()
+		return // The window was closed by Flush
+	}
+	if w.suppressed == 0 {
+		delete(s.windows, key)
+		s.mux.Unlock()
+		return
+	}
+	summary := *w
+	w.suppressed = 0
+	w.timer.Reset(s.policy.DedupWindow)
+	s.mux.Unlock()
+
+	summary.summarize()
+}
+
+// allow reports whether an entry with the message is within the policy's RateLimit, taking a
+// token from the message's bucket if it is.
+func (s *samplingState) allow(message string) bool {
+	if s.policy.RateLimit <= 0 {
+		return true
+	}
+	burst := float64(s.policy.Burst)
+	if burst <= 0 {
+		burst = math.Max(1, math.Ceil(s.policy.RateLimit))
+	}
+	now := time.Now()
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	b, ok := s.buckets[message]
+	if !ok {
+		if len(s.buckets) >= maxBuckets {
+			s.discardIdleBuckets(now, burst)
+		}
+		b = &tokenBucket{tokens: burst, last: now}
+		s.buckets[message] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*s.policy.RateLimit)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// discardIdleBuckets discards the buckets that have refilled since they were last used, as
+// they're no different from new buckets.
+func (s *samplingState) discardIdleBuckets(now time.Time, burst float64) {
+	for message, b := range s.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*s.policy.RateLimit >= burst {
+			delete(s.buckets, message)
+		}
+	}
+}
+
+// summarize logs the last suppressed duplicate with the context it was logged with, such as its
+// trace and request, along with the number of duplicates that were suppressed.
+func (w dedupWindow) summarize() {
+	r := w.record.Clone()
+	r.AddAttrs(slog.Int(SuppressedCountKey, w.suppressed))
+	w.handler.Handle(w.ctx, r)
+}
@@ -0,0 +1,347 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSamplingRates(t *testing.T) {
+	inner := &recordHandler{}
+	log := slog.New(NewSamplingHandler(inner, SamplingPolicy{
+		SampleRates: map[slog.Level]float64{
+			slog.LevelDebug: 0,
+			slog.LevelInfo:  0.5,
+			slog.LevelWarn:  1,
+		},
+	}))
+
+	for range 10000 {
+		log.Debug("debug")
+		log.Info("info")
+		log.Warn("warn")
+		log.Error("error")
+	}
+
+	// Levels without a rate are always logged, the rest are logged in proportion to their rate
+	counts := inner.Counts()
+	if counts["debug"] != 0 || counts["warn"] != 10000 || counts["error"] != 10000 {
+		t.Errorf("unexpected counts %v", counts)
+	}
+	if counts["info"] < 4000 || counts["info"] > 6000 {
+		t.Errorf("expected about half of the info entries to be logged, got %d", counts["info"])
+	}
+}
+
+func TestSamplingRateLimitBurst(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   SamplingPolicy
+		expected int
+	}{
+		{name: "configured burst", policy: SamplingPolicy{RateLimit: 2, Burst: 3}, expected: 3},
+		{name: "burst defaults to the rate", policy: SamplingPolicy{RateLimit: 2.5}, expected: 3},
+		{name: "burst is at least one", policy: SamplingPolicy{RateLimit: 0.5}, expected: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inner := &recordHandler{}
+			log := slog.New(NewSamplingHandler(inner, test.policy))
+
+			// Each message has a bucket of its own, which allows the burst at once
+			for range 5 {
+				log.Info("flood")
+			}
+			log.Info("other")
+			if counts := inner.Counts(); counts["flood"] != test.expected || counts["other"] != 1 {
+				t.Errorf("expected %d entries to be logged, got %v", test.expected, counts)
+			}
+		})
+	}
+}
+
+func TestSamplingRateLimitRefill(t *testing.T) {
+	inner := &recordHandler{}
+	handler := NewSamplingHandler(inner, SamplingPolicy{RateLimit: 2, Burst: 3})
+	log := slog.New(handler)
+	for range 5 {
+		log.Info("flood")
+	}
+
+	// The bucket refills at the rate, up to the burst. Move its last refill back rather than waiting.
+	tests := []struct {
+		elapsed  time.Duration
+		expected int
+	}{
+		{elapsed: time.Second, expected: 2},
+		{elapsed: time.Hour, expected: 3},
+	}
+	for _, test := range tests {
+		before := inner.Counts()["flood"]
+		handler.state.mux.Lock()
+		handler.state.buckets["flood"].last = handler.state.buckets["flood"].last.Add(-test.elapsed)
+		handler.state.mux.Unlock()
+		for range 5 {
+			log.Info("flood")
+		}
+		if logged := inner.Counts()["flood"] - before; logged != test.expected {
+			t.Errorf("expected %d entries to be logged after %s, got %d", test.expected, test.elapsed, logged)
+		}
+	}
+}
+
+func TestSamplingDiscardIdleBuckets(t *testing.T) {
+	handler := NewSamplingHandler(&recordHandler{}, SamplingPolicy{RateLimit: 1000})
+	log := slog.New(handler)
+
+	// Buckets that have refilled are discarded once there are too many of them
+	for i := range maxBuckets {
+		handler.state.buckets[string(rune(i))] = &tokenBucket{tokens: 1000, last: time.Now().Add(-time.Minute)}
+	}
+	handler.state.buckets["busy"] = &tokenBucket{tokens: 0, last: time.Now()}
+	log.Info("new")
+	if len(handler.state.buckets) != 2 {
+		t.Errorf("expected only the busy and new buckets to be kept, got %d", len(handler.state.buckets))
+	}
+}
+
+func TestSamplingDedupWindow(t *testing.T) {
+	inner := &recordHandler{}
+	log := slog.New(NewSamplingHandler(inner, SamplingPolicy{DedupWindow: 100 * time.Millisecond}))
+
+	// The first entry is logged, and its duplicates are suppressed until the window closes
+	log.Warn("timeout", "attempt", 1)
+	log.Warn("timeout", "attempt", 2)
+	log.Warn("timeout", "attempt", 3)
+	log.Error("timeout")
+	log.Warn("other")
+	if records := inner.Records(); len(records) != 3 {
+		t.Fatalf("expected 3 entries to be logged, got %d", len(records))
+	}
+
+	// Once the window closes, the last duplicate is logged with the number suppressed
+	eventually(t, func() bool { return len(inner.Records()) == 4 })
+	summary := inner.Records()[3]
+	attrs := recordAttrs(summary)
+	if summary.Message != "timeout" || summary.Level != slog.LevelWarn || attrs["attempt"] != int64(3) || attrs[SuppressedCountKey] != int64(2) {
+		t.Errorf("unexpected summary %s %s %v", summary.Level, summary.Message, attrs)
+	}
+
+	// The summary opens the next window, so duplicates logged now are suppressed too
+	log.Warn("timeout", "attempt", 4)
+	eventually(t, func() bool { return len(inner.Records()) == 5 })
+	if attrs := recordAttrs(inner.Records()[4]); attrs["attempt"] != int64(4) || attrs[SuppressedCountKey] != int64(1) {
+		t.Errorf("unexpected summary %v", attrs)
+	}
+
+	// Once a window closes without duplicates, the next entry is logged immediately
+	time.Sleep(200 * time.Millisecond)
+	log.Warn("timeout", "attempt", 5)
+	if records := inner.Records(); len(records) != 6 || recordAttrs(records[5])[SuppressedCountKey] != nil {
+		t.Errorf("expected the entry to be logged without a count, got %d entries", len(records))
+	}
+}
+
+func TestSamplingFlushSummarizes(t *testing.T) {
+	var buf bytes.Buffer
+	handler := NewSamplingHandler(&StructuredHandler{level: slog.LevelInfo, mux: &sync.Mutex{}, writer: &buf}, SamplingPolicy{DedupWindow: time.Hour})
+	log := slog.New(handler).With("component", "test")
+	entries := func() []map[string]any {
+		var entries []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var entry map[string]any
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("failed to parse entry %q: %s", line, err.Error())
+			}
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+
+	log.Info("retrying")
+	log.Info("retrying")
+	log.Info("retrying")
+	log.Info("alone")
+
+	// Flush summarizes the open windows that suppressed duplicates, without waiting for them to close
+	if err := FlushLogger(log); err != nil {
+		t.Fatalf("failed to flush: %s", err.Error())
+	}
+	logged := entries()
+	if len(logged) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(logged))
+	}
+	if attrs, _ := logged[2]["attributes"].(map[string]any); logged[2]["message"] != "retrying" || attrs[SuppressedCountKey] != float64(2) || attrs["component"] != "test" {
+		t.Errorf("unexpected summary %v", logged[2])
+	}
+
+	// The windows are closed, so the next entry is logged
+	log.Info("retrying")
+	if logged := entries(); len(logged) != 4 {
+		t.Errorf("expected the entry to be logged after the flush, got %d entries", len(logged))
+	}
+}
+
+func TestSamplingSummaryContext(t *testing.T) {
+	type attemptKey struct{}
+	tests := []struct {
+		name   string
+		window time.Duration
+		flush  bool // flush is whether the summary is logged by flushing, rather than the window closing
+	}{
+		{name: "window closes", window: 50 * time.Millisecond},
+		{name: "flush", window: time.Hour, flush: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inner := &recordHandler{}
+			log := slog.New(NewSamplingHandler(inner, SamplingPolicy{DedupWindow: test.window}))
+
+			// Each duplicate is logged with a different context, the last of which is canceled
+			// before the summary is logged
+			for attempt := 1; attempt <= 3; attempt++ {
+				ctx, cancel := context.WithCancel(context.WithValue(context.Background(), attemptKey{}, attempt))
+				log.WarnContext(ctx, "timeout")
+				cancel()
+			}
+			if test.flush {
+				if err := FlushLogger(log); err != nil {
+					t.Fatalf("failed to flush: %s", err.Error())
+				}
+			}
+			eventually(t, func() bool { return len(inner.Records()) == 2 })
+
+			// The summary is logged with the context of the last duplicate, without its cancellation
+			ctx := inner.Contexts()[1]
+			if attempt := ctx.Value(attemptKey{}); attempt != 3 {
+				t.Errorf("expected the summary to be logged with the context of attempt 3, got %v", attempt)
+			}
+			if err := ctx.Err(); err != nil {
+				t.Errorf("expected the summary's context not to be canceled, got %s", err.Error())
+			}
+		})
+	}
+}
+
+// recordAttrs returns the attributes of the record, keyed by name.
+func recordAttrs(r slog.Record) map[string]any {
+	attrs := make(map[string]any)
+	r.Attrs(func(attr slog.Attr) bool {
+		attrs[attr.Key] = attr.Value.Any()
+		return true
+	})
+	return attrs
+}
+
+// recordHandler is a slog.Handler recording the entries it handles, including the attributes
+// added to the handler with WithAttrs.
+type recordHandler struct {
+	attrs    []slog.Attr       // attrs are the attributes added with WithAttrs.
+	contexts []context.Context // contexts are the contexts the entries were handled with.
+	mux      sync.Mutex        // mux protects records and contexts.
+	parent   *recordHandler    // parent is the handler this one was derived from, which holds the records.
+	records  []slog.Record     // records are the entries handled.
+}
+
+// root returns the handler holding the records.
+func (h *recordHandler) root() *recordHandler {
+	if h.parent != nil {
+		return h.parent
+	}
+	return h
+}
+
+// Enabled reports that the handler handles entries at every level.
+func (h *recordHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle records a clone of the entry, with the handler's attributes, and the context it was
+// handled with.
+func (h *recordHandler) Handle(ctx context.Context, r slog.Record) error {
+	r = r.Clone()
+	r.AddAttrs(h.attrs...)
+	root := h.root()
+	root.mux.Lock()
+	defer root.mux.Unlock()
+	root.records = append(root.records, r)
+	root.contexts = append(root.contexts, ctx)
+	return nil
+}
+
+// WithAttrs returns a handler recording to this one, which adds the attributes to its entries.
+func (h *recordHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &recordHandler{attrs: append(append([]slog.Attr{}, h.attrs...), attrs...), parent: h.root()}
+}
+
+// WithGroup returns the handler, as groups aren't recorded.
+func (h *recordHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// Records returns the entries handled so far.
+func (h *recordHandler) Records() []slog.Record {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return append([]slog.Record{}, h.records...)
+}
+
+// Contexts returns the contexts the entries handled so far were handled with.
+func (h *recordHandler) Contexts() []context.Context {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return append([]context.Context{}, h.contexts...)
+}
+
+// Flush does nothing, as entries are recorded as they're handled.
+func (h *recordHandler) Flush() error {
+	return nil
+}
+
+// Counts returns the number of entries handled with each message.
+func (h *recordHandler) Counts() map[string]int {
+	counts := make(map[string]int)
+	for _, r := range h.Records() {
+		counts[r.Message]++
+	}
+	return counts
+}
+
+// eventually fails the test if the condition isn't met within a second.
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within a second")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -23,30 +23,352 @@
 package logger
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"os"
+	"path"
+	"regexp"
+	"sync"
+	"time"
 
 	"cloud.google.com/go/logging"
+	"google.golang.org/api/option"
 )
 
 // Config holds configuration details for setting up logging.
 type Config struct {
-	GCPProjectID   string     // GCPProjectID is the Google Cloud Project ID where logs will be sent.
-	ServiceName    string     // ServiceName identifies the service in Error Reporting and groups related errors together.
-	ServiceVersion string     // ServiceVersion specifies the version or revision of the service for Error Reporting.
-	LogName        string     // LogName is the name of the log stream where entries will be written.
-	Level          slog.Level // Level is the minimum log level that will be captured (e.g., DEBUG, INFO).
+	GCPProjectID   string                // GCPProjectID is the Google Cloud Project ID where logs will be sent.
+	ServiceName    string                // ServiceName identifies the service in Error Reporting and groups related errors together.
+	ServiceVersion string                // ServiceVersion specifies the version or revision of the service for Error Reporting.
+	LogName        string                // LogName is the name of the log stream where entries will be written.
+	Level          slog.Leveler          // Level is the minimum log level that will be captured (e.g., DEBUG, INFO), defaulting to INFO. Use Levels to change it at runtime.
+	Labels         map[string]string     // Labels are added to every entry sent to Google Cloud Logging.
+	Writer         io.Writer             // Writer is where the structured and development loggers write entries, defaulting to standard output.
+	Sampling       *SamplingPolicy       // Sampling limits the entries that are logged, every entry is logged when nil.
+	Redaction      *RedactionPolicy      // Redaction masks secrets and PII in entries, entries are logged as is when nil.
+	Async          *AsyncPolicy          // Async delivers entries from a background goroutine, entries are delivered by the caller when nil.
+	ClientOptions  []option.ClientOption // ClientOptions are used to create the Google Cloud Logging client (e.g., credentials).
+}
+
+// OverflowPolicy is what an AsyncHandler does with an entry when its buffer is full.
+type OverflowPolicy string
+
+const (
+	OverflowDropNewest OverflowPolicy = "drop_newest" // Drop the entry being logged, keeping the entries that explain how the buffer filled up
+	OverflowDropOldest OverflowPolicy = "drop_oldest" // Drop the oldest buffered entry, keeping the most recent entries
+	OverflowBlock      OverflowPolicy = "block"       // Block the caller until there's room, so no entries are lost
+)
+
+// AsyncPolicy controls how an AsyncHandler buffers entries and delivers them in the background.
+type AsyncPolicy struct {
+	// BufferSize is the number of entries that can be buffered before the Overflow applies.
+	// Defaults to 1024.
+	BufferSize int
+
+	// Overflow is what happens to an entry logged while the buffer is full. Defaults to
+	// OverflowDropNewest.
+	Overflow OverflowPolicy
+
+	// BatchSize is the maximum number of entries taken from the buffer and delivered at once.
+	// Defaults to 64.
+	BatchSize int
+
+	// OnDrop is called with the level of each entry that's dropped because the buffer was full,
+	// such as to count the dropped entries in a metric. It's called by the goroutine logging the
+	// entry that overflowed the buffer, and must not log with the handler.
+	OnDrop func(level slog.Level)
+}
+
+// AsyncStats describes the entries handled by an AsyncHandler.
+type AsyncStats struct {
+	Buffered  int    // Number of entries waiting to be delivered
+	Delivered uint64 // Number of entries delivered to the wrapped handler
+	Dropped   uint64 // Number of entries dropped because the buffer was full
+}
+
+// RedactionPolicy controls how secrets and PII are masked in entries. Attributes whose keys match
+// the Keys are masked, as are the fields of struct values tagged log:"redact". Bearer tokens and
+// JWT-shaped strings are scrubbed from messages and string values, along with any other Patterns.
+type RedactionPolicy struct {
+	// Keys are the patterns of the attribute keys whose values are masked, matched case-insensitively
+	// with the syntax of path.Match (e.g. "password" or "*_token"). Defaults to DefaultRedactedKeys
+	// when nil, pass an empty slice to mask no keys.
+	Keys []string
+
+	// Patterns are masked wherever they match in messages and string values, in addition to bearer
+	// tokens and JWT-shaped strings (e.g. a pattern matching email addresses).
+	Patterns []*regexp.Regexp
+
+	// Mask replaces the values that are masked. Defaults to DefaultRedactionMask.
+	Mask string
+}
+
+// SamplingPolicy controls which entries are logged by a SamplingHandler, to keep a flood of entries,
+// such as those logged by every request while a dependency is failing, from overwhelming the logs.
+type SamplingPolicy struct {
+	// SampleRates are the fractions of entries logged at each level, between 0 and 1. Entries at
+	// levels without a rate are always logged.
+	SampleRates map[slog.Level]float64
+
+	// RateLimit is the maximum rate at which entries with the same message are logged, in entries
+	// per second. Defaults to zero, which doesn't limit the rate.
+	RateLimit float64
+
+	// Burst is the number of entries with the same message that can be logged at once before the
+	// RateLimit applies. Defaults to the RateLimit, rounded up.
+	Burst int
+
+	// DedupWindow is how long entries with the same level and message as a logged entry are
+	// suppressed for. Once the window closes, the last suppressed entry is logged with the number
+	// of entries that were suppressed in its suppressed_count attribute. Defaults to zero, which
+	// doesn't suppress duplicates.
+	DedupWindow time.Duration
+}
+
+// Flusher is implemented by handlers that buffer entries, or wrap handlers that do, and by
+// writers that buffer what's written to them. FlushLogger flushes loggers whose handlers implement it.
+type Flusher interface {
+	// Flush delivers any buffered entries, returning once they've been delivered.
+	Flush() error
+}
+
+// cloudLogger is the part of *logging.Logger used by GoogleCloudLoggingHandler to send log entries.
+type cloudLogger interface {
+	Log(e logging.Entry)
+	Flush() error
 }
 
 // DevelopmentHandler is a custom handler for slog used in development environments.
 // It outputs logs to the console with formatted messages and structured data.
 type DevelopmentHandler struct {
-	level slog.Level // Level is the minimum log level at which logs will be printed to the console.
+	attrs    attrs        // attrs are the attributes and groups added with WithAttrs and WithGroup.
+	color    bool         // color is whether entries are colored by level, which they are when printed to a terminal.
+	level    slog.Leveler // Level is the minimum log level at which logs will be printed to the console.
+	mux      *sync.Mutex  // mux serializes writes to the writer, and is shared with the handlers derived from this one.
+	redactor *redactor    // redactor masks secrets and PII in entries, when redaction is enabled.
+	writer   io.Writer    // writer is where log entries are printed, defaulting to standard output when nil.
 }
 
 // GoogleCloudLoggingHandler is a custom handler for slog used to send logs to Google Cloud Logging.
 type GoogleCloudLoggingHandler struct {
-	logger         *logging.Logger // logger is the Google Cloud Logger instance used to send log entries.
-	level          slog.Level      // level is the minimum log level at which logs will be sent to Google Cloud.
-	serviceName    string          // serviceName identifies the service in Error Reporting and groups related errors together.
-	serviceVersion string          // serviceVersion specifies the version or revision of the service for Error Reporting.
+	attrs          attrs             // attrs are the attributes and groups added with WithAttrs and WithGroup.
+	labels         map[string]string // labels are added to every entry.
+	logger         cloudLogger       // logger is the Google Cloud Logger instance used to send log entries.
+	level          slog.Leveler      // level is the minimum log level at which logs will be sent to Google Cloud.
+	redactor       *redactor         // redactor masks secrets and PII in entries, when redaction is enabled.
+	projectID      string            // projectID is the Google Cloud Project ID that trace names are qualified with.
+	serviceName    string            // serviceName identifies the service in Error Reporting and groups related errors together.
+	serviceVersion string            // serviceVersion specifies the version or revision of the service for Error Reporting.
+}
+
+// StructuredHandler is a custom handler for slog that writes logs as JSON in the structured logging
+// format, which the logging agent on Cloud Run, GKE and Compute Engine ingests into Google Cloud Logging.
+type StructuredHandler struct {
+	attrs          attrs             // attrs are the attributes and groups added with WithAttrs and WithGroup.
+	labels         map[string]string // labels are added to every entry.
+	level          slog.Leveler      // level is the minimum log level at which logs will be written.
+	mux            *sync.Mutex       // mux serializes writes to the writer, and is shared with the handlers derived from this one.
+	projectID      string            // projectID is the Google Cloud Project ID that trace names are qualified with.
+	redactor       *redactor         // redactor masks secrets and PII in entries, when redaction is enabled.
+	serviceName    string            // serviceName identifies the service in Error Reporting and groups related errors together.
+	serviceVersion string            // serviceVersion specifies the version or revision of the service for Error Reporting.
+	writer         io.Writer         // writer is where entries are written.
+}
+
+// SamplingHandler is a slog handler that samples, deduplicates and rate limits entries before
+// passing them to the handler it wraps, as controlled by its SamplingPolicy.
+type SamplingHandler struct {
+	handler slog.Handler   // handler is the handler entries are passed to.
+	state   *samplingState // state is shared with the handlers derived from this one.
+}
+
+// MultiHandler is a slog handler that passes each entry to several handlers.
+type MultiHandler struct {
+	handlers []slog.Handler // handlers are the handlers entries are passed to.
+}
+
+// RotatingFileConfig controls when a RotatingFile is rotated, and how many rotated files are kept.
+type RotatingFileConfig struct {
+	// Path is the path of the file entries are written to. Rotated files are kept in the same
+	// directory, named after the file with the time they were rotated (e.g. "service-<time>.log").
+	Path string
+
+	// MaxSize is the size in bytes the file can grow to before it's rotated. Defaults to 100 MiB.
+	MaxSize int64
+
+	// MaxAge is how long the file is written to before it's rotated. Defaults to zero, which only
+	// rotates the file once it reaches its MaxSize.
+	MaxAge time.Duration
+
+	// MaxBackups is the number of rotated files that are kept, the oldest are removed. Defaults to 5.
+	MaxBackups int
+}
+
+// RotatingFile is an io.Writer that appends to a file, rotating it once it grows beyond its
+// maximum size or age, as controlled by its RotatingFileConfig. It's safe for concurrent use.
+type RotatingFile struct {
+	closed bool               // closed is whether the file has been closed with Close.
+	config RotatingFileConfig // config controls when the file is rotated.
+	file   *os.File           // file is the open file, or nil if it's closed or couldn't be reopened.
+	mux    sync.Mutex         // mux serializes writes and rotation.
+	opened time.Time          // opened is when the file was opened.
+	size   int64              // size is the size of the file in bytes.
+}
+
+// AsyncHandler is a slog handler that buffers entries and passes them to the handler it wraps
+// from a background goroutine, as controlled by its AsyncPolicy.
+type AsyncHandler struct {
+	handler slog.Handler // handler is the handler entries are passed to.
+	state   *asyncState  // state is shared with the handlers derived from this one.
+}
+
+// asyncState holds the buffer of an AsyncHandler, which is shared by the handlers derived from it.
+type asyncState struct {
+	buffer     []asyncEntry  // buffer is a ring of the entries waiting to be delivered.
+	closed     bool          // closed is whether the handler has been closed with Close.
+	count      int           // count is the number of entries in the buffer.
+	delivered  uint64        // delivered is the number of entries delivered.
+	delivering int           // delivering is the number of entries taken from the buffer that are being delivered.
+	done       chan struct{} // done is closed when the goroutine delivering entries has stopped.
+	dropped    uint64        // dropped is the number of entries dropped because the buffer was full.
+	head       int           // head is the index of the oldest entry in the buffer.
+	idle       *sync.Cond    // idle is signaled when the buffer is empty and no entries are being delivered.
+	mux        sync.Mutex    // mux protects every field but done and policy.
+	notEmpty   *sync.Cond    // notEmpty is signaled when an entry is added to the buffer.
+	notFull    *sync.Cond    // notFull is signaled when entries are taken from the buffer.
+	policy     AsyncPolicy   // policy controls how entries are buffered and delivered.
+}
+
+// asyncEntry is an entry waiting to be delivered by an AsyncHandler.
+type asyncEntry struct {
+	ctx     context.Context // ctx is the context the entry was logged with.
+	handler slog.Handler    // handler is the handler the entry was logged with.
+	record  slog.Record     // record is a clone of the entry.
+}
+
+// samplingState holds the state of a SamplingHandler, which is shared by the handlers derived from it.
+type samplingState struct {
+	buckets map[string]*tokenBucket   // buckets limit the rate of each message.
+	mux     sync.Mutex                // mux protects buckets and windows.
+	policy  SamplingPolicy            // policy controls which entries are logged.
+	windows map[dedupKey]*dedupWindow // windows are the open windows of logged entries, whose duplicates are suppressed.
+}
+
+// tokenBucket limits the rate at which entries with a message are logged.
+type tokenBucket struct {
+	tokens float64   // tokens is the number of entries that can be logged.
+	last   time.Time // last is when the tokens were last refilled.
+}
+
+// dedupKey identifies duplicate entries.
+type dedupKey struct {
+	level   slog.Level
+	message string
+}
+
+// dedupWindow tracks the duplicates of an entry suppressed while the window is open.
+type dedupWindow struct {
+	ctx        context.Context // ctx is the context the last duplicate was logged with, without its cancellation.
+	handler    slog.Handler    // handler is the handler the last duplicate was logged with.
+	record     slog.Record     // record is the last duplicate.
+	suppressed int             // suppressed is the number of duplicates suppressed.
+	timer      *time.Timer     // timer closes the window.
+}
+
+// Validate checks that the rates of the policy are within range.
+func (p SamplingPolicy) Validate() error {
+	for level, rate := range p.SampleRates {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("sample rate of %s must be between 0 and 1", level)
+		}
+	}
+	if p.RateLimit < 0 {
+		return errors.New("rate limit must not be negative")
+	}
+	if p.Burst < 0 {
+		return errors.New("burst must not be negative")
+	}
+	if p.DedupWindow < 0 {
+		return errors.New("dedup window must not be negative")
+	}
+	return nil
+}
+
+// Validate checks that the sizes of the policy aren't negative and that its overflow is known.
+func (p AsyncPolicy) Validate() error {
+	if p.BufferSize < 0 {
+		return errors.New("buffer size must not be negative")
+	}
+	if p.BatchSize < 0 {
+		return errors.New("batch size must not be negative")
+	}
+	switch p.Overflow {
+	case "", OverflowDropNewest, OverflowDropOldest, OverflowBlock:
+	default:
+		return fmt.Errorf("overflow %q must be %q, %q or %q", p.Overflow, OverflowDropNewest, OverflowDropOldest, OverflowBlock)
+	}
+	return nil
+}
+
+// Validate checks that the config has a path, and that its limits aren't negative.
+func (c RotatingFileConfig) Validate() error {
+	if c.Path == "" {
+		return errors.New("path is missing")
+	}
+	if c.MaxSize < 0 {
+		return errors.New("max size must not be negative")
+	}
+	if c.MaxAge < 0 {
+		return errors.New("max age must not be negative")
+	}
+	if c.MaxBackups < 0 {
+		return errors.New("max backups must not be negative")
+	}
+	return nil
+}
+
+// Validate checks that the key patterns of the policy are well formed.
+func (p RedactionPolicy) Validate() error {
+	for _, key := range p.Keys {
+		if _, err := path.Match(key, ""); err != nil {
+			return fmt.Errorf("invalid key pattern %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// Levels holds log levels that can be changed at runtime: a default level, used as the level of
+// the handlers it's configured as the Level of, and overrides for the loggers created with Named.
+// Changes can be temporary, reverting once their time to live elapses.
+type Levels struct {
+	configured slog.Level                // configured is the level the default level reverts to.
+	expires    time.Time                 // expires is when a temporary default level reverts, if it's temporary.
+	level      slog.LevelVar             // level is the default level.
+	mux        sync.RWMutex              // mux protects every field but level, which is read without locking.
+	overrides  map[string]*levelOverride // overrides are the levels of named loggers, keyed by name.
+	revert     *time.Timer               // revert reverts a temporary default level.
+}
+
+// levelOverride is the level of a named logger.
+type levelOverride struct {
+	expires time.Time   // expires is when the override is removed, if it's temporary.
+	level   slog.Level  // level is the level of the loggers with the name.
+	timer   *time.Timer // timer removes the override once it expires.
+}
+
+// LevelsSnapshot describes the levels at a point in time.
+type LevelsSnapshot struct {
+	Level     slog.Level      `json:"level"`             // Default level
+	Expires   *time.Time      `json:"expires,omitempty"` // When the default level reverts, if it's temporary
+	Overrides []LevelOverride `json:"overrides"`         // Levels of named loggers, sorted by name
+}
+
+// LevelOverride describes the level of a named logger.
+type LevelOverride struct {
+	Name    string     `json:"name"`              // Name of the loggers
+	Level   slog.Level `json:"level"`             // Level of the loggers
+	Expires *time.Time `json:"expires,omitempty"` // When the override is removed, if it's temporary
 }
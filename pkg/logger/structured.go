@@ -0,0 +1,125 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Special fields of structured log entries, which the logging agent moves into the corresponding
+// fields of the entry rather than leaving them in its jsonPayload.
+const (
+	sourceLocationField = "logging.googleapis.com/sourceLocation"
+	labelsField         = "logging.googleapis.com/labels"
+	spanIDField         = "logging.googleapis.com/spanId"
+	traceField          = "logging.googleapis.com/trace"
+	traceSampledField   = "logging.googleapis.com/trace_sampled"
+)
+
+// Enabled reports whether the provided log level is enabled for this handler.
+func (h *StructuredHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= minLevel(h.level)
+}
+
+// Handle writes the record as a single line of JSON in the structured logging format, which the
+// logging agent on Cloud Run, GKE and Compute Engine ingests into Google Cloud Logging. Entries
+// have the same content as those sent by GoogleCloudLoggingHandler, with the record's message and
+// attributes in the entry's jsonPayload, and the source location of the call that logged it.
+func (h *StructuredHandler) Handle(ctx context.Context, r slog.Record) error {
+	entry := newEntry(ctx, r, h.attrs, h.redactor, h.projectID, h.serviceName, h.serviceVersion)
+
+	// The payload's fields become the entry's jsonPayload, alongside the special fields
+	fields := entry.Payload.(map[string]any)
+	fields["severity"] = strings.ToUpper(entry.Severity.String())
+	if !entry.Timestamp.IsZero() {
+		fields["time"] = entry.Timestamp.Format(time.RFC3339Nano)
+	}
+	if entry.HTTPRequest != nil {
+		fields[HTTPRequestKey] = httpRequestFields(entry.HTTPRequest)
+	}
+	if entry.Trace != "" {
+		fields[traceField] = entry.Trace
+		fields[spanIDField] = entry.SpanID
+		fields[traceSampledField] = entry.TraceSampled
+	}
+	if len(h.labels) > 0 {
+		fields[labelsField] = h.labels
+	}
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		fields[sourceLocationField] = map[string]any{
+			"file":     frame.File,
+			"line":     frame.Line,
+			"function": frame.Function,
+		}
+	}
+
+	// Write the entry with a single write, so concurrent entries aren't interleaved
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(fields); err != nil {
+		return err
+	}
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	_, err := h.writer.Write(buf.Bytes())
+	return err
+}
+
+// WithAttrs returns a new handler that writes the attributes with every entry, nested within
+// the groups opened with WithGroup so far.
+func (h *StructuredHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	clone := *h
+	clone.attrs = h.attrs.withAttrs(attrs)
+	return &clone
+}
+
+// WithGroup returns a new handler that nests the attributes added afterwards within the group,
+// both with WithAttrs and with each entry. Groups without any attributes aren't written.
+func (h *StructuredHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.attrs = h.attrs.withGroup(name)
+	return &clone
+}
+
+// Flush flushes the writer, if it implements Flusher, such as a RotatingFile. Entries are written
+// as soon as they're logged, so otherwise there's nothing to flush, and this method simply returns nil.
+func (h *StructuredHandler) Flush() error {
+	if flusher, ok := h.writer.(Flusher); ok {
+		h.mux.Lock()
+		defer h.mux.Unlock()
+		return flusher.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package metrics
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	monitoring "google.golang.org/api/monitoring/v3"
+)
+
+// maxTimeSeriesPerRequest is the most time series Cloud Monitoring accepts in a single request.
+const maxTimeSeriesPerRequest = 200
+
+// CloudMonitoringExporter periodically pushes every metric in a registry to Cloud Monitoring.
+// It implements the service's Component interface, so it can be added to a service with
+// AddComponent, which starts pushing once the service is set up, and pushes a final time
+// when the service terminates.
+type CloudMonitoringExporter struct {
+	client   *monitoring.Service
+	config   CloudMonitoringConfig
+	done     chan struct{}
+	registry *Registry
+	stop     context.CancelFunc
+	stopOnce sync.Once
+}
+
+// NewCloudMonitoringExporter creates an exporter that pushes the metrics in the registry to
+// Cloud Monitoring. The client isn't created until Setup is called.
+func NewCloudMonitoringExporter(registry *Registry, config CloudMonitoringConfig) (*CloudMonitoringExporter, error) {
+
+	// Confirm the config is valid
+	if registry == nil {
+		return nil, errors.New("registry cannot be nil")
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	// Apply the defaults for anything that wasn't configured
+	if config.Interval <= 0 {
+		config.Interval = 60 * time.Second
+	}
+	if config.MetricPrefix == "" {
+		config.MetricPrefix = "custom.googleapis.com/"
+	}
+	if config.Resource == nil {
+		config.Resource = &MonitoredResource{
+			Type: "generic_task",
+			Labels: map[string]string{
+				"project_id": config.GCPProjectID,
+				"location":   "global",
+				"namespace":  config.Job,
+				"job":        config.Job,
+				"task_id":    newTaskID(),
+			},
+		}
+	}
+
+	return &CloudMonitoringExporter{
+		config:   config,
+		registry: registry,
+	}, nil
+}
+
+// Name returns the name of the exporter, as a component of the service.
+func (e *CloudMonitoringExporter) Name() string {
+	return "cloud-monitoring-exporter"
+}
+
+// DependsOn returns the components the exporter depends on, which is none.
+func (e *CloudMonitoringExporter) DependsOn() []string {
+	return nil
+}
+
+// Setup creates the Cloud Monitoring client, and starts pushing the metrics every interval
+// until Teardown is called or the context is canceled.
+func (e *CloudMonitoringExporter) Setup(ctx context.Context) error {
+	client, err := monitoring.NewService(ctx, e.config.ClientOptions...)
+	if err != nil {
+		return fmt.Errorf("failed to create Cloud Monitoring client: %w", err)
+	}
+	e.client = client
+
+	ctx, e.stop = context.WithCancel(ctx)
+	e.done = make(chan struct{})
+	go func() {
+		defer close(e.done)
+		ticker := time.NewTicker(e.config.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				// Errors are retried on the next tick, and returned by the final push in Teardown
+				e.Export(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Teardown stops pushing the metrics periodically, and pushes them a final time so values
+// recorded since the last push aren't lost.
+func (e *CloudMonitoringExporter) Teardown(ctx context.Context) error {
+	if e.client == nil {
+		return nil
+	}
+	e.stopOnce.Do(func() {
+		e.stop()
+		<-e.done
+	})
+	return e.Export(ctx)
+}
+
+// Export pushes the current value of every metric in the registry to Cloud Monitoring.
+// Counters are written as cumulative metrics, gauges as gauge metrics, and histograms as
+// cumulative distributions.
+func (e *CloudMonitoringExporter) Export(ctx context.Context) error {
+	if e.client == nil {
+		return errors.New("exporter has not been set up")
+	}
+
+	timeSeries := e.timeSeries(time.Now())
+	var errs []error
+	for start := 0; start < len(timeSeries); start += maxTimeSeriesPerRequest {
+		end := min(start+maxTimeSeriesPerRequest, len(timeSeries))
+		request := &monitoring.CreateTimeSeriesRequest{TimeSeries: timeSeries[start:end]}
+		if _, err := e.client.Projects.TimeSeries.Create("projects/"+e.config.GCPProjectID, request).Context(ctx).Do(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to write time series to Cloud Monitoring: %w", err)
+	}
+	return nil
+}
+
+// timeSeries converts every series in the registry to a Cloud Monitoring time series ending at now.
+func (e *CloudMonitoringExporter) timeSeries(now time.Time) []*monitoring.TimeSeries {
+	resource := &monitoring.MonitoredResource{
+		Type:   e.config.Resource.Type,
+		Labels: e.config.Resource.Labels,
+	}
+	end := now.UTC().Format(time.RFC3339Nano)
+
+	var timeSeries []*monitoring.TimeSeries
+	for _, f := range e.registry.Gather() {
+		for _, s := range f.Series {
+			labels := make(map[string]string, len(f.Labels))
+			for i, name := range f.Labels {
+				labels[name] = s.LabelValues[i]
+			}
+			ts := &monitoring.TimeSeries{
+				Metric: &monitoring.Metric{
+					Type:   e.config.MetricPrefix + f.Name,
+					Labels: labels,
+				},
+				Resource: resource,
+			}
+
+			// Cumulative points must start before they end, so a series recorded within the
+			// same instant as the push is pushed with the next one instead
+			if f.Kind != KindGauge && !s.Start.Before(now) {
+				continue
+			}
+			start := s.Start.UTC().Format(time.RFC3339Nano)
+
+			switch f.Kind {
+			case KindCounter:
+				value := s.Value
+				ts.MetricKind = "CUMULATIVE"
+				ts.ValueType = "DOUBLE"
+				ts.Points = []*monitoring.Point{{
+					Interval: &monitoring.TimeInterval{StartTime: start, EndTime: end},
+					Value:    &monitoring.TypedValue{DoubleValue: &value},
+				}}
+			case KindGauge:
+				value := s.Value
+				ts.MetricKind = "GAUGE"
+				ts.ValueType = "DOUBLE"
+				ts.Points = []*monitoring.Point{{
+					Interval: &monitoring.TimeInterval{EndTime: end},
+					Value:    &monitoring.TypedValue{DoubleValue: &value},
+				}}
+			case KindHistogram:
+				distribution := &monitoring.Distribution{
+					Count: int64(s.Count),
+					BucketOptions: &monitoring.BucketOptions{
+						ExplicitBuckets: &monitoring.Explicit{Bounds: f.Buckets},
+					},
+					BucketCounts: make([]int64, len(s.BucketCounts)),
+				}
+				if s.Count > 0 {
+					distribution.Mean = s.Sum / float64(s.Count)
+				}
+				for i, count := range s.BucketCounts {
+					distribution.BucketCounts[i] = int64(count)
+				}
+				ts.MetricKind = "CUMULATIVE"
+				ts.ValueType = "DISTRIBUTION"
+				ts.Points = []*monitoring.Point{{
+					Interval: &monitoring.TimeInterval{StartTime: start, EndTime: end},
+					Value:    &monitoring.TypedValue{DistributionValue: distribution},
+				}}
+			}
+			timeSeries = append(timeSeries, ts)
+		}
+	}
+	return timeSeries
+}
+
+// newTaskID generates a random ID identifying this instance of the job, so that every instance
+// writes to its own time series.
+func newTaskID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,257 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	monitoring "google.golang.org/api/monitoring/v3"
+	"google.golang.org/api/option"
+)
+
+func TestCloudMonitoringTimeSeries(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("orders_total", "Orders created.", "channel").Add(3, "web")
+	r.Gauge("connections", "Open connections.").Set(-2)
+	r.Histogram("latency_seconds", "Request latency.", []float64{0.1, 1}, "route").Observe(0.5, "/orders")
+	r.Counter("unused_total", "Never recorded.")
+	exporter, err := NewCloudMonitoringExporter(r, CloudMonitoringConfig{GCPProjectID: "project", Job: "orders"})
+	if err != nil {
+		t.Fatalf("failed to create exporter: %s", err.Error())
+	}
+	starts := make(map[string]string)
+	first := time.Now()
+	for _, f := range r.Gather() {
+		for _, series := range f.Series {
+			starts[f.Name] = series.Start.UTC().Format(time.RFC3339Nano)
+			if series.Start.Before(first) {
+				first = series.Start
+			}
+		}
+	}
+	now := time.Now().Add(time.Second)
+	end := now.UTC().Format(time.RFC3339Nano)
+
+	tests := []struct {
+		metricType string
+		labels     map[string]string
+		kind       string
+		valueType  string
+		interval   monitoring.TimeInterval
+		value      monitoring.TypedValue
+	}{
+		{
+			metricType: "custom.googleapis.com/connections",
+			labels:     map[string]string{},
+			kind:       "GAUGE",
+			valueType:  "DOUBLE",
+			interval:   monitoring.TimeInterval{EndTime: end},
+			value:      monitoring.TypedValue{DoubleValue: ptr(-2.0)},
+		},
+		{
+			metricType: "custom.googleapis.com/latency_seconds",
+			labels:     map[string]string{"route": "/orders"},
+			kind:       "CUMULATIVE",
+			valueType:  "DISTRIBUTION",
+			interval:   monitoring.TimeInterval{StartTime: starts["latency_seconds"], EndTime: end},
+			value: monitoring.TypedValue{DistributionValue: &monitoring.Distribution{
+				Count:         1,
+				Mean:          0.5,
+				BucketOptions: &monitoring.BucketOptions{ExplicitBuckets: &monitoring.Explicit{Bounds: []float64{0.1, 1}}},
+				BucketCounts:  []int64{0, 1, 0},
+			}},
+		},
+		{
+			metricType: "custom.googleapis.com/orders_total",
+			labels:     map[string]string{"channel": "web"},
+			kind:       "CUMULATIVE",
+			valueType:  "DOUBLE",
+			interval:   monitoring.TimeInterval{StartTime: starts["orders_total"], EndTime: end},
+			value:      monitoring.TypedValue{DoubleValue: ptr(3.0)},
+		},
+	}
+
+	// Every series is converted, and metrics without any series are left out
+	timeSeries := exporter.timeSeries(now)
+	if len(timeSeries) != len(tests) {
+		t.Fatalf("expected %d time series, got %d", len(tests), len(timeSeries))
+	}
+	for i, test := range tests {
+		t.Run(test.metricType, func(t *testing.T) {
+			ts := timeSeries[i]
+			if ts.Metric.Type != test.metricType || !reflect.DeepEqual(ts.Metric.Labels, test.labels) {
+				t.Errorf("expected the metric %s %v, got %s %v", test.metricType, test.labels, ts.Metric.Type, ts.Metric.Labels)
+			}
+			if ts.MetricKind != test.kind || ts.ValueType != test.valueType {
+				t.Errorf("expected a %s %s, got a %s %s", test.kind, test.valueType, ts.MetricKind, ts.ValueType)
+			}
+			if ts.Resource.Type != "generic_task" || ts.Resource.Labels["project_id"] != "project" || ts.Resource.Labels["job"] != "orders" || ts.Resource.Labels["task_id"] == "" {
+				t.Errorf("expected a generic_task for the job, got %+v", ts.Resource)
+			}
+			if len(ts.Points) != 1 {
+				t.Fatalf("expected a point, got %d", len(ts.Points))
+			}
+			if point := ts.Points[0]; !reflect.DeepEqual(*point.Interval, test.interval) || !reflect.DeepEqual(*point.Value, test.value) {
+				t.Errorf("expected a point over %+v of %s, got a point over %+v of %s", test.interval, jsonString(test.value), *point.Interval, jsonString(point.Value))
+			}
+		})
+	}
+
+	// Cumulative series can't be pushed until time has passed since they started, but gauges can
+	if timeSeries := exporter.timeSeries(first); len(timeSeries) != 1 || timeSeries[0].MetricKind != "GAUGE" {
+		t.Errorf("expected only the gauge to be pushed, got %d time series", len(timeSeries))
+	}
+}
+
+func TestCloudMonitoringExport(t *testing.T) {
+	tests := []struct {
+		name     string
+		series   int
+		status   int
+		requests []int // requests are the number of time series in each request
+		err      string
+	}{
+		{name: "single request", series: 3, status: http.StatusOK, requests: []int{3}},
+		{name: "batched requests", series: 450, status: http.StatusOK, requests: []int{200, 200, 50}},
+		{name: "failed request", series: 1, status: http.StatusForbidden, requests: []int{1}, err: "failed to write time series to Cloud Monitoring"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := &fakeMonitoring{status: test.status}
+			srv := httptest.NewServer(server)
+			defer srv.Close()
+
+			r := NewRegistry()
+			g := r.Gauge("queue_depth", "Messages waiting.", "queue")
+			for i := range test.series {
+				g.Set(float64(i), fmt.Sprintf("queue-%03d", i))
+			}
+			exporter, err := NewCloudMonitoringExporter(r, CloudMonitoringConfig{
+				GCPProjectID:  "project",
+				Job:           "orders",
+				Interval:      time.Hour,
+				ClientOptions: []option.ClientOption{option.WithEndpoint(srv.URL + "/"), option.WithoutAuthentication()},
+			})
+			if err != nil {
+				t.Fatalf("failed to create exporter: %s", err.Error())
+			}
+			if err := exporter.Export(context.Background()); err == nil {
+				t.Fatal("expected exporting before the exporter is set up to fail")
+			}
+			if err := exporter.Setup(context.Background()); err != nil {
+				t.Fatalf("failed to set up exporter: %s", err.Error())
+			}
+			defer exporter.Teardown(context.Background())
+
+			err = exporter.Export(context.Background())
+			if (err == nil && test.err != "") || (err != nil && !strings.Contains(err.Error(), test.err)) || (err != nil && test.err == "") {
+				t.Fatalf("expected the error %q, got %v", test.err, err)
+			}
+			if requests := server.requests(); !reflect.DeepEqual(requests, test.requests) {
+				t.Errorf("expected requests with %v time series, got %v", test.requests, requests)
+			}
+		})
+	}
+
+	// Teardown pushes the metrics a final time
+	server := &fakeMonitoring{status: http.StatusOK}
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+	r := NewRegistry()
+	r.Gauge("up", "Whether the service is up.").Set(1)
+	exporter, err := NewCloudMonitoringExporter(r, CloudMonitoringConfig{
+		GCPProjectID:  "project",
+		Resource:      &MonitoredResource{Type: "global"},
+		Interval:      time.Hour,
+		ClientOptions: []option.ClientOption{option.WithEndpoint(srv.URL + "/"), option.WithoutAuthentication()},
+	})
+	if err != nil {
+		t.Fatalf("failed to create exporter: %s", err.Error())
+	}
+	if err := exporter.Setup(context.Background()); err != nil {
+		t.Fatalf("failed to set up exporter: %s", err.Error())
+	}
+	if err := exporter.Teardown(context.Background()); err != nil {
+		t.Fatalf("failed to tear down exporter: %s", err.Error())
+	}
+	if requests := server.requests(); !reflect.DeepEqual(requests, []int{1}) {
+		t.Errorf("expected the metrics to be pushed on teardown, got %v", requests)
+	}
+}
+
+// fakeMonitoring is a Cloud Monitoring API server that records the time series written to it.
+type fakeMonitoring struct {
+	status  int
+	mux     sync.Mutex
+	written [][]*monitoring.TimeSeries
+}
+
+// ServeHTTP records the time series of a CreateTimeSeriesRequest, and responds with the status.
+func (f *fakeMonitoring) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" || r.URL.Path != "/v3/projects/project/timeSeries" {
+		http.NotFound(w, r)
+		return
+	}
+	var request monitoring.CreateTimeSeriesRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mux.Lock()
+	f.written = append(f.written, request.TimeSeries)
+	f.mux.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(f.status)
+	w.Write([]byte("{}"))
+}
+
+// requests returns the number of time series written by each request.
+func (f *fakeMonitoring) requests() []int {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	var requests []int
+	for _, timeSeries := range f.written {
+		requests = append(requests, len(timeSeries))
+	}
+	return requests
+}
+
+// jsonString returns the value encoded as JSON, so the values pointers refer to are printed.
+func jsonString(value any) string {
+	b, _ := json.Marshal(value)
+	return string(b)
+}
+
+// ptr returns a pointer to the value.
+func ptr[T any](value T) *T {
+	return &value
+}
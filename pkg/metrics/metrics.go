@@ -0,0 +1,355 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBuckets are the histogram buckets used when none are provided, suitable for request
+// latencies measured in seconds.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// TextContentType is the content type of the Prometheus text format written by WriteText.
+const TextContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	metricNamePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNamePattern  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		families: map[string]*family{},
+	}
+}
+
+// Counter returns the counter with the name, creating it if it doesn't exist. Values are recorded
+// with a value for each of the labels. It panics if the name or labels are invalid, or if a
+// metric with the name already exists with a different type or labels.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	if r == nil {
+		return nil
+	}
+	return &Counter{family: r.register(name, help, KindCounter, labels, nil)}
+}
+
+// Gauge returns the gauge with the name, creating it if it doesn't exist. Values are recorded
+// with a value for each of the labels. It panics if the name or labels are invalid, or if a
+// metric with the name already exists with a different type or labels.
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	if r == nil {
+		return nil
+	}
+	return &Gauge{family: r.register(name, help, KindGauge, labels, nil)}
+}
+
+// Histogram returns the histogram with the name, creating it if it doesn't exist. Observed values
+// are counted into the buckets, which are the upper bounds of each bucket in increasing order,
+// or DefaultBuckets if none are provided. Values are recorded with a value for each of the labels.
+// It panics if the name, labels or buckets are invalid, or if a metric with the name already
+// exists with a different type, labels or buckets.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if r == nil {
+		return nil
+	}
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	return &Histogram{family: r.register(name, help, KindHistogram, labels, buckets)}
+}
+
+// Inc increments the counter by 1.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increases the counter by the value. Negative values are ignored, since a counter can only increase.
+func (c *Counter) Add(value float64, labelValues ...string) {
+	if c == nil || value < 0 {
+		return
+	}
+	c.family.update(labelValues, func(s *series) {
+		s.value += value
+	})
+}
+
+// Set sets the gauge to the value.
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	if g == nil {
+		return
+	}
+	g.family.update(labelValues, func(s *series) {
+		s.value = value
+	})
+}
+
+// Add adds the value to the gauge, which may be negative.
+func (g *Gauge) Add(value float64, labelValues ...string) {
+	if g == nil {
+		return
+	}
+	g.family.update(labelValues, func(s *series) {
+		s.value += value
+	})
+}
+
+// Observe counts the value into the histogram's buckets.
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	if h == nil {
+		return
+	}
+	bucket := sort.SearchFloat64s(h.family.buckets, value)
+	h.family.update(labelValues, func(s *series) {
+		s.count++
+		s.sum += value
+		s.bucketCounts[bucket]++
+	})
+}
+
+// ObserveDuration observes the duration in seconds, which is the unit DefaultBuckets are in.
+func (h *Histogram) ObserveDuration(d time.Duration, labelValues ...string) {
+	h.Observe(d.Seconds(), labelValues...)
+}
+
+// Gather returns a snapshot of every metric in the registry, sorted by name. Metrics that haven't
+// recorded any values yet are included without any series.
+func (r *Registry) Gather() []Family {
+	if r == nil {
+		return nil
+	}
+	r.mux.RLock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mux.RUnlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	snapshot := make([]Family, 0, len(families))
+	for _, f := range families {
+		snapshot = append(snapshot, f.snapshot())
+	}
+	return snapshot
+}
+
+// WriteText writes every metric in the registry to the writer in the Prometheus text format.
+func (r *Registry) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range r.Gather() {
+		fmt.Fprintf(bw, "# HELP %s %s\n", f.Name, escapeHelp(f.Help))
+		fmt.Fprintf(bw, "# TYPE %s %s\n", f.Name, f.Kind)
+		for _, s := range f.Series {
+			switch f.Kind {
+			case KindHistogram:
+				var cumulative uint64
+				for i, bound := range f.Buckets {
+					cumulative += s.BucketCounts[i]
+					labels := formatLabels(f.Labels, s.LabelValues, "le", formatFloat(bound))
+					fmt.Fprintf(bw, "%s_bucket%s %d\n", f.Name, labels, cumulative)
+				}
+				fmt.Fprintf(bw, "%s_bucket%s %d\n", f.Name, formatLabels(f.Labels, s.LabelValues, "le", "+Inf"), s.Count)
+				fmt.Fprintf(bw, "%s_sum%s %s\n", f.Name, formatLabels(f.Labels, s.LabelValues), formatFloat(s.Sum))
+				fmt.Fprintf(bw, "%s_count%s %d\n", f.Name, formatLabels(f.Labels, s.LabelValues), s.Count)
+			default:
+				fmt.Fprintf(bw, "%s%s %s\n", f.Name, formatLabels(f.Labels, s.LabelValues), formatFloat(s.Value))
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// Handler returns an http.Handler that serves every metric in the registry in the Prometheus
+// text format, so it can be scraped by Prometheus or any compatible collector.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", TextContentType)
+		r.WriteText(w)
+	})
+}
+
+// String returns the name of the kind, as used in the Prometheus text format.
+func (k Kind) String() string {
+	switch k {
+	case KindCounter:
+		return "counter"
+	case KindGauge:
+		return "gauge"
+	case KindHistogram:
+		return "histogram"
+	default:
+		return "untyped"
+	}
+}
+
+// register returns the metric with the name, creating it if it doesn't exist.
+func (r *Registry) register(name, help string, kind Kind, labels []string, buckets []float64) *family {
+	if !metricNamePattern.MatchString(name) {
+		panic(fmt.Sprintf("metrics: invalid metric name %q", name))
+	}
+	for _, label := range labels {
+		if !labelNamePattern.MatchString(label) || strings.HasPrefix(label, "__") || (kind == KindHistogram && label == "le") {
+			panic(fmt.Sprintf("metrics: invalid label name %q for metric %s", label, name))
+		}
+	}
+	for i := range buckets {
+		if math.IsNaN(buckets[i]) || (i > 0 && buckets[i] <= buckets[i-1]) {
+			panic(fmt.Sprintf("metrics: buckets for metric %s must be in increasing order", name))
+		}
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if existing, ok := r.families[name]; ok {
+		if existing.kind != kind || !slices.Equal(existing.labels, labels) || !slices.Equal(existing.buckets, buckets) {
+			panic(fmt.Sprintf("metrics: %s %s is already registered with a different type, labels or buckets", existing.kind, name))
+		}
+		return existing
+	}
+	f := &family{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  slices.Clone(labels),
+		buckets: slices.Clone(buckets),
+		series:  map[string]*series{},
+	}
+	r.families[name] = f
+	return f
+}
+
+// update applies the function to the series for the label values, creating the series if it
+// doesn't exist. Missing label values are recorded as empty, and extra values are ignored.
+func (f *family) update(labelValues []string, fn func(*series)) {
+	if len(labelValues) != len(f.labels) {
+		values := make([]string, len(f.labels))
+		copy(values, labelValues)
+		labelValues = values
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{
+			labelValues: slices.Clone(labelValues),
+			start:       time.Now(),
+		}
+		if f.kind == KindHistogram {
+			s.bucketCounts = make([]uint64, len(f.buckets)+1)
+		}
+		f.series[key] = s
+	}
+	fn(s)
+}
+
+// snapshot returns a copy of the metric and its series, with the series sorted by their label values.
+func (f *family) snapshot() Family {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	snapshot := Family{
+		Name:    f.name,
+		Help:    f.help,
+		Kind:    f.kind,
+		Labels:  slices.Clone(f.labels),
+		Buckets: slices.Clone(f.buckets),
+		Series:  make([]Series, 0, len(f.series)),
+	}
+	for _, s := range f.series {
+		snapshot.Series = append(snapshot.Series, Series{
+			LabelValues:  slices.Clone(s.labelValues),
+			Start:        s.start,
+			Value:        s.value,
+			Count:        s.count,
+			Sum:          s.sum,
+			BucketCounts: slices.Clone(s.bucketCounts),
+		})
+	}
+	sort.Slice(snapshot.Series, func(i, j int) bool {
+		return slices.Compare(snapshot.Series[i].LabelValues, snapshot.Series[j].LabelValues) < 0
+	})
+	return snapshot
+}
+
+// formatLabels formats the labels and their values, followed by any extra name and value pairs,
+// as a Prometheus label set. It returns an empty string when there are no labels.
+func formatLabels(names, values []string, extra ...string) string {
+	if len(names) == 0 && len(extra) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	write := func(name, value string) {
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(value))
+		b.WriteByte('"')
+	}
+	for i, name := range names {
+		write(name, values[i])
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		write(extra[i], extra[i+1])
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// formatFloat formats the value as it's written in the Prometheus text format.
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+// escapeHelp escapes backslashes and line feeds in a metric's description.
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+// escapeLabelValue escapes backslashes, double quotes and line feeds in a label value.
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package metrics
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	tests := []struct {
+		name     string
+		record   func(r *Registry)
+		expected string
+	}{
+		{
+			name: "counter",
+			record: func(r *Registry) {
+				c := r.Counter("orders_total", "Orders created.", "channel", "region")
+				c.Inc("web", "eu")
+				c.Add(2.5, "web", "eu")
+				c.Add(-1, "web", "eu")
+				c.Inc("api")
+			},
+			expected: "# HELP orders_total Orders created.\n" +
+				"# TYPE orders_total counter\n" +
+				`orders_total{channel="api",region=""} 1` + "\n" +
+				`orders_total{channel="web",region="eu"} 3.5` + "\n",
+		},
+		{
+			name: "gauge",
+			record: func(r *Registry) {
+				g := r.Gauge("connections", "Open connections.")
+				g.Set(5)
+				g.Add(-7)
+			},
+			expected: "# HELP connections Open connections.\n" +
+				"# TYPE connections gauge\n" +
+				"connections -2\n",
+		},
+		{
+			name: "histogram",
+			record: func(r *Registry) {
+				h := r.Histogram("latency_seconds", "Request latency.", []float64{0.1, 1}, "route")
+				h.Observe(0.05, "/orders")
+				h.Observe(0.1, "/orders")
+				h.Observe(0.5, "/orders")
+				h.Observe(3, "/orders")
+			},
+			expected: "# HELP latency_seconds Request latency.\n" +
+				"# TYPE latency_seconds histogram\n" +
+				`latency_seconds_bucket{route="/orders",le="0.1"} 2` + "\n" +
+				`latency_seconds_bucket{route="/orders",le="1"} 3` + "\n" +
+				`latency_seconds_bucket{route="/orders",le="+Inf"} 4` + "\n" +
+				`latency_seconds_sum{route="/orders"} 3.65` + "\n" +
+				`latency_seconds_count{route="/orders"} 4` + "\n",
+		},
+		{
+			name: "escaping",
+			record: func(r *Registry) {
+				r.Gauge("temperature", "Temperature in \\degrees,\nmeasured.", "sensor").Set(math.Inf(1), "a \"b\"\\c\n")
+			},
+			expected: "# HELP temperature Temperature in \\\\degrees,\\nmeasured.\n" +
+				"# TYPE temperature gauge\n" +
+				`temperature{sensor="a \"b\"\\c\n"} +Inf` + "\n",
+		},
+		{
+			name: "sorted by name, without values",
+			record: func(r *Registry) {
+				r.Counter("b_total", "B.")
+				r.Gauge("a", "A.")
+			},
+			expected: "# HELP a A.\n# TYPE a gauge\n# HELP b_total B.\n# TYPE b_total counter\n",
+		},
+		{
+			name: "nil metrics",
+			record: func(r *Registry) {
+				var nilRegistry *Registry
+				nilRegistry.Counter("orders_total", "Orders created.").Inc()
+				nilRegistry.Histogram("latency_seconds", "Request latency.", nil).Observe(1)
+			},
+			expected: "",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := NewRegistry()
+			test.record(r)
+			var text strings.Builder
+			if err := r.WriteText(&text); err != nil {
+				t.Fatalf("failed to write metrics: %s", err.Error())
+			}
+			if text.String() != test.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", test.expected, text.String())
+			}
+		})
+	}
+}
+
+func TestRegisterPanics(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(r *Registry)
+	}{
+		{name: "invalid name", register: func(r *Registry) { r.Counter("orders-total", "") }},
+		{name: "invalid label", register: func(r *Registry) { r.Counter("orders_total", "", "1channel") }},
+		{name: "reserved label", register: func(r *Registry) { r.Counter("orders_total", "", "__channel") }},
+		{name: "le label on a histogram", register: func(r *Registry) { r.Histogram("latency_seconds", "", nil, "le") }},
+		{name: "unordered buckets", register: func(r *Registry) { r.Histogram("latency_seconds", "", []float64{1, 0.5}) }},
+		{name: "different kind", register: func(r *Registry) { r.Counter("orders", ""); r.Gauge("orders", "") }},
+		{name: "different labels", register: func(r *Registry) { r.Counter("orders", "", "a"); r.Counter("orders", "", "b") }},
+		{name: "different buckets", register: func(r *Registry) {
+			r.Histogram("latency_seconds", "", []float64{1})
+			r.Histogram("latency_seconds", "", []float64{2})
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected registering the metric to panic")
+				}
+			}()
+			test.register(NewRegistry())
+		})
+	}
+
+	// Registering the same metric again returns it
+	r := NewRegistry()
+	r.Counter("orders_total", "", "channel").Inc("web")
+	r.Counter("orders_total", "", "channel").Inc("web")
+	if series := r.Gather()[0].Series; len(series) != 1 || series[0].Value != 2 {
+		t.Errorf("expected both counters to record to the same series, got %+v", series)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.Gauge("up", "Whether the service is up.").Set(1)
+
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != TextContentType {
+		t.Errorf("expected status %d and content type %q, got %d and %q", http.StatusOK, TextContentType, w.Code, w.Header().Get("Content-Type"))
+	}
+	if !strings.HasSuffix(w.Body.String(), "\nup 1\n") {
+		t.Errorf("unexpected body:\n%s", w.Body.String())
+	}
+}
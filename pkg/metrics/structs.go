@@ -0,0 +1,133 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/option"
+)
+
+// Kind identifies the type of a metric.
+type Kind int
+
+const (
+	KindCounter   Kind = iota // A value that only increases, such as a number of requests
+	KindGauge                 // A value that can go up and down, such as a number of open connections
+	KindHistogram             // A distribution of observed values, such as request latencies
+)
+
+// Registry holds a set of metrics, and exposes them in the Prometheus text format. The methods
+// of a nil *Registry return nil metrics, which discard every value recorded with them.
+type Registry struct {
+	families map[string]*family // Metrics keyed by name
+	mux      sync.RWMutex       // Mutex for synchronizing access to the families
+}
+
+// Counter is a metric whose value only increases. A nil *Counter discards every value.
+type Counter struct {
+	family *family
+}
+
+// Gauge is a metric whose value can go up and down. A nil *Gauge discards every value.
+type Gauge struct {
+	family *family
+}
+
+// Histogram is a metric that counts observed values into buckets. A nil *Histogram discards every value.
+type Histogram struct {
+	family *family
+}
+
+// Family is a snapshot of a metric, with a Series for every combination of label values it
+// has recorded a value for.
+type Family struct {
+	Name    string    // Name of the metric
+	Help    string    // Description of the metric
+	Kind    Kind      // Type of the metric
+	Labels  []string  // Names of the metric's labels
+	Buckets []float64 // Upper bounds of the histogram's buckets, in increasing order
+	Series  []Series  // Values of the metric, one for each combination of label values
+}
+
+// Series is a snapshot of the value of a metric for one combination of label values.
+type Series struct {
+	LabelValues  []string  // Values of the metric's labels, in the order of Family.Labels
+	Start        time.Time // Time the series was first recorded, from which counters and histograms accumulate
+	Value        float64   // Value of a counter or gauge
+	Count        uint64    // Number of values observed by a histogram
+	Sum          float64   // Sum of the values observed by a histogram
+	BucketCounts []uint64  // Number of values observed by a histogram in each bucket, followed by the number above the last bucket
+}
+
+// CloudMonitoringConfig holds configuration details for a CloudMonitoringExporter.
+type CloudMonitoringConfig struct {
+	GCPProjectID  string                // Google Cloud Project ID the metrics are written to
+	Job           string                // Name of the job (e.g. the service name) the metrics are written for
+	Interval      time.Duration         // How often the metrics are pushed, defaults to 60 seconds
+	MetricPrefix  string                // Prefix added to the name of every metric, defaults to "custom.googleapis.com/"
+	Resource      *MonitoredResource    // Resource the metrics are written for, defaults to a generic_task for the job
+	ClientOptions []option.ClientOption // Optional settings used to create the Cloud Monitoring client (e.g., credentials or an endpoint)
+}
+
+// MonitoredResource identifies the resource metrics are written for in Cloud Monitoring.
+type MonitoredResource struct {
+	Type   string            // Type of the resource (e.g. "generic_task")
+	Labels map[string]string // Labels identifying the resource, as required by its type
+}
+
+// family holds every series of a single metric.
+type family struct {
+	name    string
+	help    string
+	kind    Kind
+	labels  []string
+	buckets []float64
+	series  map[string]*series // Series keyed by their label values
+	mux     sync.Mutex         // Mutex for synchronizing access to the series
+}
+
+// series holds the value of a metric for one combination of label values.
+type series struct {
+	labelValues  []string
+	start        time.Time
+	value        float64
+	count        uint64
+	sum          float64
+	bucketCounts []uint64
+}
+
+// Validate checks the CloudMonitoringConfig struct for required fields and
+// returns an error if any required fields are missing
+func (c *CloudMonitoringConfig) Validate() error {
+	if c.GCPProjectID == "" {
+		return fmt.Errorf("GCPProjectID is empty")
+	}
+	if c.Resource == nil && strings.TrimSpace(c.Job) == "" {
+		return fmt.Errorf("Job is empty, it's required when no Resource is provided")
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package pubsub
+
+// Publisher is implemented by anything that can publish messages to a Pub/Sub topic.
+// PubSub satisfies this interface, and it allows alternative implementations (such as
+// in-memory fakes used in tests) to be substituted wherever messages are published.
+type Publisher interface {
+	// Publish sends a message to the specified topic and returns the ID assigned to it.
+	// The message can be a string, a byte slice, or any value that can be marshaled to JSON.
+	Publish(topic string, message interface{}) (string, error)
+}
+
+// AttributePublisher is implemented by publishers that can attach attributes to the messages
+// they publish. PubSub satisfies this interface. Publishers that only implement Publisher
+// publish messages without their attributes.
+type AttributePublisher interface {
+	// PublishWithAttributes sends a message with the attributes to the specified topic and
+	// returns the ID assigned to it.
+	PublishWithAttributes(topic string, message interface{}, attributes map[string]string) (string, error)
+}
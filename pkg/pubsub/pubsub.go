@@ -30,6 +30,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	ps "cloud.google.com/go/pubsub"
 	"google.golang.org/api/idtoken"
@@ -50,7 +51,7 @@ func New(ctx context.Context, config Config) (*PubSub, error) {
 	}
 
 	// Initialize the Pub/Sub client
-	client, err := ps.NewClient(ctx, config.GCPProjectID)
+	client, err := ps.NewClient(ctx, config.GCPProjectID, config.ClientOptions...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Pub/Sub client: %w", err)
 	}
@@ -60,12 +61,23 @@ func New(ctx context.Context, config Config) (*PubSub, error) {
 		ctx:    ctx,
 		Client: client,
 		Topics: make(map[string]*ps.Topic),
+		publishErrors: config.Metrics.Counter("pubsub_publish_errors_total",
+			"Number of messages that failed to publish to Pub/Sub, by topic.", "topic"),
+		publishLatency: config.Metrics.Histogram("pubsub_publish_duration_seconds",
+			"Time taken to publish messages to Pub/Sub, by topic.", nil, "topic"),
 	}, nil
 }
 
 // Publish sends a message to the specified Pub/Sub topic.
 // It returns the message ID or an error if the operation fails.
 func (p *PubSub) Publish(topic string, message interface{}) (string, error) {
+	return p.PublishWithAttributes(topic, message, nil)
+}
+
+// PublishWithAttributes sends a message with the attributes to the specified Pub/Sub topic.
+// Subscribers receive the attributes alongside the message data.
+// It returns the message ID or an error if the operation fails.
+func (p *PubSub) PublishWithAttributes(topic string, message interface{}, attributes map[string]string) (string, error) {
 	// Ensure the client is initialized
 	if p.Client == nil {
 		return "", errors.New("Pub/Sub client is not initialized")
@@ -86,7 +98,7 @@ func (p *PubSub) Publish(topic string, message interface{}) (string, error) {
 	}
 
 	// Serialize the message into bytes
-	data, err := p.serializeMessage(message)
+	data, err := SerializeMessage(message)
 	if err != nil {
 		return "", fmt.Errorf("failed to serialize message: %w", err)
 	}
@@ -97,14 +109,32 @@ func (p *PubSub) Publish(topic string, message interface{}) (string, error) {
 	}
 
 	// Publish the message and return the message ID or an error
-	result := t.Publish(p.ctx, &ps.Message{Data: data})
+	start := time.Now()
+	result := t.Publish(p.ctx, &ps.Message{Data: data, Attributes: attributes})
 	msgID, err := result.Get(p.ctx)
+	p.publishLatency.ObserveDuration(time.Since(start), topic)
 	if err != nil {
+		p.publishErrors.Inc(topic)
 		return "", fmt.Errorf("failed to publish message: %w", err)
 	}
 	return msgID, nil
 }
 
+// Close stops every cached topic, flushing any messages that are still being published,
+// and then closes the Pub/Sub client.
+func (p *PubSub) Close() error {
+	p.Mux.Lock()
+	defer p.Mux.Unlock()
+	for name, topic := range p.Topics {
+		topic.Stop()
+		delete(p.Topics, name)
+	}
+	if p.Client == nil {
+		return nil
+	}
+	return p.Client.Close()
+}
+
 // ValidateGooglePubSubRequest validates an incoming HTTP request from Google Pub/Sub
 // by checking its Authorization header for a Bearer token. It ensures that the token
 // is well-formed, verifies it using Google's ID token validation, and optionally
@@ -146,9 +176,9 @@ func ValidateGooglePubSubRequest(ctx context.Context, r *http.Request, audience
 	return nil
 }
 
-// serializeMessage converts the message to a byte slice based on its type.
+// SerializeMessage converts the message to a byte slice based on its type.
 // Supports string, []byte, or marshals other types into JSON.
-func (p *PubSub) serializeMessage(message interface{}) ([]byte, error) {
+func SerializeMessage(message interface{}) ([]byte, error) {
 	switch v := message.(type) {
 	case string:
 		return []byte(v), nil
@@ -28,20 +28,26 @@ import (
 	"sync"
 
 	ps "cloud.google.com/go/pubsub"
+	"github.com/albeebe/service/pkg/metrics"
+	"google.golang.org/api/option"
 )
 
 // PubSub handles publishing messages to Google Pub/Sub topics.
 // It manages the Pub/Sub client and a map of topics for reuse.
 type PubSub struct {
-	ctx    context.Context
-	Client *ps.Client
-	Topics map[string]*ps.Topic
-	Mux    sync.RWMutex
+	ctx            context.Context
+	Client         *ps.Client
+	Topics         map[string]*ps.Topic
+	Mux            sync.RWMutex
+	publishErrors  *metrics.Counter   // Counts messages that failed to publish, by topic
+	publishLatency *metrics.Histogram // Observes how long messages take to publish, by topic
 }
 
 // Config holds configuration details for PubSub.
 type Config struct {
-	GCPProjectID string
+	GCPProjectID  string                // Google Cloud Project ID the topics belong to
+	ClientOptions []option.ClientOption // Optional settings used to create the Pub/Sub client (e.g., credentials)
+	Metrics       *metrics.Registry     // Optional registry that publish latencies and errors are recorded in
 }
 
 // validate checks the Config struct for required fields and
@@ -23,6 +23,7 @@
 package router
 
 import (
+	"context"
 	"errors"
 	"io"
 	"log"
@@ -31,11 +32,14 @@ import (
 )
 
 // awaitContextDone waits for the context's cancellation or completion signal (ctx.Done()).
-// Once the context is done, it gracefully shuts down the router. If an error occurs during
-// the shutdown process, it logs the error message.
+// Once the context is done, it gracefully shuts down the router, allowing up to the configured
+// shutdown timeout for ongoing connections to finish. If an error occurs during the shutdown
+// process, it logs the error message.
 func (r *Router) awaitContextDone() {
 	<-r.ctx.Done()
-	if err := r.Shutdown(); err != nil {
+	ctx, cancel := context.WithTimeout(context.Background(), r.shutdownTimeout)
+	defer cancel()
+	if err := r.Shutdown(ctx); err != nil {
 		log.Printf("router failed to shutdown: %s", err.Error())
 	}
 }
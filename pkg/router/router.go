@@ -24,10 +24,13 @@ package router
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -50,7 +53,13 @@ func NewRouter(ctx context.Context, config Config) (*Router, error) {
 	}
 
 	// Initialize the Router struct
-	router := &Router{ctx: ctx}
+	router := &Router{
+		ctx:             ctx,
+		shutdownTimeout: config.ShutdownTimeout,
+	}
+	if router.shutdownTimeout <= 0 {
+		router.shutdownTimeout = 5 * time.Second
+	}
 
 	// Set Gin mode to release
 	gin.SetMode(gin.ReleaseMode)
@@ -71,6 +80,15 @@ func NewRouter(ctx context.Context, config Config) (*Router, error) {
 		})
 	}
 
+	// Set up the 405 route. The Allow header listing the methods the path supports is
+	// set before the handler is invoked.
+	if config.NoMethodHandler != nil {
+		router.ginRouter.HandleMethodNotAllowed = true
+		router.ginRouter.NoMethod(func(c *gin.Context) {
+			(*config.NoMethodHandler)(c.Writer, c.Request)
+		})
+	}
+
 	// Apply CORS middleware
 	if config.Cors != nil {
 		router.ginRouter.Use(cors.New(cors.Config{
@@ -83,13 +101,17 @@ func NewRouter(ctx context.Context, config Config) (*Router, error) {
 		}))
 	}
 
-	// Set up the HTTP server
+	// Set up the HTTP server. Requests are passed through any middleware added with Use
+	// before they're routed.
+	router.handler = h2c.NewHandler(
+		router.ginRouter,
+		&http2.Server{},
+	)
 	router.server = &http.Server{
 		Addr: config.Host,
-		Handler: h2c.NewHandler(
-			router.ginRouter,
-			&http2.Server{},
-		),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			router.handler.ServeHTTP(w, req)
+		}),
 	}
 
 	// Gracefully shutdown the server when the context is canceled
@@ -100,18 +122,44 @@ func NewRouter(ctx context.Context, config Config) (*Router, error) {
 
 // ListenAndServe starts the HTTP server in a separate goroutine and returns a channel that captures any errors.
 func (r *Router) ListenAndServe() chan error {
-	errorChan := make(chan error)
+	errorChan := make(chan error, 1)
 	go func() {
-		errorChan <- r.server.ListenAndServe()
+		listener, err := net.Listen("tcp", r.server.Addr)
+		if err != nil {
+			errorChan <- err
+			return
+		}
+		r.serving.Store(true)
+		defer r.serving.Store(false)
+		errorChan <- r.server.Serve(listener)
 	}()
 	return errorChan
 }
 
-// RegisterHandler registers a handler for the specified HTTP method and path.
-func (r *Router) RegisterHandler(method, relativePath string, handler func(w http.ResponseWriter, r *http.Request)) error {
+// Serving reports whether the server is currently accepting connections.
+func (r *Router) Serving() bool {
+	return r.serving.Load()
+}
+
+// RegisterHandler registers a handler for the specified HTTP method and path. An error is
+// returned if the method is not supported, or if the path is invalid or conflicts with a
+// path that's already registered.
+func (r *Router) RegisterHandler(method, relativePath string, handler func(w http.ResponseWriter, r *http.Request)) (err error) {
+
+	// Gin panics when a path is invalid or conflicts with an existing path, so recover
+	// and return the panic as an error instead
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("failed to register path '%s': %v", relativePath, recovered)
+		}
+	}()
 
-	// Middleware wrapper to adapt standard http.Handler to Gin's context
+	// Middleware wrapper to adapt standard http.Handler to Gin's context. Path parameters
+	// are made available to the handler through the request's PathValue method.
 	wrappedHandler := func(c *gin.Context) {
+		for _, param := range c.Params {
+			c.Request.SetPathValue(param.Key, param.Value)
+		}
 		handler(c.Writer, c.Request)
 	}
 
@@ -137,45 +185,81 @@ func (r *Router) RegisterHandler(method, relativePath string, handler func(w htt
 
 // SendResponse sends an HTTP response with the provided status code, headers, and body
 // content to the client. It streams the body data in chunks, ensures  headers are set
-// correctly, and handles client disconnection or errors during streaming.
+// correctly, and handles client disconnection or errors during streaming. The first chunk
+// of the body is read before anything is written, so if reading it fails, the error is
+// returned with the response unsent, and the caller can still send an error response.
 func SendResponse(w http.ResponseWriter, statusCode int, headers http.Header, body io.ReadCloser) error {
 
-	// Set the headers
+	// Read the first chunk of the body
+	var buf []byte
+	var n int
+	var err error
+	if body != nil {
+		defer body.Close()
+		buf = make([]byte, 4096)
+		n, err = body.Read(buf)
+		if n == 0 && err != nil && err != io.EOF {
+			return err
+		}
+	}
+
+	// Set the headers, replacing any existing values while keeping every value of
+	// headers that have more than one (e.g. Set-Cookie)
 	for key, values := range headers {
+		w.Header().Del(key)
 		for _, value := range values {
-			w.Header().Set(key, value)
+			w.Header().Add(key, value)
 		}
 	}
 
 	// Set the HTTP status code
 	w.WriteHeader(statusCode)
 
-	// If the body is provided, stream it to the client and ensure it gets closed
-	if body != nil {
-		defer body.Close()
-		buf := make([]byte, 4096)
-		for {
-			n, err := body.Read(buf)
-			if n > 0 {
-				if _, writeErr := w.Write(buf[:n]); writeErr != nil {
-					if isClientDisconnected(writeErr) {
-						return nil
-					}
-					return writeErr
+	// If the body is provided, stream it to the client
+	if body == nil {
+		return nil
+	}
+	for {
+		if n > 0 {
+			if _, writeErr := w.Write(buf[:n]); writeErr != nil {
+				if isClientDisconnected(writeErr) {
+					return nil
 				}
+				return writeErr
 			}
-			if err != nil {
-				if err == io.EOF {
-					break
-				}
-				return err
+		}
+		if err != nil {
+			if err == io.EOF {
+				return nil
 			}
+			return err
 		}
+		n, err = body.Read(buf)
 	}
-	return nil
+}
+
+// Use wraps every request handled by the router, including requests for undefined routes,
+// with the middleware. Middleware added later wraps the middleware added before it, so it runs
+// first. Use must be called before the router starts serving requests.
+func (r *Router) Use(middleware func(next http.Handler) http.Handler) {
+	r.handler = middleware(r.handler)
+}
+
+// ServeHTTP dispatches the request to the registered handlers in-process, without going
+// through the network listener. This allows the router to be exercised with tools like httptest.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.server.Handler.ServeHTTP(w, req)
 }
 
 // Shutdown gracefully shuts down the server, waiting for ongoing connections to finish.
-func (r *Router) Shutdown() error {
-	return r.server.Shutdown(context.Background())
+// If the context expires before they finish, Shutdown returns the context's error.
+// It's safe to call Shutdown more than once.
+func (r *Router) Shutdown(ctx context.Context) error {
+	r.serving.Store(false)
+	err := r.server.Shutdown(ctx)
+	if errors.Is(err, net.ErrClosed) {
+		// The listener was already closed by an earlier call
+		return nil
+	}
+	return err
 }
@@ -26,6 +26,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -33,9 +34,12 @@ import (
 
 // Router wraps the HTTP server, managing routing and lifecycle (graceful shutdown).
 type Router struct {
-	ctx       context.Context // Manages router lifecycle (shutdown on cancel)
-	ginRouter *gin.Engine     // Gin engine for routing HTTP requests
-	server    *http.Server    // HTTP server handling requests and shutdown
+	ctx             context.Context // Manages router lifecycle (shutdown on cancel)
+	ginRouter       *gin.Engine     // Gin engine for routing HTTP requests
+	handler         http.Handler    // Handler for every request, wrapping the Gin engine with any middleware
+	server          *http.Server    // HTTP server handling requests and shutdown
+	serving         atomic.Bool     // Whether the server is accepting connections
+	shutdownTimeout time.Duration   // Maximum time to wait for connections to finish when the context is canceled
 }
 
 // Cors defines the structure for configuring Cross-Origin Resource Sharing (CORS) settings.
@@ -79,9 +83,11 @@ type Cors struct {
 // Config holds the server configuration options, including the host address,
 // CORS settings, and a custom handler for undefined routes.
 type Config struct {
-	Host           string                                        // Server host address
-	Cors           *Cors                                         // CORS configuration
-	NoRouteHandler *func(w http.ResponseWriter, r *http.Request) // Custom handler for undefined routes
+	Host            string                                        // Server host address
+	Cors            *Cors                                         // CORS configuration
+	NoMethodHandler *func(w http.ResponseWriter, r *http.Request) // Custom handler for routes that exist, but not for the request's method (responds with 404 when nil)
+	NoRouteHandler  *func(w http.ResponseWriter, r *http.Request) // Custom handler for undefined routes
+	ShutdownTimeout time.Duration                                 // Maximum time to wait for connections to finish when the context is canceled (defaults to 5 seconds)
 }
 
 // validate checks the Config struct for required fields and
@@ -0,0 +1,204 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package tracing
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	cloudtrace "google.golang.org/api/cloudtrace/v2"
+	"google.golang.org/api/option"
+)
+
+// CloudTraceContextHeader is the header Google Cloud uses to propagate trace context, in the
+// format "TRACE_ID/SPAN_ID;o=OPTIONS", where the span ID is a decimal number.
+const CloudTraceContextHeader = "X-Cloud-Trace-Context"
+
+// maxAttributesPerSpan is the most attributes Cloud Trace accepts on a single span.
+const maxAttributesPerSpan = 32
+
+// Inject sets the X-Cloud-Trace-Context header from the span context in the context, if it has one.
+func (CloudTraceContext) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	spanID := sc.SpanID()
+	options := 0
+	if sc.IsSampled() {
+		options = 1
+	}
+	carrier.Set(CloudTraceContextHeader, fmt.Sprintf("%s/%d;o=%d", sc.TraceID(), binary.BigEndian.Uint64(spanID[:]), options))
+}
+
+// Extract returns a copy of the context with the remote span context described by the
+// X-Cloud-Trace-Context header. The context is returned unchanged if the header is missing or invalid.
+func (CloudTraceContext) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	header := carrier.Get(CloudTraceContextHeader)
+	if header == "" {
+		return ctx
+	}
+
+	// Split the header into its trace ID, span ID and options
+	traceIDHex, rest, ok := strings.Cut(header, "/")
+	if !ok {
+		return ctx
+	}
+	spanIDDecimal, options, _ := strings.Cut(rest, ";")
+
+	traceID, err := trace.TraceIDFromHex(traceIDHex)
+	if err != nil {
+		return ctx
+	}
+	spanIDValue, err := strconv.ParseUint(spanIDDecimal, 10, 64)
+	if err != nil || spanIDValue == 0 {
+		return ctx
+	}
+	var spanID trace.SpanID
+	binary.BigEndian.PutUint64(spanID[:], spanIDValue)
+
+	config := trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Remote:  true,
+	}
+	if options == "o=1" {
+		config.TraceFlags = trace.FlagsSampled
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(config))
+}
+
+// Fields returns the header the propagator reads and writes.
+func (CloudTraceContext) Fields() []string {
+	return []string{CloudTraceContextHeader}
+}
+
+// NewCloudTraceExporter creates an exporter that writes spans to Cloud Trace in the project. The
+// options are used to create the Cloud Trace client, so they can provide credentials, or point
+// the exporter at a local stand-in for Cloud Trace.
+func NewCloudTraceExporter(ctx context.Context, projectID string, opts ...option.ClientOption) (*CloudTraceExporter, error) {
+	if projectID == "" {
+		return nil, errors.New("projectID is empty")
+	}
+	client, err := cloudtrace.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Cloud Trace client: %w", err)
+	}
+	return &CloudTraceExporter{
+		client:    client,
+		projectID: projectID,
+	}, nil
+}
+
+// ExportSpans writes the spans to Cloud Trace.
+func (e *CloudTraceExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	request := &cloudtrace.BatchWriteSpansRequest{
+		Spans: make([]*cloudtrace.Span, 0, len(spans)),
+	}
+	for _, span := range spans {
+		request.Spans = append(request.Spans, e.convertSpan(span))
+	}
+	if _, err := e.client.Projects.Traces.BatchWrite("projects/"+e.projectID, request).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to write spans to Cloud Trace: %w", err)
+	}
+	return nil
+}
+
+// Shutdown is required to satisfy the sdktrace.SpanExporter interface. The exporter doesn't
+// buffer any spans, so there's nothing to flush.
+func (e *CloudTraceExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// convertSpan converts the span to the format written to Cloud Trace.
+func (e *CloudTraceExporter) convertSpan(span sdktrace.ReadOnlySpan) *cloudtrace.Span {
+	sc := span.SpanContext()
+	converted := &cloudtrace.Span{
+		Name:                    fmt.Sprintf("projects/%s/traces/%s/spans/%s", e.projectID, sc.TraceID(), sc.SpanID()),
+		SpanId:                  sc.SpanID().String(),
+		DisplayName:             &cloudtrace.TruncatableString{Value: span.Name()},
+		StartTime:               span.StartTime().UTC().Format(time.RFC3339Nano),
+		EndTime:                 span.EndTime().UTC().Format(time.RFC3339Nano),
+		SameProcessAsParentSpan: span.Parent().IsValid() && !span.Parent().IsRemote(),
+		Attributes:              &cloudtrace.Attributes{AttributeMap: map[string]cloudtrace.AttributeValue{}},
+	}
+	if span.Parent().IsValid() {
+		converted.ParentSpanId = span.Parent().SpanID().String()
+	}
+
+	switch span.SpanKind() {
+	case trace.SpanKindServer:
+		converted.SpanKind = "SERVER"
+	case trace.SpanKindClient:
+		converted.SpanKind = "CLIENT"
+	case trace.SpanKindProducer:
+		converted.SpanKind = "PRODUCER"
+	case trace.SpanKindConsumer:
+		converted.SpanKind = "CONSUMER"
+	default:
+		converted.SpanKind = "INTERNAL"
+	}
+
+	// Record the span's attributes, followed by the attributes of the resource that created it
+	attributes := append(append([]attribute.KeyValue{}, span.Attributes()...), span.Resource().Attributes()...)
+	for _, kv := range attributes {
+		if len(converted.Attributes.AttributeMap) == maxAttributesPerSpan {
+			converted.Attributes.DroppedAttributesCount++
+			continue
+		}
+		converted.Attributes.AttributeMap[string(kv.Key)] = convertAttributeValue(kv.Value)
+	}
+
+	if span.Status().Code == codes.Error {
+		converted.Status = &cloudtrace.Status{
+			Code:    2, // UNKNOWN
+			Message: span.Status().Description,
+		}
+	}
+	return converted
+}
+
+// convertAttributeValue converts an attribute value to one of the types Cloud Trace supports.
+func convertAttributeValue(value attribute.Value) cloudtrace.AttributeValue {
+	switch value.Type() {
+	case attribute.BOOL:
+		return cloudtrace.AttributeValue{BoolValue: value.AsBool(), ForceSendFields: []string{"BoolValue"}}
+	case attribute.INT64:
+		return cloudtrace.AttributeValue{IntValue: value.AsInt64(), ForceSendFields: []string{"IntValue"}}
+	default:
+		return cloudtrace.AttributeValue{StringValue: &cloudtrace.TruncatableString{Value: value.Emit()}}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestCloudTraceContext(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("105445aa7843bc8bf206b12000100000")
+	spanID, _ := trace.SpanIDFromHex("0000000000000001")
+	tests := []struct {
+		name    string
+		header  string
+		valid   bool // valid is whether a span context is expected to be extracted
+		sampled bool
+	}{
+		{name: "sampled", header: "105445aa7843bc8bf206b12000100000/1;o=1", valid: true, sampled: true},
+		{name: "not sampled", header: "105445aa7843bc8bf206b12000100000/1;o=0", valid: true},
+		{name: "without options", header: "105445aa7843bc8bf206b12000100000/1", valid: true},
+		{name: "missing", header: ""},
+		{name: "missing span ID", header: "105445aa7843bc8bf206b12000100000;o=1"},
+		{name: "invalid trace ID", header: "not-a-trace-id/1;o=1"},
+		{name: "zero trace ID", header: "00000000000000000000000000000000/1;o=1"},
+		{name: "invalid span ID", header: "105445aa7843bc8bf206b12000100000/abc;o=1"},
+		{name: "zero span ID", header: "105445aa7843bc8bf206b12000100000/0;o=1"},
+		{name: "span ID out of range", header: "105445aa7843bc8bf206b12000100000/18446744073709551616;o=1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			carrier := propagation.HeaderCarrier{}
+			if test.header != "" {
+				carrier.Set(CloudTraceContextHeader, test.header)
+			}
+			sc := trace.SpanContextFromContext(CloudTraceContext{}.Extract(context.Background(), carrier))
+			if sc.IsValid() != test.valid {
+				t.Fatalf("expected a span context to be extracted: %t, got %v", test.valid, sc)
+			}
+			if !test.valid {
+				return
+			}
+			if sc.TraceID() != traceID || sc.SpanID() != spanID || sc.IsSampled() != test.sampled || !sc.IsRemote() {
+				t.Errorf("unexpected span context %v", sc)
+			}
+
+			// Injecting the extracted span context writes the header it was read from, with its options
+			injected := propagation.HeaderCarrier{}
+			CloudTraceContext{}.Inject(trace.ContextWithSpanContext(context.Background(), sc), injected)
+			expected := "105445aa7843bc8bf206b12000100000/1;o=0"
+			if test.sampled {
+				expected = "105445aa7843bc8bf206b12000100000/1;o=1"
+			}
+			if header := injected.Get(CloudTraceContextHeader); header != expected {
+				t.Errorf("expected the header %q, got %q", expected, header)
+			}
+		})
+	}
+
+	// Nothing is injected without a span context
+	carrier := propagation.HeaderCarrier{}
+	CloudTraceContext{}.Inject(context.Background(), carrier)
+	if len(carrier) != 0 {
+		t.Errorf("expected no headers, got %v", carrier)
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OpenDB opens a database with the driver and data source name, creating a client span for every
+// query, statement and transaction. The system attribute (e.g. semconv.DBSystemMySQL) is recorded
+// on every span to identify the type of database.
+func OpenDB(d driver.Driver, dsn string, tracerProvider trace.TracerProvider, system attribute.KeyValue) *sql.DB {
+	return sql.OpenDB(&tracedConnector{
+		driver: d,
+		dsn:    dsn,
+		system: system,
+		tracer: tracerProvider.Tracer(InstrumentationName),
+	})
+}
+
+// tracedConnector opens connections with the driver, wrapped so their use is traced.
+type tracedConnector struct {
+	driver driver.Driver
+	dsn    string
+	system attribute.KeyValue
+	tracer trace.Tracer
+}
+
+// Connect opens a connection with the driver.
+func (c *tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	var conn driver.Conn
+	var err error
+	if dc, ok := c.driver.(driver.DriverContext); ok {
+		var connector driver.Connector
+		if connector, err = dc.OpenConnector(c.dsn); err != nil {
+			return nil, err
+		}
+		conn, err = connector.Connect(ctx)
+	} else {
+		conn, err = c.driver.Open(c.dsn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn, connector: c}, nil
+}
+
+// Driver returns the underlying driver.
+func (c *tracedConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// start starts a client span for a database operation.
+func (c *tracedConnector) start(ctx context.Context, operation, query string) (context.Context, trace.Span) {
+	attributes := []attribute.KeyValue{c.system, semconv.DBOperationName(operation)}
+	if query != "" {
+		attributes = append(attributes, semconv.DBQueryText(query))
+	}
+	return c.tracer.Start(ctx, "sql."+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+}
+
+// end ends the span, recording the error if the operation failed. driver.ErrSkip isn't recorded,
+// since it only tells database/sql to fall back to another way of performing the operation.
+func end(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, driver.ErrSkip) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// tracedConn is a connection that creates a span for every query, statement and transaction.
+// It implements the context aware interfaces of database/sql/driver, and reports driver.ErrSkip
+// when the underlying connection doesn't, so database/sql falls back to its other interfaces.
+type tracedConn struct {
+	driver.Conn
+	connector *tracedConnector
+}
+
+// ExecContext executes the query within a span.
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (result driver.Result, err error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, span := c.connector.start(ctx, "exec", query)
+	defer func() { end(span, err) }()
+	return execer.ExecContext(ctx, query, args)
+}
+
+// QueryContext executes the query within a span.
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (rows driver.Rows, err error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, span := c.connector.start(ctx, "query", query)
+	defer func() { end(span, err) }()
+	return queryer.QueryContext(ctx, query, args)
+}
+
+// PrepareContext prepares the statement within a span, and returns a statement that executes
+// within spans of its own.
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (stmt driver.Stmt, err error) {
+	ctx, span := c.connector.start(ctx, "prepare", query)
+	defer func() { end(span, err) }()
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tracedStmt{Stmt: stmt, connector: c.connector, query: query}, nil
+}
+
+// BeginTx begins a transaction within a span.
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (tx driver.Tx, err error) {
+	parent := ctx
+	ctx, span := c.connector.start(ctx, "begin", "")
+	defer func() { end(span, err) }()
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = beginner.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.Conn.Begin()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tracedTx{Tx: tx, ctx: parent, connector: c.connector}, nil
+}
+
+// Ping verifies the connection is still alive, if the underlying connection supports it.
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// ResetSession resets the connection before it's reused, if the underlying connection supports it.
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid reports whether the connection can be reused, if the underlying connection supports it.
+func (c *tracedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// CheckNamedValue checks the argument with the underlying connection, if it supports it.
+func (c *tracedConn) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+// tracedStmt is a prepared statement that executes within a span.
+type tracedStmt struct {
+	driver.Stmt
+	connector *tracedConnector
+	query     string
+}
+
+// ExecContext executes the statement within a span.
+func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (result driver.Result, err error) {
+	ctx, span := s.connector.start(ctx, "exec", s.query)
+	defer func() { end(span, err) }()
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		return execer.ExecContext(ctx, args)
+	}
+	return s.Stmt.Exec(namedValuesToValues(args))
+}
+
+// QueryContext executes the statement within a span.
+func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (rows driver.Rows, err error) {
+	ctx, span := s.connector.start(ctx, "query", s.query)
+	defer func() { end(span, err) }()
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		return queryer.QueryContext(ctx, args)
+	}
+	return s.Stmt.Query(namedValuesToValues(args))
+}
+
+// tracedTx is a transaction whose commit or rollback is recorded within a span.
+type tracedTx struct {
+	driver.Tx
+	ctx       context.Context
+	connector *tracedConnector
+}
+
+// Commit commits the transaction within a span.
+func (t *tracedTx) Commit() (err error) {
+	_, span := t.connector.start(t.ctx, "commit", "")
+	defer func() { end(span, err) }()
+	return t.Tx.Commit()
+}
+
+// Rollback rolls back the transaction within a span.
+func (t *tracedTx) Rollback() (err error) {
+	_, span := t.connector.start(t.ctx, "rollback", "")
+	defer func() { end(span, err) }()
+	return t.Tx.Rollback()
+}
+
+// namedValuesToValues converts the arguments for statements that don't support named arguments.
+func namedValuesToValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
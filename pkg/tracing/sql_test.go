@@ -0,0 +1,221 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+func TestOpenDB(t *testing.T) {
+	tests := []struct {
+		name     string
+		run      func(ctx context.Context, db *sql.DB) error
+		expected []string // expected are the names of the spans, with the failed ones followed by "!"
+	}{
+		{
+			name: "exec",
+			run: func(ctx context.Context, db *sql.DB) error {
+				_, err := db.ExecContext(ctx, "UPDATE orders SET paid = 1")
+				return err
+			},
+			expected: []string{"sql.exec"},
+		},
+		{
+			name: "query",
+			run: func(ctx context.Context, db *sql.DB) error {
+				var id int
+				return db.QueryRowContext(ctx, "SELECT id FROM orders").Scan(&id)
+			},
+			expected: []string{"sql.query"},
+		},
+		{
+			name: "prepared statement",
+			run: func(ctx context.Context, db *sql.DB) error {
+				stmt, err := db.PrepareContext(ctx, "UPDATE orders SET paid = ?")
+				if err != nil {
+					return err
+				}
+				defer stmt.Close()
+				_, err = stmt.ExecContext(ctx, 1)
+				return err
+			},
+			expected: []string{"sql.prepare", "sql.exec"},
+		},
+		{
+			name: "committed transaction",
+			run: func(ctx context.Context, db *sql.DB) error {
+				tx, err := db.BeginTx(ctx, nil)
+				if err != nil {
+					return err
+				}
+				if _, err := tx.ExecContext(ctx, "UPDATE orders SET paid = 1"); err != nil {
+					return err
+				}
+				return tx.Commit()
+			},
+			expected: []string{"sql.begin", "sql.exec", "sql.commit"},
+		},
+		{
+			name: "rolled back transaction",
+			run: func(ctx context.Context, db *sql.DB) error {
+				tx, err := db.BeginTx(ctx, nil)
+				if err != nil {
+					return err
+				}
+				return tx.Rollback()
+			},
+			expected: []string{"sql.begin", "sql.rollback"},
+		},
+		{
+			name: "failed query",
+			run: func(ctx context.Context, db *sql.DB) error {
+				if _, err := db.ExecContext(ctx, "fail"); err == nil {
+					return errors.New("expected the query to fail")
+				}
+				return nil
+			},
+			expected: []string{"sql.exec!"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spans := tracetest.NewInMemoryExporter()
+			tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans))
+			db := OpenDB(fakeDriver{}, "orders", tracerProvider, semconv.DBSystemMySQL)
+			defer db.Close()
+
+			// Run the operations within a parent span, which the spans of the operations belong to
+			ctx, parent := tracerProvider.Tracer("test").Start(context.Background(), "parent")
+			if err := test.run(ctx, db); err != nil {
+				t.Fatalf("failed to run operations: %s", err.Error())
+			}
+			parent.End()
+
+			var names []string
+			for _, span := range spans.GetSpans() {
+				if span.Name == "parent" {
+					continue
+				}
+				name := span.Name
+				if span.Status.Code == codes.Error {
+					name += "!"
+				}
+				names = append(names, name)
+				if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+					t.Errorf("expected %s to be a child of the parent span", span.Name)
+				}
+				system, query := false, false
+				for _, attr := range span.Attributes {
+					system = system || attr == semconv.DBSystemMySQL
+					query = query || attr.Key == semconv.DBQueryTextKey
+				}
+				if !system {
+					t.Errorf("expected %s to record the database system, got %v", span.Name, span.Attributes)
+				}
+				if transaction := slices.Contains([]string{"sql.begin", "sql.commit", "sql.rollback"}, span.Name); query == transaction {
+					t.Errorf("expected %s to record the query: %t, got %v", span.Name, !transaction, span.Attributes)
+				}
+			}
+			if !reflect.DeepEqual(names, test.expected) {
+				t.Errorf("expected the spans %v, got %v", test.expected, names)
+			}
+		})
+	}
+}
+
+// fakeDriver opens connections to an in-memory database, whose queries fail if they're "fail".
+type fakeDriver struct{}
+
+// Open opens a connection.
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	return &fakeConn{}, nil
+}
+
+// fakeConn is a connection to the fake database. It only implements the context aware
+// interfaces for executing and querying, so statements and transactions use the others.
+type fakeConn struct{}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{query: query}, nil }
+func (c *fakeConn) Close() error                              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if query == "fail" {
+		return nil, errors.New("query failed")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if query == "fail" {
+		return nil, errors.New("query failed")
+	}
+	return &fakeRows{}, nil
+}
+
+// fakeStmt is a prepared statement of the fake database.
+type fakeStmt struct {
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) { return &fakeRows{}, nil }
+
+// fakeTx is a transaction of the fake database.
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+// fakeRows is the result of a query of the fake database, which is a single row with an id.
+type fakeRows struct {
+	read bool
+}
+
+func (r *fakeRows) Columns() []string { return []string{"id"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.read {
+		return io.EOF
+	}
+	r.read = true
+	dest[0] = int64(1)
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	cloudtrace "google.golang.org/api/cloudtrace/v2"
+)
+
+// Config holds configuration details for creating a TracerProvider.
+type Config struct {
+	ServiceName    string                // Name of the service, recorded on every span
+	ServiceVersion string                // Version of the service, recorded on every span
+	Exporter       sdktrace.SpanExporter // Exporter that spans are sent to once they end
+	SampleRatio    float64               // Fraction of new traces that are sampled, defaults to 1. Traces continued from a caller follow the caller's decision
+	Synchronous    bool                  // Export each span as soon as it ends rather than in batches, intended for tests
+}
+
+// CloudTraceContext is a propagator for the X-Cloud-Trace-Context header, which Google Cloud
+// load balancers, Cloud Run and Cloud Tasks use to propagate trace context.
+type CloudTraceContext struct{}
+
+// CloudTraceExporter is a span exporter that writes spans to Cloud Trace.
+type CloudTraceExporter struct {
+	client    *cloudtrace.Service
+	projectID string
+}
+
+// Transport is an http.RoundTripper that creates a client span for every request it sends,
+// and injects the span's context into the request's headers.
+type Transport struct {
+	base       http.RoundTripper
+	propagator propagation.TextMapPropagator
+	tracer     trace.Tracer
+}
+
+// Validate checks the Config struct for required fields and
+// returns an error if any required fields are missing
+func (c *Config) Validate() error {
+	if c.Exporter == nil {
+		return fmt.Errorf("Exporter is nil")
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return fmt.Errorf("SampleRatio must be between 0 and 1")
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName is the name of the tracers used to create spans in this module.
+const InstrumentationName = "github.com/albeebe/service"
+
+// NewTracerProvider creates a TracerProvider that samples and exports spans as described by
+// the config. The provider must be shut down to flush any spans that haven't been exported.
+func NewTracerProvider(config Config) (*sdktrace.TracerProvider, error) {
+
+	// Confirm the config is valid
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	// Describe the service the spans are created by
+	attributes := []attribute.KeyValue{}
+	if config.ServiceName != "" {
+		attributes = append(attributes, semconv.ServiceName(config.ServiceName))
+	}
+	if config.ServiceVersion != "" {
+		attributes = append(attributes, semconv.ServiceVersion(config.ServiceVersion))
+	}
+
+	// Sample new traces at the configured ratio, and follow the caller's decision otherwise
+	ratio := config.SampleRatio
+	if ratio == 0 {
+		ratio = 1
+	}
+
+	processor := sdktrace.WithBatcher(config.Exporter)
+	if config.Synchronous {
+		processor = sdktrace.WithSyncer(config.Exporter)
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithResource(resource.NewSchemaless(attributes...)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		processor,
+	), nil
+}
+
+// Propagator returns the propagator used to carry trace context across requests and messages.
+// It injects and extracts W3C traceparent, tracestate and baggage, and X-Cloud-Trace-Context.
+// When both traceparent and X-Cloud-Trace-Context are present, traceparent takes precedence.
+func Propagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(CloudTraceContext{}, propagation.TraceContext{}, propagation.Baggage{})
+}
+
+// NewOTLPExporter creates an exporter that sends spans to an OTLP collector over HTTP, such as
+// "https://collector.example.com:4318/v1/traces". The headers are sent with every export, and
+// can be used to authenticate with the collector.
+func NewOTLPExporter(ctx context.Context, endpointURL string, headers map[string]string) (sdktrace.SpanExporter, error) {
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpointURL), otlptracehttp.WithHeaders(headers))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+	return exporter, nil
+}
+
+// NewTransport creates a Transport that sends requests with the base round tripper, or
+// http.DefaultTransport if it's nil.
+func NewTransport(base http.RoundTripper, tracerProvider trace.TracerProvider, propagator propagation.TextMapPropagator) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{
+		base:       base,
+		propagator: propagator,
+		tracer:     tracerProvider.Tracer(InstrumentationName),
+	}
+}
+
+// RoundTrip sends the request within a client span, with the span's context injected into a
+// clone of the request's headers.
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx, span := t.tracer.Start(r.Context(), "HTTP "+r.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(r.Method),
+			semconv.URLFull(r.URL.Redacted()),
+			semconv.ServerAddress(r.URL.Hostname()),
+		),
+	)
+	defer span.End()
+
+	// RoundTrippers must not modify the request, so modify a clone of it
+	r = r.Clone(ctx)
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+	return resp, nil
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// panicError is a panic recovered from a goroutine other than the one handling the request,
+// such as the goroutine encoding a JSON response, so it can be handed to the request's goroutine.
+type panicError struct {
+	value any    // Value passed to panic
+	stack []byte // Stack of the goroutine that panicked
+}
+
+// newPanicError creates a panicError for the value, capturing the stack of the calling goroutine.
+// It must be called from the function deferred to recover from the panic.
+func newPanicError(value any) *panicError {
+	return &panicError{value: value, stack: debug.Stack()}
+}
+
+// Error describes the value the goroutine panicked with.
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+// RecoveredPanics returns the number of panics the service has recovered from while handling
+// requests, since it was created.
+func (s *Service) RecoveredPanics() uint64 {
+	return s.internal.panics.Load()
+}
+
+// recoveryMiddleware returns router middleware that recovers from panics in every handler the
+// router invokes, including endpoint and websocket handlers, and the goroutines encoding their
+// responses. The panic is logged in the format Error Reporting expects, and a 500 Internal Server
+// Error is sent, unless the response had already started.
+func (s *Service) recoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &responseRecorder{ResponseWriter: w}
+		defer func() {
+			value := recover()
+			if value == nil {
+				return
+			}
+			if value == http.ErrAbortHandler {
+				// The handler deliberately aborted the response, which net/http handles silently
+				panic(value)
+			}
+
+			var stack []byte
+			var p *panicError
+			if err, ok := value.(error); ok && errors.As(err, &p) {
+				value, stack = p.value, p.stack
+			} else {
+				stack = debug.Stack()
+			}
+			s.logPanic(r, value, stack)
+
+			if !recorder.Started() {
+				sendError(recorder, internalError())
+			}
+		}()
+		next.ServeHTTP(recorder, r)
+	})
+}
+
+// logPanic counts the panic, and logs it with its stack. The message is formatted the way the Go
+// runtime reports a panic, which is the format Error Reporting recognizes.
+func (s *Service) logPanic(r *http.Request, value any, stack []byte) {
+	s.internal.panics.Add(1)
+	s.internal.metrics.recoveredPanics.Inc()
+	s.Log.LogAttrs(correlationContext(r), slog.LevelError, fmt.Sprintf("panic: %v\n\n%s", value, stack),
+		slog.String("stack_trace", string(stack)),
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("request_id", RequestID(r)),
+	)
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/albeebe/service"
+	"github.com/albeebe/service/servicetest"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// panickingValue panics when it's encoded as JSON.
+type panickingValue struct{}
+
+// MarshalJSON panics.
+func (panickingValue) MarshalJSON() ([]byte, error) {
+	panic("failed to encode")
+}
+
+func TestPanicRecovery(t *testing.T) {
+	var output lockedBuffer
+	h := servicetest.New(t, servicetest.Config{Logger: slog.New(slog.NewJSONHandler(&output, nil))})
+	err := h.Service.AddPublicEndpoint("GET", "/handler", func(ctx context.Context, s *service.Service, r *http.Request) *service.HTTPResponse {
+		panic("boom")
+	})
+	if err != nil {
+		t.Fatalf("failed to add endpoint: %s", err.Error())
+	}
+	err = h.Service.AddPublicEndpoint("GET", "/encoding", func(ctx context.Context, s *service.Service, r *http.Request) *service.HTTPResponse {
+		return service.JSON(http.StatusOK, map[string]any{"value": panickingValue{}})
+	})
+	if err != nil {
+		t.Fatalf("failed to add endpoint: %s", err.Error())
+	}
+
+	tests := []struct {
+		target    string
+		value     string
+		requestID string
+	}{
+		{target: "/handler", value: "boom", requestID: "request-1"},
+		{target: "/encoding", value: "failed to encode", requestID: "request-2"},
+	}
+	for i, test := range tests {
+		t.Run(strings.TrimPrefix(test.target, "/"), func(t *testing.T) {
+			r := httptest.NewRequest("GET", test.target, nil)
+			r.Header.Set("X-Request-ID", test.requestID)
+
+			// The panic is answered with a 500, and the service carries on
+			resp := h.Do(r)
+			if problem := decodeProblem(t, resp); resp.StatusCode != http.StatusInternalServerError || problem.Code != service.CodeInternal {
+				t.Errorf("expected status %d and code %q, got %d and %+v", http.StatusInternalServerError, service.CodeInternal, resp.StatusCode, problem)
+			}
+			if recovered := h.Service.RecoveredPanics(); recovered != uint64(i+1) {
+				t.Errorf("expected %d panics to be recovered, got %d", i+1, recovered)
+			}
+
+			// The panic is logged with its stack, in the format Error Reporting expects
+			entry := panicEntry(t, output.String(), test.value)
+			if entry["level"] != "ERROR" || entry["request_id"] != test.requestID || entry["path"] != test.target {
+				t.Errorf("unexpected entry %v", entry)
+			}
+			if message, _ := entry["msg"].(string); !strings.HasPrefix(message, "panic: "+test.value+"\n\ngoroutine ") {
+				t.Errorf("expected the message to be formatted as a panic, got %q", message)
+			}
+			if stack, _ := entry["stack_trace"].(string); !strings.Contains(stack, "recovery_test.go") {
+				t.Errorf("expected the stack of the goroutine that panicked, got %q", stack)
+			}
+		})
+	}
+
+	// Recovered panics are counted in the metrics, and mark the request's span as failed
+	resp := h.Do(httptest.NewRequest("GET", service.MetricsPath, nil))
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "http_recovered_panics_total 2\n") {
+		t.Errorf("expected the metrics to count 2 recovered panics, got:\n%s", body)
+	}
+	for _, span := range h.Spans.GetSpans() {
+		if span.Name == "GET /handler" && span.Status.Code != codes.Error {
+			t.Errorf("expected the span to have failed, got %+v", span.Status)
+		}
+	}
+}
+
+// panicEntry returns the entry logged for the panic with the value, failing the test if there isn't one.
+func panicEntry(t *testing.T, output, value string) map[string]any {
+	t.Helper()
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("failed to parse entry %q: %s", line, err.Error())
+		}
+		if message, _ := entry["msg"].(string); strings.HasPrefix(message, "panic: "+value) {
+			return entry
+		}
+	}
+	t.Fatalf("expected the panic %q to be logged, got:\n%s", value, output)
+	return nil
+}
+
+// lockedBuffer is a bytes.Buffer that's safe to write to from several goroutines.
+type lockedBuffer struct {
+	buf bytes.Buffer
+	mux sync.Mutex
+}
+
+// Write appends the bytes to the buffer.
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.buf.Write(p)
+}
+
+// String returns the contents of the buffer.
+func (b *lockedBuffer) String() string {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.buf.String()
+}
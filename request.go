@@ -0,0 +1,148 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrNotAuthenticated is returned when claims are requested for a request that wasn't authenticated.
+var ErrNotAuthenticated = errors.New("request was not authenticated")
+
+// Identity describes the verified caller of an authenticated or service endpoint.
+type Identity struct {
+	Subject string                 // Subject the token was issued to (the "sub" claim)
+	Service bool                   // Whether the request was identified as coming from a service
+	Claims  map[string]interface{} // Every claim in the verified token
+}
+
+// requestState holds the state of a request as it passes through the service. It's attached to
+// the request's context before any middleware runs, so values set while handling the request,
+// such as the caller's identity, are visible to middleware once the handler returns.
+type requestState struct {
+	identity         *Identity
+	pubSub           pubSubPush // What's known about the request, if it's a Pub/Sub push request
+	requestID        string
+	route            *Route            // Route the request matched, or nil if it didn't match one
+	spanContext      trace.SpanContext // Span the request was handled in, once it's been routed
+	withoutAccessLog bool              // Whether the matched endpoint is excluded from the access log
+}
+
+// pubSubPush holds what's been learned about a Pub/Sub push request, so that the request is only
+// verified, and its body only read, once.
+type pubSubPush struct {
+	attributes map[string]string // Attributes of the message in the request's body
+	checked    bool              // Whether the request has been verified, with err holding the result
+	err        error             // Error verifying the request, if it failed verification
+	read       bool              // Whether the request's body has been read
+}
+
+// requestStateKey is the context key for the request's requestState.
+type requestStateKey struct{}
+
+// withRequestState returns the request with a requestState attached to its context, along with
+// the state. If the request already has one, it's returned unchanged.
+func withRequestState(r *http.Request) (*http.Request, *requestState) {
+	if state := requestStateFromContext(r.Context()); state != nil {
+		return r, state
+	}
+	state := &requestState{}
+	return r.WithContext(context.WithValue(r.Context(), requestStateKey{}, state)), state
+}
+
+// requestStateFromContext returns the requestState attached to the context, or nil if there isn't one.
+func requestStateFromContext(ctx context.Context) *requestState {
+	state, _ := ctx.Value(requestStateKey{}).(*requestState)
+	return state
+}
+
+// Principal returns the verified identity of the caller of an authenticated or service endpoint.
+// It returns false if the request wasn't authenticated, such as for a public endpoint.
+func Principal(r *http.Request) (Identity, bool) {
+	return PrincipalFromContext(r.Context())
+}
+
+// PrincipalFromContext returns the verified identity of the caller from the request's context,
+// as passed to typed endpoint handlers. It returns false if the request wasn't authenticated.
+func PrincipalFromContext(ctx context.Context) (Identity, bool) {
+	state := requestStateFromContext(ctx)
+	if state == nil || state.identity == nil {
+		return Identity{}, false
+	}
+	return *state.identity, true
+}
+
+// Claims decodes the verified claims of an authenticated request into T, which is typically a
+// struct with `json` tags matching the claims. Unlike ParseClaimsFromRequest, the claims are
+// those of the token that was verified when the request was authenticated. ErrNotAuthenticated
+// is returned if the request wasn't authenticated.
+//
+// Example:
+//
+//	type OrderClaims struct {
+//		Subject  string `json:"sub"`
+//		TenantID string `json:"tenant_id"`
+//	}
+//
+//	claims, err := service.Claims[OrderClaims](r)
+func Claims[T any](r *http.Request) (T, error) {
+	return ClaimsFromContext[T](r.Context())
+}
+
+// ClaimsFromContext decodes the verified claims of an authenticated request from the request's
+// context into T, as described by Claims.
+func ClaimsFromContext[T any](ctx context.Context) (T, error) {
+	var claims T
+	identity, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return claims, ErrNotAuthenticated
+	}
+	encoded, err := json.Marshal(identity.Claims)
+	if err != nil {
+		return claims, fmt.Errorf("failed to encode claims: %w", err)
+	}
+	if err := json.Unmarshal(encoded, &claims); err != nil {
+		return claims, fmt.Errorf("failed to decode claims: %w", err)
+	}
+	return claims, nil
+}
+
+// setPrincipal records the verified identity of the caller in the request's state.
+func setPrincipal(r *http.Request, claims map[string]interface{}, isService bool) {
+	state := requestStateFromContext(r.Context())
+	if state == nil {
+		return
+	}
+	subject, _ := claims["sub"].(string)
+	state.identity = &Identity{
+		Subject: subject,
+		Service: isService,
+		Claims:  claims,
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/albeebe/service"
+	"github.com/albeebe/service/servicetest"
+)
+
+// orderClaims matches the claims of the tokens minted by servicetest.
+type orderClaims struct {
+	Subject     string   `json:"sub"`
+	Permissions []string `json:"permissions"`
+}
+
+// mismatchedClaims has a subject whose type doesn't match the subject claim.
+type mismatchedClaims struct {
+	Subject int `json:"sub"`
+}
+
+func TestClaims(t *testing.T) {
+	tests := []struct {
+		name          string
+		path          string
+		authenticated bool
+		claims        func(r *http.Request) (any, error) // claims decodes the claims of the request
+		expected      any
+		err           string // err is part of the error expected decoding the claims
+		sentinel      error  // sentinel is the error expected to be wrapped by the error, if any
+	}{
+		{
+			name:          "matching type",
+			path:          "/orders",
+			authenticated: true,
+			claims:        func(r *http.Request) (any, error) { return service.Claims[orderClaims](r) },
+			expected:      orderClaims{Subject: "alice", Permissions: []string{"orders.read"}},
+		},
+		{
+			name:          "matching type from context",
+			path:          "/orders",
+			authenticated: true,
+			claims:        func(r *http.Request) (any, error) { return service.ClaimsFromContext[orderClaims](r.Context()) },
+			expected:      orderClaims{Subject: "alice", Permissions: []string{"orders.read"}},
+		},
+		{
+			name:          "mismatched type",
+			path:          "/orders",
+			authenticated: true,
+			claims:        func(r *http.Request) (any, error) { return service.Claims[mismatchedClaims](r) },
+			expected:      mismatchedClaims{},
+			err:           "failed to decode claims",
+		},
+		{
+			name:     "unauthenticated request",
+			path:     "/public",
+			claims:   func(r *http.Request) (any, error) { return service.Claims[orderClaims](r) },
+			expected: orderClaims{},
+			err:      service.ErrNotAuthenticated.Error(),
+			sentinel: service.ErrNotAuthenticated,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := servicetest.New(t, servicetest.Config{})
+			var claims any
+			var err error
+			handler := func(ctx context.Context, s *service.Service, r *http.Request) *service.HTTPResponse {
+				claims, err = test.claims(r)
+				return service.Text(http.StatusOK, "ok")
+			}
+			if err := h.Service.AddAuthenticatedEndpoint("GET", "/orders", "orders.read", handler); err != nil {
+				t.Fatalf("failed to add authenticated endpoint: %s", err.Error())
+			}
+			if err := h.Service.AddPublicEndpoint("GET", "/public", handler); err != nil {
+				t.Fatalf("failed to add public endpoint: %s", err.Error())
+			}
+
+			r := httptest.NewRequest("GET", test.path, nil)
+			if test.authenticated {
+				if err := h.Auth.Authorize(r, servicetest.Claims{Subject: "alice", Permissions: []string{"orders.read"}}); err != nil {
+					t.Fatalf("failed to authorize request: %s", err.Error())
+				}
+			}
+			if resp := h.Do(r); resp.StatusCode != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+			}
+
+			switch {
+			case test.err == "" && err != nil:
+				t.Errorf("expected no error, got %s", err.Error())
+			case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+				t.Errorf("expected an error containing %q, got %v", test.err, err)
+			}
+			if test.sentinel != nil && !errors.Is(err, test.sentinel) {
+				t.Errorf("expected %v, got %v", test.sentinel, err)
+			}
+			if !reflect.DeepEqual(claims, test.expected) {
+				t.Errorf("expected claims %+v, got %+v", test.expected, claims)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+const (
+	// RequestIDHeader is the header used to accept and propagate request IDs over HTTP,
+	// including to tasks created with CreateCloudTaskContext.
+	RequestIDHeader = "X-Request-ID"
+
+	// RequestIDAttribute is the attribute used to propagate request IDs to messages published
+	// with PublishToPubSubContext.
+	RequestIDAttribute = "request_id"
+
+	// maxRequestIDLength is the longest inbound request ID that's accepted. Longer IDs are
+	// replaced with a generated one.
+	maxRequestIDLength = 128
+)
+
+// RequestID returns the ID of the request. Every request handled by the service has one, either
+// accepted from the request's X-Request-ID header, or generated when the header is missing or
+// invalid. The ID is also sent back to the client in the response's X-Request-ID header.
+func RequestID(r *http.Request) string {
+	return RequestIDFromContext(r.Context())
+}
+
+// RequestIDFromContext returns the ID of the request from the request's context, as passed to
+// typed endpoint handlers. It returns an empty string if the context isn't from a request.
+func RequestIDFromContext(ctx context.Context) string {
+	if state := requestStateFromContext(ctx); state != nil {
+		return state.requestID
+	}
+	return ""
+}
+
+// requestIDMiddleware accepts the request ID from the inbound request, or generates one, and
+// attaches it to the request's context and the response's headers.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r, state := withRequestState(r)
+		state.requestID = r.Header.Get(RequestIDHeader)
+		if !validRequestID(state.requestID) {
+			state.requestID = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, state.requestID)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// newRequestID generates a random 128-bit request ID, encoded as hex.
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID reports whether the ID is safe to accept from a client, which requires it to
+// be no longer than maxRequestIDLength and to only contain visible ASCII characters.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// maxPubSubBodySize is the largest body read from a Pub/Sub push request to find the message's
+// attributes. Pub/Sub messages are limited to 10 MB, which grows by a third when the message is
+// base64 encoded in the push envelope.
+const maxPubSubBodySize = 16 << 20
+
+// extractPubSubRequestID replaces the request's ID, and the ID in the response's X-Request-ID
+// header, with the ID published as an attribute of the Pub/Sub message in the request's body, if
+// it has one. It must only be called once the request has been verified as coming from Google
+// Pub/Sub, and before the response's headers are written.
+func extractPubSubRequestID(w http.ResponseWriter, r *http.Request) {
+	state := requestStateFromContext(r.Context())
+	if state == nil {
+		return
+	}
+	if id := pubSubAttributes(w, r)[RequestIDAttribute]; validRequestID(id) {
+		state.requestID = id
+		w.Header().Set(RequestIDHeader, id)
+	}
+}
+
+// pubSubAttributes returns the attributes of the Pub/Sub message in the request's body, or nil if
+// the body isn't a Pub/Sub push envelope. The body is restored so that it can still be read by the
+// handler. Bodies larger than maxPubSubBodySize aren't read in full, and the handler receives the
+// same error when it reads them. The attributes are recorded in the request's state, so the body
+// is only read once.
+func pubSubAttributes(w http.ResponseWriter, r *http.Request) map[string]string {
+	state := requestStateFromContext(r.Context())
+	if state != nil && state.pubSub.read {
+		return state.pubSub.attributes
+	}
+	attributes := readPubSubAttributes(w, r)
+	if state != nil {
+		state.pubSub.attributes = attributes
+		state.pubSub.read = true
+	}
+	return attributes
+}
+
+// readPubSubAttributes reads the request's body, up to maxPubSubBodySize, and returns the
+// attributes of the Pub/Sub message it contains, as described by pubSubAttributes.
+func readPubSubAttributes(w http.ResponseWriter, r *http.Request) map[string]string {
+	if r.Body == nil {
+		return nil
+	}
+	limited := http.MaxBytesReader(w, r.Body, maxPubSubBodySize)
+	body, err := io.ReadAll(limited)
+	if err != nil {
+		// Give the handler what was read, followed by the error
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), limited), limited}
+		return nil
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	var envelope struct {
+		Message struct {
+			Attributes map[string]string `json:"attributes"`
+		} `json:"message"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil
+	}
+	return envelope.Message.Attributes
+}
+
+// requestIDTransport is an http.RoundTripper that propagates the ID of the request the outbound
+// request was made on behalf of, as found in the outbound request's context.
+type requestIDTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip adds the X-Request-ID header to the request, unless it already has one, and sends it
+// with the underlying transport.
+func (t *requestIDTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if id := RequestIDFromContext(r.Context()); id != "" && r.Header.Get(RequestIDHeader) == "" {
+		// RoundTrippers must not modify the request, so modify a clone of it
+		r = r.Clone(r.Context())
+		r.Header.Set(RequestIDHeader, id)
+	}
+	return t.base.RoundTrip(r)
+}
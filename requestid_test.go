@@ -0,0 +1,132 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/albeebe/service"
+	"github.com/albeebe/service/servicetest"
+)
+
+func TestRequestID(t *testing.T) {
+	h := servicetest.New(t, servicetest.Config{})
+	err := h.Service.AddPublicEndpoint("POST", "/orders", func(ctx context.Context, s *service.Service, r *http.Request) *service.HTTPResponse {
+		if _, err := s.PublishToPubSubContext(ctx, "orders", map[string]string{"id": "1"}); err != nil {
+			return service.Problem(err)
+		}
+		return service.Text(http.StatusOK, service.RequestID(r))
+	})
+	if err != nil {
+		t.Fatalf("failed to add endpoint: %s", err.Error())
+	}
+
+	tests := []struct {
+		name     string
+		header   string
+		expected string // Expected ID, or empty if a new ID should be generated
+	}{
+		{name: "accepted", header: "abc-123", expected: "abc-123"},
+		{name: "missing"},
+		{name: "invalid", header: "abc 123"},
+		{name: "too long", header: strings.Repeat("a", 129)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h.Publisher.Reset()
+			r := httptest.NewRequest("POST", "/orders", nil)
+			if test.header != "" {
+				r.Header.Set(service.RequestIDHeader, test.header)
+			}
+			resp := h.Do(r)
+			body, _ := io.ReadAll(resp.Body)
+			id := resp.Headers.Get(service.RequestIDHeader)
+			if string(body) != id {
+				t.Errorf("expected the handler to see the ID %q, got %q", id, body)
+			}
+			if test.expected != "" && id != test.expected {
+				t.Errorf("expected the ID %q, got %q", test.expected, id)
+			}
+			if test.expected == "" && (id == "" || id == test.header) {
+				t.Errorf("expected a generated ID, got %q", id)
+			}
+
+			// The ID is propagated to messages published on the request's behalf
+			messages := h.Publisher.Messages("orders")
+			if len(messages) != 1 {
+				t.Fatalf("expected 1 message, got %d", len(messages))
+			}
+			if attribute := messages[0].Attributes[service.RequestIDAttribute]; attribute != id {
+				t.Errorf("expected the message's %s attribute to be %q, got %q", service.RequestIDAttribute, id, attribute)
+			}
+		})
+	}
+}
+
+func TestPubSubRequestID(t *testing.T) {
+	h := servicetest.New(t, servicetest.Config{})
+	err := h.Service.AddPubSubEndpoint("/orders", func(ctx context.Context, s *service.Service, r *http.Request) *service.HTTPResponse {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return service.Text(http.StatusRequestEntityTooLarge, service.RequestID(r))
+			}
+			return service.Problem(err)
+		}
+		return service.Text(http.StatusOK, service.RequestID(r))
+	})
+	if err != nil {
+		t.Fatalf("failed to add endpoint: %s", err.Error())
+	}
+
+	// The ID of the request that published the message is continued, and sent back in place of
+	// the ID of the push request
+	r, err := servicetest.NewPubSubRequestWithAttributes("/orders", map[string]string{"id": "1"}, map[string]string{service.RequestIDAttribute: "abc-123"})
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err.Error())
+	}
+	r.Header.Set(service.RequestIDHeader, "push-456")
+	resp := h.Do(r)
+	if body, _ := io.ReadAll(resp.Body); resp.StatusCode != http.StatusOK || string(body) != "abc-123" {
+		t.Errorf("expected status %d with the ID %q, got %d with %q", http.StatusOK, "abc-123", resp.StatusCode, body)
+	}
+	if id := resp.Headers.Get(service.RequestIDHeader); id != "abc-123" {
+		t.Errorf("expected the %s header to be %q, got %q", service.RequestIDHeader, "abc-123", id)
+	}
+
+	// Bodies too large to be a Pub/Sub message aren't read in full, and the handler sees the error
+	body := `{"message":{"attributes":{"request_id":"abc-123"}},"padding":"` + strings.Repeat("a", 17<<20) + `"}`
+	resp = h.Do(httptest.NewRequest("POST", "/orders", strings.NewReader(body)))
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status %d, got %d", http.StatusRequestEntityTooLarge, resp.StatusCode)
+	}
+	if body, _ := io.ReadAll(resp.Body); string(body) == "abc-123" {
+		t.Error("expected the ID not to be read from a body that's too large")
+	}
+}
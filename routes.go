@@ -0,0 +1,215 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/logging"
+	"github.com/albeebe/service/pkg/logger"
+)
+
+// EndpointKind identifies how an endpoint was registered, and therefore how its requests
+// are authenticated before they reach the handler.
+type EndpointKind string
+
+const (
+	EndpointAuthenticated  EndpointKind = "authenticated"
+	EndpointCloudScheduler EndpointKind = "cloud-scheduler"
+	EndpointCloudTask      EndpointKind = "cloud-task"
+	EndpointCloudWorkflow  EndpointKind = "cloud-workflow"
+	EndpointHealth         EndpointKind = "health"
+	EndpointMetrics        EndpointKind = "metrics"
+	EndpointPubSub         EndpointKind = "pubsub"
+	EndpointPublic         EndpointKind = "public"
+	EndpointService        EndpointKind = "service"
+	EndpointWebsocket      EndpointKind = "websocket"
+)
+
+// Route describes an endpoint that has been registered with the service.
+type Route struct {
+	Method     string       // HTTP method (e.g. "GET")
+	Path       string       // Path relative to the root of the service (e.g. "/users/:id")
+	Kind       EndpointKind // How the endpoint was registered
+	Permission string       // Permission required by authenticated and service endpoints, if any
+	Request    reflect.Type // Request type decoded by typed endpoints, nil otherwise
+	Response   reflect.Type // Response type encoded by typed endpoints, nil otherwise
+}
+
+// String returns the route's method and path (e.g. "GET /users/:id").
+func (r Route) String() string {
+	return r.Method + " " + r.Path
+}
+
+// routeTable keeps track of every route registered with the service, along with any
+// routes that failed to register, so they can be reported together before the service runs.
+type routeTable struct {
+	errs   []error
+	mux    sync.Mutex
+	routes []Route
+}
+
+// Routes returns every route that was successfully registered with the service, in the
+// order they were registered. If any endpoints failed to register, because they were invalid,
+// duplicated, or conflicted with another route, the returned error describes all of them.
+//
+// Run calls Routes before starting the service, and terminates if it returns an error.
+func (s *Service) Routes() ([]Route, error) {
+	s.internal.routes.mux.Lock()
+	defer s.internal.routes.mux.Unlock()
+	routes := make([]Route, len(s.internal.routes.routes))
+	copy(routes, s.internal.routes.routes)
+	return routes, errors.Join(s.internal.routes.errs...)
+}
+
+// registerRoute checks the route against every route that's already registered, and registers
+// the handler with the router if it doesn't conflict with any of them. Any error is both
+// returned and recorded, so it's also reported by Routes.
+func (s *Service) registerRoute(route Route, handler http.HandlerFunc) error {
+	table := s.internal.routes
+	table.mux.Lock()
+	defer table.mux.Unlock()
+
+	err := func() error {
+		if err := route.validate(); err != nil {
+			return err
+		}
+		if (route.Kind == EndpointAuthenticated || route.Kind == EndpointService) && s.internal.auth == nil {
+			return fmt.Errorf("%s endpoint %s requires the service to be initialized with an AuthProvider", route.Kind, route)
+		}
+		for _, existing := range table.routes {
+			if existing.Method != route.Method {
+				continue
+			}
+			if reason := conflictBetweenPaths(existing.Path, route.Path); reason != "" {
+				return fmt.Errorf("%s endpoint %s conflicts with %s endpoint %s: %s", route.Kind, route, existing.Kind, existing, reason)
+			}
+		}
+		// Record the matched route on the request, so it can be reported by the access log, continue
+		// with the ID of the request that published a verified Pub/Sub message, attach the request
+		// to its context, so entries logged with it are shown with the request in Cloud Logging, and
+		// handle the request within a span
+		traced := s.traceHandler(route, handler)
+		routed := func(w http.ResponseWriter, r *http.Request) {
+			if state := requestStateFromContext(r.Context()); state != nil {
+				state.route = &route
+			}
+			if route.Kind == EndpointPubSub && s.verifyPubSubRequest(r) == nil {
+				extractPubSubRequestID(w, r)
+			}
+			ctx := logger.ContextWithHTTPRequest(r.Context(), &logging.HTTPRequest{Request: r, RemoteIP: s.remoteIP(r)})
+			traced(w, r.WithContext(ctx))
+		}
+		if err := s.internal.router.RegisterHandler(route.Method, route.Path, routed); err != nil {
+			return fmt.Errorf("failed to register %s endpoint %s: %w", route.Kind, route, err)
+		}
+		return nil
+	}()
+	if err != nil {
+		table.errs = append(table.errs, err)
+		return err
+	}
+	table.routes = append(table.routes, route)
+	return nil
+}
+
+// record records an error for an endpoint that couldn't be registered, so it's reported by Routes.
+func (t *routeTable) record(err error) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	t.errs = append(t.errs, err)
+}
+
+// newRoute creates a Route from the parameters passed to one of the Add*Endpoint functions,
+// trimming whitespace and normalizing the method to upper case.
+func newRoute(kind EndpointKind, method, relativePath, permission string) Route {
+	return Route{
+		Method:     strings.ToUpper(strings.TrimSpace(method)),
+		Path:       strings.TrimSpace(relativePath),
+		Kind:       kind,
+		Permission: strings.TrimSpace(permission),
+	}
+}
+
+// validate checks that the route has a supported method and a well formed path.
+func (r Route) validate() error {
+	switch r.Method {
+	case "DELETE", "GET", "HEAD", "PATCH", "POST", "PUT":
+	default:
+		return fmt.Errorf("%s endpoint %s has an invalid http method '%s'", r.Kind, r.Path, r.Method)
+	}
+	if !strings.HasPrefix(r.Path, "/") {
+		return fmt.Errorf("%s endpoint %s: path must begin with '/'", r.Kind, r)
+	}
+	segments := strings.Split(r.Path, "/")
+	for i, segment := range segments {
+		if segment == ":" || segment == "*" {
+			return fmt.Errorf("%s endpoint %s: wildcards must be named", r.Kind, r)
+		}
+		if strings.HasPrefix(segment, "*") && i != len(segments)-1 {
+			return fmt.Errorf("%s endpoint %s: catch-all wildcards are only allowed at the end of the path", r.Kind, r)
+		}
+	}
+	return nil
+}
+
+// conflictBetweenPaths compares two paths registered for the same method, and returns the
+// reason they can't both be registered, or an empty string if they can.
+//
+// Paths conflict when they're identical, when they differ only by the names of their
+// wildcards (e.g. "/users/:id" and "/users/:name"), or when a catch-all wildcard overlaps
+// another segment (e.g. "/files/*path" and "/files/:id"). A static segment and a named
+// wildcard in the same position don't conflict, as the static segment takes precedence.
+func conflictBetweenPaths(a, b string) string {
+	segmentsA := strings.Split(a, "/")
+	segmentsB := strings.Split(b, "/")
+	for i := 0; i < len(segmentsA) && i < len(segmentsB); i++ {
+		segmentA, segmentB := segmentsA[i], segmentsB[i]
+		catchAllA, catchAllB := strings.HasPrefix(segmentA, "*"), strings.HasPrefix(segmentB, "*")
+		paramA, paramB := strings.HasPrefix(segmentA, ":"), strings.HasPrefix(segmentB, ":")
+		switch {
+		case catchAllA && catchAllB && segmentA == segmentB:
+			return "duplicate route"
+		case catchAllA:
+			return fmt.Sprintf("catch-all wildcard '%s' conflicts with '%s'", segmentA, segmentB)
+		case catchAllB:
+			return fmt.Sprintf("catch-all wildcard '%s' conflicts with '%s'", segmentB, segmentA)
+		case paramA && paramB && segmentA != segmentB:
+			return fmt.Sprintf("wildcards '%s' and '%s' in the same position must have the same name", segmentA, segmentB)
+		case paramA != paramB:
+			// A static segment takes precedence over a wildcard, so the paths diverge here
+			return ""
+		case segmentA != segmentB:
+			return ""
+		}
+	}
+	if len(segmentsA) == len(segmentsB) {
+		return "duplicate route"
+	}
+	return ""
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/albeebe/service"
+	"github.com/albeebe/service/servicetest"
+)
+
+func TestRoutes(t *testing.T) {
+	ok := func(ctx context.Context, s *service.Service, r *http.Request) *service.HTTPResponse {
+		return service.Text(http.StatusOK, "ok")
+	}
+	tests := []struct {
+		name     string
+		register func(s *service.Service) error // register adds the endpoints, returning the error of the last
+		err      string                         // err is part of the error of the last endpoint, and of Routes
+		routes   []string                       // routes are the public and Pub/Sub routes expected to be registered
+	}{
+		{
+			name: "distinct routes",
+			register: func(s *service.Service) error {
+				s.AddPublicEndpoint("GET", "/orders/:id", ok)
+				s.AddPublicEndpoint("POST", "/orders/:id", ok)
+				return s.AddPublicEndpoint("GET", "/orders/recent", ok)
+			},
+			routes: []string{"GET /orders/:id", "POST /orders/:id", "GET /orders/recent"},
+		},
+		{
+			name: "duplicate",
+			register: func(s *service.Service) error {
+				s.AddPublicEndpoint("GET", "/orders", ok)
+				return s.AddPublicEndpoint("get", " /orders ", ok)
+			},
+			err:    "public endpoint GET /orders conflicts with public endpoint GET /orders: duplicate route",
+			routes: []string{"GET /orders"},
+		},
+		{
+			name: "across kinds",
+			register: func(s *service.Service) error {
+				s.AddPubSubEndpoint("/events", ok)
+				return s.AddAuthenticatedEndpoint("POST", "/events", "", ok)
+			},
+			err:    "authenticated endpoint POST /events conflicts with pubsub endpoint POST /events",
+			routes: []string{"POST /events"},
+		},
+		{
+			name: "wildcard names",
+			register: func(s *service.Service) error {
+				s.AddPublicEndpoint("GET", "/users/:id", ok)
+				return s.AddPublicEndpoint("GET", "/users/:name/orders", ok)
+			},
+			err:    "wildcards ':id' and ':name' in the same position must have the same name",
+			routes: []string{"GET /users/:id"},
+		},
+		{
+			name: "catch-all",
+			register: func(s *service.Service) error {
+				s.AddPublicEndpoint("GET", "/files/*path", ok)
+				return s.AddPublicEndpoint("GET", "/files/:id", ok)
+			},
+			err:    "catch-all wildcard '*path' conflicts with ':id'",
+			routes: []string{"GET /files/*path"},
+		},
+		{
+			name: "invalid method",
+			register: func(s *service.Service) error {
+				return s.AddPublicEndpoint("FETCH", "/orders", ok)
+			},
+			err: "invalid http method 'FETCH'",
+		},
+		{
+			name: "relative path",
+			register: func(s *service.Service) error {
+				return s.AddCloudTaskEndpoint("orders", ok)
+			},
+			err: "path must begin with '/'",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := servicetest.New(t, servicetest.Config{})
+			err := test.register(h.Service)
+			if test.err == "" && err != nil {
+				t.Fatalf("failed to add endpoint: %s", err.Error())
+			}
+			if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+				t.Fatalf("expected an error containing %q, got %v", test.err, err)
+			}
+
+			// Routes reports the error, and lists the routes that were registered
+			routes, err := h.Service.Routes()
+			if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+				t.Errorf("expected Routes to report %q, got %v", test.err, err)
+			}
+			var registered []string
+			for _, route := range routes {
+				if route.Kind == service.EndpointPublic || route.Kind == service.EndpointPubSub {
+					registered = append(registered, route.String())
+				}
+			}
+			if strings.Join(registered, ", ") != strings.Join(test.routes, ", ") {
+				t.Errorf("expected the routes %v, got %v", test.routes, registered)
+			}
+			for _, route := range test.routes {
+				method, path, _ := strings.Cut(route, " ")
+				if resp := h.Do(httptest.NewRequest(method, strings.Replace(path, ":id", "1", 1), nil)); resp.StatusCode == http.StatusNotFound {
+					t.Errorf("expected %s to be served", route)
+				}
+			}
+
+			// The service doesn't run with routes that failed to register
+			if test.err != "" {
+				var terminating error
+				h.Service.Run(service.State{Terminating: func(err error) { terminating = err }})
+				if terminating == nil || !strings.Contains(terminating.Error(), test.err) {
+					t.Errorf("expected the service to terminate with %q, got %v", test.err, terminating)
+				}
+			}
+		})
+	}
+}
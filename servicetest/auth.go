@@ -0,0 +1,169 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package servicetest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/albeebe/service"
+	"github.com/albeebe/service/pkg/auth"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingKey is generated once per process, as generating RSA keys is slow enough to
+// noticeably delay tests that create many harnesses.
+var signingKey struct {
+	once sync.Once
+	key  *rsa.PrivateKey
+	err  error
+}
+
+// NewAuthProvider creates a fake AuthProvider with a locally generated signing key.
+func NewAuthProvider() (*AuthProvider, error) {
+	signingKey.once.Do(func() {
+		signingKey.key, signingKey.err = rsa.GenerateKey(rand.Reader, 2048)
+	})
+	if signingKey.err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", signingKey.err)
+	}
+	return &AuthProvider{
+		key: signingKey.key,
+		kid: "servicetest",
+	}, nil
+}
+
+// Token mints a signed token carrying the claims. The token is valid for one hour.
+func (p *AuthProvider) Token(claims Claims) (string, error) {
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"sub":         claims.Subject,
+		"permissions": claims.Permissions,
+		"service":     claims.Service,
+		"iat":         now.Unix(),
+		"exp":         now.Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = p.kid
+	return token.SignedString(p.key)
+}
+
+// Authorize mints a token carrying the claims and attaches it to the request's
+// Authorization header as a Bearer token.
+func (p *AuthProvider) Authorize(r *http.Request, claims Claims) error {
+	token, err := p.Token(claims)
+	if err != nil {
+		return fmt.Errorf("failed to mint token: %w", err)
+	}
+	r.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// AuthorizeRequest reports whether the token attached to the request grants the permission.
+// An empty permission is always granted. Tokens that weren't signed by the provider, or have
+// expired, grant nothing.
+func (p *AuthProvider) AuthorizeRequest(r *http.Request, permission string) (bool, error) {
+	if permission == "" {
+		return true, nil
+	}
+	claims, err := p.verifiedClaims(r)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(claims.Permissions, permission), nil
+}
+
+// IsServiceRequest reports whether the token attached to the request was minted by the provider
+// for a service.
+func (p *AuthProvider) IsServiceRequest(r *http.Request) bool {
+	claims, err := p.verifiedClaims(r)
+	if err != nil {
+		return false
+	}
+	return claims.Service
+}
+
+// verifiedClaims returns the claims of the token attached to the request, once its signature has
+// been verified with the provider's key and it has been checked that it hasn't expired.
+func (p *AuthProvider) verifiedClaims(r *http.Request) (Claims, error) {
+	var claims Claims
+	token, ok := auth.ExtractBearerToken(r)
+	if !ok {
+		return claims, errors.New("failed to extract bearer token")
+	}
+	_, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		if kid, _ := token.Header["kid"].(string); kid != p.kid {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		return &p.key.PublicKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return claims, fmt.Errorf("failed to verify token: %w", err)
+	}
+	if err := service.ParseClaimsFromRequest(r, &claims); err != nil {
+		return claims, err
+	}
+	return claims, nil
+}
+
+// RefreshAccessToken mints a service token, which is attached to requests made with the
+// client returned by the service's AuthClient method.
+func (p *AuthProvider) RefreshAccessToken() (*auth.AccessToken, time.Time, error) {
+	token, err := p.Token(Claims{Subject: "servicetest", Service: true})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	now := time.Now()
+	return &auth.AccessToken{
+		Token:   token,
+		Expires: now.Add(time.Hour),
+	}, now.Add(30 * time.Minute), nil
+}
+
+// RefreshKeys returns the public half of the signing key.
+func (p *AuthProvider) RefreshKeys() ([]*auth.Key, time.Time, error) {
+	if p.key == nil {
+		return nil, time.Time{}, errors.New("signing key is missing")
+	}
+	der, err := x509.MarshalPKIXPublicKey(&p.key.PublicKey)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to marshal public key: %w", err)
+	}
+	now := time.Now()
+	return []*auth.Key{
+		{
+			Kid: p.kid,
+			Iat: now.Unix(),
+			Exp: now.Add(24 * time.Hour).Unix(),
+			Alg: "RS256",
+			Pem: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+		},
+	}, now.Add(time.Hour), nil
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package servicetest
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/albeebe/service/pkg/pubsub"
+)
+
+// Publish records the message against the topic and returns its generated ID. If an error
+// has been set with FailWith, the message is discarded and the error is returned instead.
+func (p *Publisher) Publish(topic string, message interface{}) (string, error) {
+	return p.PublishWithAttributes(topic, message, nil)
+}
+
+// PublishWithAttributes records the message and its attributes against the topic, as described by Publish.
+func (p *Publisher) PublishWithAttributes(topic string, message interface{}, attributes map[string]string) (string, error) {
+
+	// Serialize the message the same way the real publisher does
+	data, err := pubsub.SerializeMessage(message)
+	if err != nil {
+		return "", err
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.err != nil {
+		return "", p.err
+	}
+	id := strconv.Itoa(len(p.messages) + 1)
+	p.messages = append(p.messages, Message{
+		ID:         id,
+		Topic:      topic,
+		Data:       data,
+		Attributes: attributes,
+		Published:  time.Now(),
+	})
+	return id, nil
+}
+
+// Messages returns the messages published to the topic, in the order they were published.
+// If topic is empty, the messages published to every topic are returned.
+func (p *Publisher) Messages(topic string) []Message {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	messages := []Message{}
+	for _, message := range p.messages {
+		if topic == "" || message.Topic == topic {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// FailWith causes every subsequent call to Publish to return err. Passing nil restores
+// normal behavior.
+func (p *Publisher) FailWith(err error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.err = err
+}
+
+// Reset discards every recorded message and clears any error set with FailWith.
+func (p *Publisher) Reset() {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.messages = nil
+	p.err = nil
+}
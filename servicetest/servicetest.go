@@ -0,0 +1,164 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package servicetest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/albeebe/service"
+	"github.com/albeebe/service/pkg/pubsub"
+	"github.com/albeebe/service/pkg/tracing"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// messageCounter generates unique message IDs for Pub/Sub requests built by NewPubSubRequest.
+var messageCounter atomic.Int64
+
+// New creates a Harness wrapping a service that runs entirely in-process. The service is backed
+// by an in-memory router, a fake AuthProvider and a fake Pub/Sub publisher, so endpoint handlers
+// can be exercised without any network access. Spans are recorded in memory as soon as they end.
+// The harness is closed automatically when the test completes.
+func New(t testing.TB, config Config) *Harness {
+	t.Helper()
+
+	// Apply the defaults for anything that wasn't configured
+	if config.ServiceName == "" {
+		config.ServiceName = "test-service"
+	}
+	if config.Config.GCPProjectID == "" {
+		config.Config.GCPProjectID = "test-project"
+	}
+	if config.Config.Host == "" {
+		config.Config.Host = "localhost:0"
+	}
+	if config.Config.ServiceAccount == "" {
+		config.Config.ServiceAccount = "test-service@test-project.iam.gserviceaccount.com"
+	}
+
+	// Create the fakes
+	authProvider, err := NewAuthProvider()
+	if err != nil {
+		t.Fatalf("failed to create auth provider: %s", err.Error())
+	}
+	publisher := &Publisher{}
+	spans := tracetest.NewInMemoryExporter()
+
+	// Record spans in memory, unless the options configure tracing themselves
+	options := append([]service.Option{
+		service.WithTracing(tracing.Config{Exporter: spans, Synchronous: true}),
+	}, config.Options...)
+
+	// Create the service
+	s, err := service.NewTestService(config.ServiceName, config.Config, service.TestDependencies{
+		AuthProvider: authProvider,
+		Logger:       config.Logger,
+		Publisher:    publisher,
+		Options:      options,
+	})
+	if err != nil {
+		t.Fatalf("failed to create service: %s", err.Error())
+	}
+
+	h := &Harness{
+		Service:   s,
+		Auth:      authProvider,
+		Publisher: publisher,
+		Spans:     spans,
+	}
+	t.Cleanup(h.Close)
+	return h
+}
+
+// Do dispatches the request to the service in-process and returns the response that
+// the service sent. The response body is fully buffered, so it can be read after Do returns.
+func (h *Harness) Do(r *http.Request) *service.HTTPResponse {
+	recorder := httptest.NewRecorder()
+	h.Service.ServeHTTP(recorder, r)
+	result := recorder.Result()
+	return &service.HTTPResponse{
+		StatusCode: result.StatusCode,
+		Headers:    result.Header,
+		Body:       result.Body,
+	}
+}
+
+// Close shuts down the service, stopping the auth service and any other background work.
+func (h *Harness) Close() {
+	h.Service.Shutdown()
+}
+
+// NewPubSubRequest builds a POST request for relativePath carrying the message wrapped in
+// a Pub/Sub push envelope, exactly as Google Pub/Sub would deliver it to an endpoint
+// registered with AddPubSubEndpoint. The message is serialized the same way PublishToPubSub
+// serializes it.
+func NewPubSubRequest(relativePath string, message interface{}) (*http.Request, error) {
+	return NewPubSubRequestWithAttributes(relativePath, message, nil)
+}
+
+// NewPubSubRequestWithAttributes builds a Pub/Sub push request for relativePath, as described by
+// NewPubSubRequest, with the attributes attached to the message. Passing the Attributes of a
+// Message recorded by the Publisher delivers the message as it was published.
+func NewPubSubRequestWithAttributes(relativePath string, message interface{}, attributes map[string]string) (*http.Request, error) {
+
+	// Serialize the message
+	data, err := pubsub.SerializeMessage(message)
+	if err != nil {
+		return nil, err
+	}
+
+	// Wrap the message in an envelope
+	type Message struct {
+		Attributes  map[string]string `json:"attributes,omitempty"`
+		Data        string            `json:"data"`
+		MessageID   string            `json:"messageId"`
+		PublishTime time.Time         `json:"publishTime"`
+	}
+	type Envelope struct {
+		Message      Message `json:"message"`
+		Subscription string  `json:"subscription"`
+	}
+	body, err := json.Marshal(Envelope{
+		Message: Message{
+			Attributes:  attributes,
+			Data:        base64.StdEncoding.EncodeToString(data),
+			MessageID:   strconv.FormatInt(messageCounter.Add(1), 10),
+			PublishTime: time.Now().UTC(),
+		},
+		Subscription: "projects/test-project/subscriptions/test-subscription",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r := httptest.NewRequest(http.MethodPost, relativePath, bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	return r, nil
+}
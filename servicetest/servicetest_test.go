@@ -0,0 +1,196 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package servicetest_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/albeebe/service"
+	"github.com/albeebe/service/servicetest"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestHarnessAuth(t *testing.T) {
+	h := servicetest.New(t, servicetest.Config{})
+	ok := func(ctx context.Context, s *service.Service, r *http.Request) *service.HTTPResponse {
+		return service.Text(http.StatusOK, "ok")
+	}
+	h.Service.AddAuthenticatedEndpoint("GET", "/orders", "orders.read", ok)
+	h.Service.AddServiceEndpoint("POST", "/sync", "", ok)
+	forged := signToken(t, jwt.SigningMethodRS256, newKey(t), jwt.MapClaims{
+		"sub": "billing", "service": true, "permissions": []string{"orders.read"}, "exp": time.Now().Add(time.Hour).Unix(),
+	})
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		claims   *servicetest.Claims
+		token    string
+		expected int
+	}{
+		{name: "no token", method: "GET", path: "/orders", expected: http.StatusUnauthorized},
+		{name: "permission granted", method: "GET", path: "/orders", claims: &servicetest.Claims{Subject: "alice", Permissions: []string{"orders.read"}}, expected: http.StatusOK},
+		{name: "permission missing", method: "GET", path: "/orders", claims: &servicetest.Claims{Subject: "alice"}, expected: http.StatusForbidden},
+		{name: "forged token", method: "GET", path: "/orders", token: forged, expected: http.StatusUnauthorized},
+		{name: "service", method: "POST", path: "/sync", claims: &servicetest.Claims{Subject: "billing", Service: true}, expected: http.StatusOK},
+		{name: "user calling a service endpoint", method: "POST", path: "/sync", claims: &servicetest.Claims{Subject: "alice"}, expected: http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(test.method, test.path, nil)
+			if test.claims != nil {
+				if err := h.Auth.Authorize(r, *test.claims); err != nil {
+					t.Fatalf("failed to authorize request: %s", err.Error())
+				}
+			}
+			if test.token != "" {
+				r.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			if resp := h.Do(r); resp.StatusCode != test.expected {
+				t.Errorf("expected status %d, got %d", test.expected, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestAuthProviderRejectsUnverifiedTokens(t *testing.T) {
+	provider, err := servicetest.NewAuthProvider()
+	if err != nil {
+		t.Fatalf("failed to create auth provider: %s", err.Error())
+	}
+	claims := jwt.MapClaims{"sub": "billing", "service": true, "permissions": []string{"orders.read"}, "exp": time.Now().Add(time.Hour).Unix()}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "signed with another key", token: signToken(t, jwt.SigningMethodRS256, newKey(t), claims)},
+		{name: "unsigned", token: signToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, claims)},
+		{name: "signed with a shared secret", token: signToken(t, jwt.SigningMethodHS256, []byte("secret"), claims)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("Authorization", "Bearer "+test.token)
+			if provider.IsServiceRequest(r) {
+				t.Error("expected the token not to identify a service")
+			}
+			if authorized, err := provider.AuthorizeRequest(r, "orders.read"); authorized || err == nil {
+				t.Errorf("expected the token to be rejected, got %t and %v", authorized, err)
+			}
+		})
+	}
+}
+
+func TestHarnessPublisher(t *testing.T) {
+	h := servicetest.New(t, servicetest.Config{})
+	type OrderCreated struct {
+		ID string `json:"id"`
+	}
+	h.Service.AddPublicEndpoint("POST", "/orders", func(ctx context.Context, s *service.Service, r *http.Request) *service.HTTPResponse {
+		if _, err := s.PublishToPubSub("orders", OrderCreated{ID: "123"}); err != nil {
+			return service.Text(http.StatusServiceUnavailable, err.Error())
+		}
+		return service.Text(http.StatusCreated, "created")
+	})
+	var received OrderCreated
+	h.Service.AddPubSubEndpoint("/_pubsub/orders", func(ctx context.Context, s *service.Service, r *http.Request) *service.HTTPResponse {
+		data, _, _, err := service.ParsePubSubEnvelope(r)
+		if err == nil {
+			err = json.Unmarshal(data, &received)
+		}
+		if err != nil {
+			return service.Text(http.StatusBadRequest, err.Error())
+		}
+		return service.Text(http.StatusOK, "ok")
+	})
+
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+		messages int
+	}{
+		{name: "published", expected: http.StatusCreated, messages: 1},
+		{name: "failing", err: errors.New("unavailable"), expected: http.StatusServiceUnavailable, messages: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h.Publisher.Reset()
+			h.Publisher.FailWith(test.err)
+			if resp := h.Do(httptest.NewRequest("POST", "/orders", nil)); resp.StatusCode != test.expected {
+				t.Fatalf("expected status %d, got %d", test.expected, resp.StatusCode)
+			}
+			if messages := h.Publisher.Messages("orders"); len(messages) != test.messages {
+				t.Fatalf("expected %d messages, got %d", test.messages, len(messages))
+			}
+		})
+	}
+
+	// A recorded message can be delivered to a Pub/Sub endpoint as Pub/Sub would deliver it
+	h.Publisher.FailWith(nil)
+	h.Do(httptest.NewRequest("POST", "/orders", nil))
+	var message OrderCreated
+	if err := json.Unmarshal(h.Publisher.Messages("orders")[0].Data, &message); err != nil {
+		t.Fatalf("failed to decode message: %s", err.Error())
+	}
+	r, err := servicetest.NewPubSubRequest("/_pubsub/orders", message)
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err.Error())
+	}
+	if resp := h.Do(r); resp.StatusCode != http.StatusOK || received.ID != "123" {
+		t.Errorf("expected the message to be delivered, got status %d and %+v", resp.StatusCode, received)
+	}
+}
+
+// newKey generates an RSA key other than the one the fake AuthProvider signs tokens with.
+func newKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err.Error())
+	}
+	return key
+}
+
+// signToken signs a token carrying the claims with the method and key, using the identifier of
+// the fake AuthProvider's key.
+func signToken(t *testing.T, method jwt.SigningMethod, key any, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = "servicetest"
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign token: %s", err.Error())
+	}
+	return signed
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package servicetest
+
+import (
+	"crypto/rsa"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/albeebe/service"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// Config holds the options used to create a Harness.
+type Config struct {
+	ServiceName string           // Name of the service, defaults to "test-service"
+	Config      service.Config   // Service configuration, required fields are given placeholder values when empty
+	Logger      *slog.Logger     // Logger used by the service, output is discarded when nil
+	Options     []service.Option // Additional options applied when creating the service (e.g., service.WithDB)
+}
+
+// Harness wraps a service that runs entirely in-process along with the fakes backing it.
+type Harness struct {
+	Service   *service.Service            // The service under test
+	Auth      *AuthProvider               // Fake AuthProvider used to mint tokens for authenticated requests
+	Publisher *Publisher                  // Fake publisher recording every message sent with PublishToPubSub
+	Spans     *tracetest.InMemoryExporter // Exporter recording every span the service ends
+}
+
+// Claims describes the identity encoded into the tokens minted by the fake AuthProvider.
+type Claims struct {
+	Subject     string   `json:"sub,omitempty"`         // Subject the token was issued to
+	Permissions []string `json:"permissions,omitempty"` // Permissions granted to the subject
+	Service     bool     `json:"service,omitempty"`     // Whether the subject is a service
+}
+
+// AuthProvider is an in-memory implementation of auth.AuthProvider. It signs tokens with a
+// locally generated RSA key and authorizes requests using the claims of the tokens it signed.
+type AuthProvider struct {
+	key *rsa.PrivateKey // Key used to sign tokens
+	kid string          // Identifier of the signing key
+}
+
+// Message is a message recorded by the fake Publisher.
+type Message struct {
+	ID         string            // Unique identifier assigned to the message
+	Topic      string            // Topic the message was published to
+	Data       []byte            // Serialized message payload
+	Attributes map[string]string // Attributes published with the message, if any
+	Published  time.Time         // Time the message was published
+}
+
+// Publisher is an in-memory implementation of pubsub.Publisher that records every message
+// published to it instead of sending it to Google Pub/Sub.
+type Publisher struct {
+	err      error      // Error returned by Publish, when set
+	messages []Message  // Messages published so far
+	mux      sync.Mutex // Protects err and messages
+}
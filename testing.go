@@ -0,0 +1,93 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// NewTestService creates a service that is backed entirely by in-process components. It does not
+// load Google credentials, connect to Cloud SQL, Cloud Storage, Cloud Tasks, IAM or Pub/Sub, and
+// it never binds a port, which makes it suitable for unit testing endpoint handlers.
+// Requests are dispatched to the registered endpoints by calling ServeHTTP directly.
+//
+// It is primarily intended to be used through the servicetest package.
+func NewTestService(serviceName string, config Config, deps TestDependencies) (*Service, error) {
+
+	// Never connect to Cloud SQL, a database can be provided with WithDB instead
+	config.CloudSQLConnection = ""
+	config.CloudSQLDatabase = ""
+	config.CloudSQLUser = ""
+
+	// Replace every Google Cloud client with the provided dependencies
+	logger := deps.Logger
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	opts := []Option{WithoutGoogleCloud(), WithLogger(logger)}
+	if deps.Publisher != nil {
+		opts = append(opts, WithPublisher(deps.Publisher))
+	}
+	opts = append(opts, deps.Options...)
+
+	// Create the service
+	s, err := New(serviceName, config, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start the auth service and wait for the keys to load, so the first request
+	// isn't delayed waiting for a key refresh
+	if deps.AuthProvider != nil {
+		if err := s.SetAuthProvider(deps.AuthProvider); err != nil {
+			s.Shutdown()
+			return nil, fmt.Errorf("failed to set auth provider: %w", err)
+		}
+		go s.startAuthService()
+		if err := s.awaitAuthKeys(5 * time.Second); err != nil {
+			s.Shutdown()
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// awaitAuthKeys blocks until the auth service has loaded its keys, or the timeout elapses.
+func (s *Service) awaitAuthKeys(timeout time.Duration) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	deadline := time.After(timeout)
+	for !s.internal.auth.KeysLoaded() {
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			return errors.New("timed out waiting for the auth keys to load")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/albeebe/service/pkg/tracing"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// setupTracing creates the tracer the service uses to create spans. Without WithTracing, spans
+// aren't recorded, but the trace context of inbound requests is still propagated to outbound
+// requests, messages and tasks.
+func (s *Service) setupTracing() error {
+	s.internal.propagator = tracing.Propagator()
+	config := s.internal.options.tracing
+	if config == nil {
+		s.Tracer = noop.NewTracerProvider().Tracer(tracing.InstrumentationName)
+		return nil
+	}
+	if config.ServiceName == "" {
+		config.ServiceName = s.Name
+	}
+	provider, err := tracing.NewTracerProvider(*config)
+	if err != nil {
+		return err
+	}
+	s.internal.tracerProvider = provider
+	s.Tracer = provider.Tracer(tracing.InstrumentationName)
+	return nil
+}
+
+// tracerProvider returns the provider of the service's tracer, which doesn't record any spans
+// unless the service was created with WithTracing.
+func (s *Service) tracerProvider() trace.TracerProvider {
+	if s.internal.tracerProvider == nil {
+		return noop.NewTracerProvider()
+	}
+	return s.internal.tracerProvider
+}
+
+// traceHandler returns a handler that handles each request to the route within a server span,
+// continuing the trace propagated by the caller. Requests to Pub/Sub endpoints continue the trace
+// propagated in the message's attributes instead, once they've been verified as coming from
+// Google Pub/Sub. Health and metrics endpoints aren't traced.
+func (s *Service) traceHandler(route Route, handler http.HandlerFunc) http.HandlerFunc {
+	if route.Kind == EndpointHealth || route.Kind == EndpointMetrics {
+		return handler
+	}
+	name := route.Method + " " + route.Path
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := s.internal.propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		if route.Kind == EndpointPubSub && s.verifyPubSubRequest(r) == nil {
+			ctx = s.internal.propagator.Extract(ctx, propagation.MapCarrier(pubSubAttributes(w, r)))
+		}
+		ctx, span := s.Tracer.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(route.Method),
+				semconv.HTTPRoute(route.Path),
+				semconv.URLPath(r.URL.Path),
+				semconv.UserAgentOriginal(r.UserAgent()),
+				semconv.ClientAddress(s.remoteIP(r)),
+			),
+		)
+		if state := requestStateFromContext(ctx); state != nil {
+			state.spanContext = span.SpanContext()
+		}
+		recorder := &responseRecorder{ResponseWriter: w}
+		defer func() {
+			if value := recover(); value != nil {
+				span.SetStatus(codes.Error, fmt.Sprintf("panic: %v", value))
+				span.End()
+				panic(value)
+			}
+			status := recorder.Status()
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+			span.End()
+		}()
+		handler(recorder, r.WithContext(ctx))
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	cloudtasks "cloud.google.com/go/cloudtasks/apiv2"
+	taskspb "cloud.google.com/go/cloudtasks/apiv2/cloudtaskspb"
+	"github.com/albeebe/service"
+	"github.com/albeebe/service/pkg/tracing"
+	"github.com/albeebe/service/servicetest"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestPubSubTraceContinuation(t *testing.T) {
+	h := servicetest.New(t, servicetest.Config{})
+	handler := func(ctx context.Context, s *service.Service, r *http.Request) *service.HTTPResponse {
+		if _, err := s.PublishToPubSubContext(ctx, "orders", map[string]string{"id": "1"}); err != nil {
+			return service.Problem(err)
+		}
+		return service.Text(http.StatusOK, "ok")
+	}
+	if err := h.Service.AddPublicEndpoint("POST", "/orders", handler); err != nil {
+		t.Fatalf("failed to add endpoint: %s", err.Error())
+	}
+	if err := h.Service.AddPubSubEndpoint("/events", handler); err != nil {
+		t.Fatalf("failed to add endpoint: %s", err.Error())
+	}
+
+	// Publish a message from a traced request, and deliver it to the Pub/Sub endpoint
+	if resp := h.Do(httptest.NewRequest("POST", "/orders", nil)); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	messages := h.Publisher.Messages("orders")
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(messages))
+	}
+	r, err := servicetest.NewPubSubRequestWithAttributes("/events", map[string]string{"id": "1"}, messages[0].Attributes)
+	if err != nil {
+		t.Fatalf("failed to create request: %s", err.Error())
+	}
+	if resp := h.Do(r); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	// The span handling the message continues the trace of the request that published it
+	var publisher, subscriber tracetest.SpanStub
+	for _, span := range h.Spans.GetSpans() {
+		switch span.Name {
+		case "POST /orders":
+			publisher = span
+		case "POST /events":
+			subscriber = span
+		}
+	}
+	if !publisher.SpanContext.IsValid() || !subscriber.SpanContext.IsValid() {
+		t.Fatal("expected spans for both requests")
+	}
+	if subscriber.Parent.TraceID() != publisher.SpanContext.TraceID() {
+		t.Errorf("expected the message to be handled in the trace %s, got %s", publisher.SpanContext.TraceID(), subscriber.Parent.TraceID())
+	}
+}
+
+func TestOutboundTraceContext(t *testing.T) {
+
+	// Receive the outbound requests and tasks, recording the headers they're sent with
+	var headers fakeHeaders
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers.record(r.Header)
+	}))
+	defer srv.Close()
+	tasks := newFakeCloudTasks(t, &headers)
+
+	h := servicetest.New(t, servicetest.Config{Options: []service.Option{service.WithCloudTasksClient(tasks)}})
+	tests := []struct {
+		name string
+		path string // path is the path of the endpoint that sends the request or task
+		send func(ctx context.Context, s *service.Service) error
+		span string // span is the name of the span the request or task is sent within
+	}{
+		{
+			name: "auth client",
+			path: "/stock",
+			send: func(ctx context.Context, s *service.Service) error {
+				client, err := s.AuthClient()
+				if err != nil {
+					return err
+				}
+				r, err := http.NewRequestWithContext(ctx, "GET", srv.URL+"/inventory", nil)
+				if err != nil {
+					return err
+				}
+				resp, err := client.Do(r)
+				if err != nil {
+					return err
+				}
+				return resp.Body.Close()
+			},
+			span: "HTTP GET",
+		},
+		{
+			name: "cloud task",
+			path: "/orders",
+			send: func(ctx context.Context, s *service.Service) error {
+				return s.CreateCloudTaskContext(ctx, "projects/test-project/locations/us-central1/queues/orders", "", srv.URL+"/fulfil", nil, 0, time.Minute)
+			},
+			span: "cloudtasks create",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h.Spans.Reset()
+			headers.reset()
+			err := h.Service.AddPublicEndpoint("POST", test.path, func(ctx context.Context, s *service.Service, r *http.Request) *service.HTTPResponse {
+				if err := test.send(ctx, s); err != nil {
+					return service.Problem(err)
+				}
+				return service.Text(http.StatusOK, "ok")
+			})
+			if err != nil {
+				t.Fatalf("failed to add endpoint: %s", err.Error())
+			}
+			r := httptest.NewRequest("POST", test.path, nil)
+			r.Header.Set(service.RequestIDHeader, "request-1")
+			if resp := h.Do(r); resp.StatusCode != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+			}
+
+			// The request or task carries the ID of the inbound request, and the trace context of the
+			// span it was sent within, in both the W3C and Cloud Trace formats
+			var sent tracetest.SpanStub
+			for _, span := range h.Spans.GetSpans() {
+				if span.Name == test.span {
+					sent = span
+				}
+			}
+			if !sent.SpanContext.IsValid() {
+				t.Fatalf("expected a %q span, got %d spans", test.span, len(h.Spans.GetSpans()))
+			}
+			received := headers.get()
+			if received == nil {
+				t.Fatal("expected the request or task to be received")
+			}
+			if id := received.Get(service.RequestIDHeader); id != "request-1" {
+				t.Errorf("expected the request ID %q, got %q", "request-1", id)
+			}
+			for name, propagator := range map[string]propagation.TextMapPropagator{"traceparent": propagation.TraceContext{}, tracing.CloudTraceContextHeader: tracing.CloudTraceContext{}} {
+				sc := trace.SpanContextFromContext(propagator.Extract(context.Background(), propagation.HeaderCarrier(received)))
+				if sc.TraceID() != sent.SpanContext.TraceID() || sc.SpanID() != sent.SpanContext.SpanID() || !sc.IsSampled() {
+					t.Errorf("expected the %s header to carry the span %s, got %q", name, sent.SpanContext.SpanID(), received.Get(name))
+				}
+			}
+		})
+	}
+}
+
+// fakeHeaders records the headers of the last outbound request or task that was received.
+type fakeHeaders struct {
+	header http.Header
+	mux    sync.Mutex
+}
+
+// record records the headers of a request or task.
+func (f *fakeHeaders) record(header http.Header) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.header = header.Clone()
+}
+
+// get returns the headers of the last request or task, or nil if none has been received.
+func (f *fakeHeaders) get() http.Header {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.header
+}
+
+// reset forgets the headers that have been received.
+func (f *fakeHeaders) reset() {
+	f.record(nil)
+}
+
+// fakeCloudTasks is a Cloud Tasks server that records the headers of the tasks created with it,
+// as they'd be sent to the task's URL.
+type fakeCloudTasks struct {
+	taskspb.UnimplementedCloudTasksServer
+	headers *fakeHeaders
+}
+
+// CreateTask records the headers of the task.
+func (f *fakeCloudTasks) CreateTask(ctx context.Context, request *taskspb.CreateTaskRequest) (*taskspb.Task, error) {
+	header := http.Header{}
+	for name, value := range request.GetTask().GetHttpRequest().GetHeaders() {
+		header.Set(name, value)
+	}
+	f.headers.record(header)
+	return request.GetTask(), nil
+}
+
+// newFakeCloudTasks starts a fake Cloud Tasks server recording the headers of tasks, and returns
+// a client connected to it. The server is stopped when the test completes.
+func newFakeCloudTasks(t *testing.T, headers *fakeHeaders) *cloudtasks.Client {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err.Error())
+	}
+	server := grpc.NewServer()
+	taskspb.RegisterCloudTasksServer(server, &fakeCloudTasks{headers: headers})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	client, err := cloudtasks.NewClient(context.Background(),
+		option.WithEndpoint(listener.Addr().String()),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+	)
+	if err != nil {
+		t.Fatalf("failed to create Cloud Tasks client: %s", err.Error())
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
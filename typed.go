@@ -0,0 +1,257 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+)
+
+// TypedHandler handles a request that has been decoded into Req and validated, and returns the
+// response to encode as JSON. The context is the request's context. Returning an HTTPError
+// sends it to the client, and returning any other error results in a 500 Internal Server Error.
+type TypedHandler[Req, Resp any] func(ctx context.Context, s *Service, req Req) (Resp, error)
+
+// EndpointRegistrar registers endpoints. It's implemented by both Service and Group, so typed
+// endpoints can be added to either.
+type EndpointRegistrar interface {
+	AddAuthenticatedEndpoint(method, relativePath, permission string, handler EndpointHandler, opts ...EndpointOption) error
+	AddPublicEndpoint(method, relativePath string, handler EndpointHandler, opts ...EndpointOption) error
+}
+
+// AddTypedEndpoint registers an authenticated endpoint whose requests are decoded into Req and
+// whose responses are encoded from Resp, removing the need for each handler to decode, validate
+// and encode them itself. Requests are authenticated and authorized exactly as they are for
+// AddAuthenticatedEndpoint.
+//
+// Req is populated from the JSON request body, if there is one, followed by the query parameters
+// of fields tagged with `query:"name"`, and the path parameters of fields tagged with `path:"name"`.
+// It's then validated using its `validate` tags. Requests that fail to decode or validate receive
+// a 400 Bad Request listing the fields that failed. The Resp returned by the handler is sent as JSON
+// with a 200 OK.
+//
+// Example:
+//
+//	type GetOrderRequest struct {
+//		ID     string `path:"id" json:"-" validate:"required"`
+//		Expand bool   `query:"expand" json:"-"`
+//	}
+//
+//	service.AddTypedEndpoint(s, "GET", "/orders/:id", "orders.read", func(ctx context.Context, s *service.Service, req GetOrderRequest) (Order, error) {
+//		return getOrder(ctx, s.DB, req.ID, req.Expand)
+//	})
+func AddTypedEndpoint[Req, Resp any](r EndpointRegistrar, method, relativePath, permission string, handler TypedHandler[Req, Resp], opts ...EndpointOption) error {
+	return r.AddAuthenticatedEndpoint(method, relativePath, permission, typedEndpointHandler(handler), typedEndpointOptions[Req, Resp](opts)...)
+}
+
+// AddTypedPublicEndpoint registers a public endpoint whose requests are decoded into Req and
+// whose responses are encoded from Resp, as described by AddTypedEndpoint. The endpoint does not
+// require authentication, unless it's added to a group that does.
+func AddTypedPublicEndpoint[Req, Resp any](r EndpointRegistrar, method, relativePath string, handler TypedHandler[Req, Resp], opts ...EndpointOption) error {
+	return r.AddPublicEndpoint(method, relativePath, typedEndpointHandler(handler), typedEndpointOptions[Req, Resp](opts)...)
+}
+
+// typedEndpointOptions returns the endpoint's options, along with an option that records the
+// request and response types on the endpoint's route.
+func typedEndpointOptions[Req, Resp any](opts []EndpointOption) []EndpointOption {
+	return append([]EndpointOption{func(o *endpointOptions) {
+		o.requestType = reflect.TypeFor[Req]()
+		o.responseType = reflect.TypeFor[Resp]()
+	}}, opts...)
+}
+
+// typedEndpointHandler adapts a TypedHandler to an EndpointHandler, decoding and validating
+// the request before invoking the handler, and encoding the response it returns.
+func typedEndpointHandler[Req, Resp any](handler TypedHandler[Req, Resp]) EndpointHandler {
+	return func(ctx context.Context, s *Service, r *http.Request) *HTTPResponse {
+
+		// Decode the request
+		var req Req
+		fieldErrs, err := decodeTypedRequest(r, &req)
+		if err != nil {
+			return invalidRequest(err.Error(), nil)
+		}
+		if len(fieldErrs) > 0 {
+			return invalidRequest("invalid request", fieldErrs)
+		}
+
+		// Validate the request
+		if value := reflect.Indirect(reflect.ValueOf(req)); value.Kind() == reflect.Struct {
+			for _, err := range ValidateStruct(req) {
+				var fieldErr *FieldError
+				if !errors.As(err, &fieldErr) {
+					s.Log.Error("failed to validate request", slog.String("error", err.Error()))
+					return internalError().Response()
+				}
+				fieldErrs = append(fieldErrs, fieldErr)
+			}
+			if len(fieldErrs) > 0 {
+				return invalidRequest("invalid request", fieldErrs)
+			}
+		}
+
+		// Send the request to the handler and encode the response
+		resp, err := handler(ctx, s, req)
+		if err != nil {
+			var httpErr *HTTPError
+			if !errors.As(err, &httpErr) {
+				s.Log.Error("typed endpoint handler failed", slog.String("error", err.Error()), slog.String("method", r.Method), slog.String("path", r.URL.Path))
+			}
+			return Problem(err)
+		}
+		return JSON(http.StatusOK, resp)
+	}
+}
+
+// invalidRequest returns a 400 Bad Request describing why the request is invalid, with the
+// fields that failed to decode or validate, if any, as its details.
+func invalidRequest(message string, fieldErrs []*FieldError) *HTTPResponse {
+	err := NewHTTPError(http.StatusBadRequest, CodeInvalidRequest, message)
+	if len(fieldErrs) > 0 {
+		err.Details = fieldErrs
+	}
+	return err.Response()
+}
+
+// decodeTypedRequest decodes the JSON request body, if there is one, into the target, followed by
+// its query and path parameters. An error is returned if the body can't be decoded, and the
+// parameters that can't be decoded are returned as field errors.
+func decodeTypedRequest(r *http.Request, target any) ([]*FieldError, error) {
+	value := reflect.ValueOf(target).Elem()
+	if value.Kind() == reflect.Pointer && value.IsNil() {
+		value.Set(reflect.New(value.Type().Elem()))
+	}
+
+	// Decode the body, allowing it to be empty since the request may only have parameters
+	if r.Body != nil && r.Body != http.NoBody {
+		if err := UnmarshalJSONBody(r, target); err != nil && !errors.Is(err, errBodyEmpty) {
+			return nil, fmt.Errorf("invalid request body: %w", err)
+		}
+	}
+
+	// Decode the query and path parameters into the fields tagged with them
+	if value = reflect.Indirect(value); value.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	return decodeRequestParams(r, r.URL.Query(), value), nil
+}
+
+// decodeRequestParams sets the struct's fields that are tagged with `query` or `path` to the
+// values of the matching parameters, descending into embedded structs. Path parameters take
+// precedence over query parameters.
+func decodeRequestParams(r *http.Request, query url.Values, value reflect.Value) []*FieldError {
+	var fieldErrs []*FieldError
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			fieldErrs = append(fieldErrs, decodeRequestParams(r, query, value.Field(i))...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name := field.Tag.Get("query"); name != "" && name != "-" {
+			if values, ok := query[name]; ok {
+				if err := setFieldValue(value.Field(i), values); err != nil {
+					fieldErrs = append(fieldErrs, &FieldError{Field: name, Tag: "type", Message: fmt.Sprintf("%s must be %s", name, err.Error())})
+				}
+			}
+		}
+		if name := field.Tag.Get("path"); name != "" && name != "-" {
+			if pathValue := r.PathValue(name); pathValue != "" {
+				if err := setFieldValue(value.Field(i), []string{pathValue}); err != nil {
+					fieldErrs = append(fieldErrs, &FieldError{Field: name, Tag: "type", Message: fmt.Sprintf("%s must be %s", name, err.Error())})
+				}
+			}
+		}
+	}
+	return fieldErrs
+}
+
+// setFieldValue parses the values into the field, which can be a string, bool, integer, float,
+// encoding.TextUnmarshaler (such as time.Time), or a pointer or slice of any of them. Fields that
+// aren't slices are set to the first value. The returned error describes the value that was expected.
+func setFieldValue(field reflect.Value, values []string) error {
+	if field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		return setFieldValue(field.Elem(), values)
+	}
+	if unmarshaler, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := unmarshaler.UnmarshalText([]byte(values[0])); err != nil {
+			return fmt.Errorf("a valid %s", field.Type())
+		}
+		return nil
+	}
+	if field.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setFieldValue(slice.Index(i), []string{value}); err != nil {
+				return fmt.Errorf("a list of %s", err.Error())
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+
+	value := values[0]
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("true or false")
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return errors.New("an integer")
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return errors.New("a non-negative integer")
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return errors.New("a number")
+		}
+		field.SetFloat(n)
+	default:
+		return fmt.Errorf("a supported type, not %s", field.Type())
+	}
+	return nil
+}
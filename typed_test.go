@@ -0,0 +1,150 @@
+// Copyright (c) 2024 Alan Beebe [www.alanbeebe.com]
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+//
+// Created: October 16, 2026
+
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/albeebe/service"
+	"github.com/albeebe/service/servicetest"
+)
+
+// updateOrderRequest is decoded from the path, query and body of the typed endpoint under test.
+type updateOrderRequest struct {
+	ID     int      `path:"id" json:"-" validate:"required"`
+	Notify bool     `query:"notify" json:"-"`
+	Tags   []string `query:"tag" json:"-"`
+	Note   string   `json:"note" validate:"required"`
+}
+
+// updateOrderResponse is encoded from the typed endpoint under test.
+type updateOrderResponse struct {
+	ID     int      `json:"id"`
+	Notify bool     `json:"notify"`
+	Tags   []string `json:"tags"`
+	Note   string   `json:"note"`
+}
+
+// updateOrder echoes the request, unless its note asks for an error or the order doesn't exist.
+func updateOrder(ctx context.Context, s *service.Service, req updateOrderRequest) (updateOrderResponse, error) {
+	switch {
+	case req.Note == "broken":
+		return updateOrderResponse{}, errors.New("database is unavailable")
+	case req.ID == 7:
+		return updateOrderResponse{}, service.NewHTTPError(http.StatusNotFound, "order_not_found", "order %d does not exist", req.ID)
+	}
+	return updateOrderResponse(req), nil
+}
+
+func TestTypedEndpoint(t *testing.T) {
+	h := servicetest.New(t, servicetest.Config{})
+	if err := service.AddTypedPublicEndpoint(h.Service, "PUT", "/orders/:id", updateOrder); err != nil {
+		t.Fatalf("failed to add endpoint: %s", err.Error())
+	}
+	if err := service.AddTypedEndpoint(h.Service, "PUT", "/accounts/:id", "accounts.write", updateOrder); err != nil {
+		t.Fatalf("failed to add endpoint: %s", err.Error())
+	}
+
+	tests := []struct {
+		name     string
+		target   string
+		body     string
+		status   int
+		expected *updateOrderResponse // expected is the response, when the request succeeds
+		field    string               // field is the field expected to fail, if any
+		tag      string               // tag is the check the field is expected to fail
+	}{
+		{name: "path, query and body", target: "/orders/42?notify=true&tag=a&tag=b", body: `{"note":"gift"}`, status: http.StatusOK, expected: &updateOrderResponse{ID: 42, Notify: true, Tags: []string{"a", "b"}, Note: "gift"}},
+		{name: "invalid path parameter", target: "/orders/abc", body: `{"note":"gift"}`, status: http.StatusBadRequest, field: "id", tag: "type"},
+		{name: "invalid query parameter", target: "/orders/42?notify=maybe", body: `{"note":"gift"}`, status: http.StatusBadRequest, field: "notify", tag: "type"},
+		{name: "failed validation", target: "/orders/42", body: `{}`, status: http.StatusBadRequest, field: "note", tag: "required"},
+		{name: "malformed body", target: "/orders/42", body: `{"note":`, status: http.StatusBadRequest},
+		{name: "handler error", target: "/orders/42", body: `{"note":"broken"}`, status: http.StatusInternalServerError},
+		{name: "handler HTTP error", target: "/orders/7", body: `{"note":"gift"}`, status: http.StatusNotFound},
+		{name: "unauthenticated", target: "/accounts/42", body: `{"note":"gift"}`, status: http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := h.Do(httptest.NewRequest("PUT", test.target, strings.NewReader(test.body)))
+			if resp.StatusCode != test.status {
+				t.Fatalf("expected status %d, got %d", test.status, resp.StatusCode)
+			}
+			if test.expected != nil {
+				var order updateOrderResponse
+				if err := json.NewDecoder(resp.Body).Decode(&order); err != nil {
+					t.Fatalf("failed to decode response: %s", err.Error())
+				}
+				if !reflect.DeepEqual(order, *test.expected) {
+					t.Errorf("expected %+v, got %+v", *test.expected, order)
+				}
+			}
+			if test.field != "" {
+				problem := decodeProblem(t, resp)
+				if problem.Code != service.CodeInvalidRequest || len(problem.Details) != 1 || problem.Details[0].Field != test.field || problem.Details[0].Tag != test.tag {
+					t.Errorf("expected the field %q to fail %q, got %+v", test.field, test.tag, problem)
+				}
+			}
+		})
+	}
+
+	// The routes of typed endpoints describe their types
+	routes, _ := h.Service.Routes()
+	for _, route := range routes {
+		if route.Kind == service.EndpointPublic || route.Kind == service.EndpointAuthenticated {
+			if route.Request != reflect.TypeFor[updateOrderRequest]() || route.Response != reflect.TypeFor[updateOrderResponse]() {
+				t.Errorf("expected %s to describe its types, got %v and %v", route, route.Request, route.Response)
+			}
+		}
+	}
+}
+
+// problemResponse is the application/problem+json document of an error response.
+type problemResponse struct {
+	Type      string               `json:"type"`
+	Title     string               `json:"title"`
+	Status    int                  `json:"status"`
+	Detail    string               `json:"detail"`
+	Code      string               `json:"code"`
+	Retryable bool                 `json:"retryable"`
+	Details   []service.FieldError `json:"details"`
+}
+
+// decodeProblem decodes the problem details of an error response, failing the test if it isn't one.
+func decodeProblem(t *testing.T, resp *service.HTTPResponse) problemResponse {
+	t.Helper()
+	var problem problemResponse
+	if contentType := resp.Headers.Get("Content-Type"); contentType != service.ProblemContentType {
+		t.Fatalf("expected the content type %q, got %q", service.ProblemContentType, contentType)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+		t.Fatalf("failed to decode problem: %s", err.Error())
+	}
+	return problem
+}
@@ -25,28 +25,60 @@ package service
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	cloudtasks "cloud.google.com/go/cloudtasks/apiv2"
 	credentials "cloud.google.com/go/iam/credentials/apiv1"
 	"cloud.google.com/go/storage"
 	"github.com/albeebe/service/pkg/auth"
+	"github.com/albeebe/service/pkg/logger"
+	"github.com/albeebe/service/pkg/metrics"
 	"github.com/albeebe/service/pkg/pubsub"
 	"github.com/albeebe/service/pkg/router"
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/oauth2/google"
 )
 
-type EndpointHandler func(*Service, *http.Request) *HTTPResponse
+// EndpointHandler handles a request to an endpoint. The context is the request's context, which is
+// cancelled when the client disconnects and carries the caller's verified identity for authenticated
+// and service endpoints (see Principal and Claims), so it should be passed to any work done on the
+// caller's behalf.
+type EndpointHandler func(ctx context.Context, s *Service, r *http.Request) *HTTPResponse
 
 type PubSubHandler func(*Service, PubSubMessage) error
 
 type WebsocketHandler func(*Service, *websocket.Conn)
 
+// Component is a part of the service with its own lifecycle, such as a database connection, a
+// cache, a gRPC connection or a background worker. Components are set up concurrently, with each
+// component only being set up once all of the components it depends on have been set up. They are
+// torn down in reverse dependency order when the service terminates.
+type Component interface {
+	// Name returns the unique name of the component, which other components use to depend on it.
+	Name() string
+
+	// Setup prepares the component for use. The context is canceled when the service terminates.
+	Setup(ctx context.Context) error
+
+	// Teardown releases the component's resources. The context expires when the time allowed
+	// for the service to tear down has elapsed.
+	Teardown(ctx context.Context) error
+
+	// DependsOn returns the names of the components that must be set up before this component,
+	// and that must only be torn down after it.
+	DependsOn() []string
+}
+
 type Service struct {
 	Context            context.Context
 	CloudStorageClient *storage.Client
@@ -55,17 +87,41 @@ type Service struct {
 	IAMClient          *credentials.IamCredentialsClient
 	DB                 *sql.DB
 	Log                *slog.Logger
+	LogLevels          *logger.Levels
+	Metrics            *metrics.Registry
 	Name               string
+	Tracer             trace.Tracer
 	internal           *internal
 }
 
 type Config struct {
-	CloudSQLConnection string // Cloud SQL instance connection string in the format "project:region:instance"
-	CloudSQLDatabase   string // Name of the specific database within the Cloud SQL instance
-	CloudSQLUser       string // Username for accessing the Cloud SQL database
-	GCPProjectID       string // Google Cloud Platform Project ID where the service is deployed
-	Host               string // The host address where the service listens for incoming requests (e.g., ":8080")
-	ServiceAccount     string // Service account email used for authentication with GCP resources
+	CloudSQLConnection string      // Cloud SQL instance connection string in the format "project:region:instance"
+	CloudSQLDatabase   string      // Name of the specific database within the Cloud SQL instance
+	CloudSQLUser       string      // Username for accessing the Cloud SQL database
+	GCPProjectID       string      // Google Cloud Platform Project ID where the service is deployed
+	Host               string      // The host address where the service listens for incoming requests (e.g., ":8080")
+	LogDelivery        LogDelivery // How logs are delivered to Google Cloud Logging in production, defaults to LogDeliveryAPI
+	ServiceAccount     string      // Service account email used for authentication with GCP resources, required by CreateCloudTask and GenerateGoogleIDToken
+}
+
+// LogDelivery is how the service delivers its logs to Google Cloud Logging in production.
+type LogDelivery string
+
+const (
+	LogDeliveryAPI    LogDelivery = "api"    // Send entries with the Google Cloud Logging client, which buffers them and flushes them on shutdown
+	LogDeliveryStdout LogDelivery = "stdout" // Write entries to standard output as structured JSON, which the logging agent on Cloud Run and GKE ingests
+)
+
+// FieldError describes a request field that failed to decode or validate.
+type FieldError struct {
+	Field   string `json:"field"`         // Name of the field, preferring its JSON, path or query name
+	Tag     string `json:"tag,omitempty"` // Validation tag that failed (e.g. "required"), if any
+	Message string `json:"message"`       // Human readable description of the failure
+}
+
+// Error returns the message describing the failure.
+func (e *FieldError) Error() string {
+	return e.Message
 }
 
 type HTTPResponse struct {
@@ -81,22 +137,71 @@ type PubSubMessage struct {
 }
 
 type State struct {
-	Starting    func()          // Called when the service is starting
-	Running     func()          // Called when the service is running
-	Terminating func(err error) // Called when the service is terminating, with an optional error if it was due to a failure
+	Starting    func()                      // Called when the service is starting
+	Running     func()                      // Called when the service is running
+	Terminating func(err error)             // Called when the service is terminating, with an optional error if it was due to a failure
+	Terminated  func(report ShutdownReport) // Called once the service has shut down, with the outcome of tearing down each component
+}
+
+// ShutdownPolicy controls how the service shuts down once it begins terminating.
+type ShutdownPolicy struct {
+	// DrainDelay is how long the service keeps serving requests after the readiness endpoint starts
+	// failing, giving load balancers time to stop routing new traffic to it. Defaults to zero.
+	DrainDelay time.Duration
+
+	// RouterTimeout is the maximum time to wait for in-flight requests to complete once the
+	// router stops accepting connections. Defaults to 5 seconds.
+	RouterTimeout time.Duration
+
+	// TeardownTimeout is the maximum time allowed for tearing down the remaining components,
+	// such as closing the Google Cloud clients and the database. Defaults to 5 seconds.
+	TeardownTimeout time.Duration
+}
+
+// ShutdownReport describes the outcome of shutting down the service.
+type ShutdownReport struct {
+	Components []ComponentShutdown // Outcome of tearing down each component, in the order they were torn down
+}
+
+// ComponentShutdown describes the outcome of tearing down a single component.
+type ComponentShutdown struct {
+	Name     string        // Name of the component
+	Duration time.Duration // Time taken to tear down the component
+	Error    error         // Error encountered while tearing down the component, if any
+	TimedOut bool          // Whether the component didn't finish tearing down before the deadline
+}
+
+type TestDependencies struct {
+	AuthProvider auth.AuthProvider // Optional provider used to authenticate and authorize requests
+	Logger       *slog.Logger      // Optional logger, output is discarded when nil
+	Publisher    pubsub.Publisher  // Optional publisher that receives messages sent with PublishToPubSub
+	Options      []Option          // Additional options, such as WithDB, applied when creating the service
 }
 
 type internal struct {
-	auth   *auth.Auth
-	cancel context.CancelFunc
-	config *Config
-	pubsub *pubsub.PubSub
-	router *router.Router
+	auth           *auth.Auth
+	cancel         context.CancelFunc
+	components     *componentRegistry
+	config         *Config
+	logFile        *logger.RotatingFile
+	metrics        *serviceMetrics
+	middleware     []Middleware
+	middlewareMux  sync.RWMutex
+	options        *options
+	panics         atomic.Uint64
+	propagator     propagation.TextMapPropagator
+	pubsub         pubsub.Publisher
+	router         *router.Router
+	routes         *routeTable
+	running        atomic.Bool
+	terminating    atomic.Bool
+	tracerProvider *sdktrace.TracerProvider
 }
 
 // validate checks the Config struct for required fields and
-// returns an error if any required fields are missing
-func (config *Config) validate() error {
+// returns an error if any required fields are missing. Fields are only
+// required when the components that use them are enabled by the options.
+func (config *Config) validate(o *options) error {
 
 	if config.CloudSQLConnection != "" {
 		if config.CloudSQLDatabase == "" {
@@ -123,16 +228,62 @@ func (config *Config) validate() error {
 	}
 
 	if config.GCPProjectID == "" {
-		return fmt.Errorf("GCPProjectID is empty")
+		if o.createsPubSub() {
+			return fmt.Errorf("GCPProjectID is empty, it's required to create the Pub/Sub client")
+		}
+		if o.logger == nil && runningInProduction() {
+			return fmt.Errorf("GCPProjectID is empty, it's required to log to Google Cloud Logging")
+		}
 	}
 
 	if config.Host == "" {
 		return fmt.Errorf("Host is empty")
 	}
 
-	if config.ServiceAccount == "" {
-		return fmt.Errorf("ServiceAccount is empty")
+	switch config.LogDelivery {
+	case "", LogDeliveryAPI, LogDeliveryStdout:
+	default:
+		return fmt.Errorf("LogDelivery %q is invalid, it must be %q or %q", config.LogDelivery, LogDeliveryAPI, LogDeliveryStdout)
 	}
 
 	return nil
 }
+
+// withDefaults returns a copy of the policy with the defaults applied to any unset fields.
+func (policy ShutdownPolicy) withDefaults() ShutdownPolicy {
+	if policy.DrainDelay < 0 {
+		policy.DrainDelay = 0
+	}
+	if policy.RouterTimeout <= 0 {
+		policy.RouterTimeout = 5 * time.Second
+	}
+	if policy.TeardownTimeout <= 0 {
+		policy.TeardownTimeout = 5 * time.Second
+	}
+	return policy
+}
+
+// Err joins the errors of every component that failed or timed out while tearing down.
+// It returns nil if every component was torn down successfully.
+func (report ShutdownReport) Err() error {
+	var errs []error
+	for _, component := range report.Components {
+		if component.TimedOut {
+			errs = append(errs, fmt.Errorf("timed out tearing down %s", component.Name))
+		} else if component.Error != nil {
+			errs = append(errs, fmt.Errorf("failed to tear down %s: %w", component.Name, component.Error))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// TimedOut returns the names of the components that didn't finish tearing down before the deadline.
+func (report ShutdownReport) TimedOut() []string {
+	names := []string{}
+	for _, component := range report.Components {
+		if component.TimedOut {
+			names = append(names, component.Name)
+		}
+	}
+	return names
+}
@@ -71,7 +71,15 @@ func JSON(statusCode int, obj interface{}) *HTTPResponse {
 	r.Headers.Set("Content-Type", "application/json")
 	pr, pw := io.Pipe()
 	go func() {
-		defer pw.Close()
+		// A panic while encoding (e.g. in a MarshalJSON method) is passed to the reader as an
+		// error, rather than taking down the process
+		defer func() {
+			if value := recover(); value != nil {
+				pw.CloseWithError(newPanicError(value))
+				return
+			}
+			pw.Close()
+		}()
 		err := json.NewEncoder(pw).Encode(obj)
 		if err != nil {
 			pw.Write([]byte(`null`))
@@ -81,22 +89,27 @@ func JSON(statusCode int, obj interface{}) *HTTPResponse {
 	return r
 }
 
-// InternalServerError returns an HTTP 500 response with a standard "internal server error" message.
+// InternalServerError returns an HTTP 500 problem details response with a standard "internal server error" message.
 func InternalServerError() *HTTPResponse {
-	return Text(500, "internal server error")
+	return internalError().Response()
 }
 
-// NotImplemented returns an HTTP 501 response with a standard "not implemented" message.
+// NotImplemented returns an HTTP 501 problem details response with a standard "not implemented" message.
 func NotImplemented() *HTTPResponse {
-	return Text(501, "not implemented")
+	return NewHTTPError(http.StatusNotImplemented, CodeNotImplemented, "not implemented").Response()
 }
 
+var (
+	errBodyEmpty   = errors.New("request body is empty")
+	errBodyMissing = errors.New("request body is missing")
+)
+
 // UnmarshalJSONBody reads the JSON-encoded body of an HTTP request and unmarshals it into the provided target.
 // It returns an error if the request body is empty or if the JSON decoding fails.
 func UnmarshalJSONBody(r *http.Request, target interface{}) error {
 	// Ensure the request body is not nil or empty
 	if r.Body == nil {
-		return errors.New("request body is missing")
+		return errBodyMissing
 	}
 	defer r.Body.Close()
 
@@ -104,7 +117,7 @@ func UnmarshalJSONBody(r *http.Request, target interface{}) error {
 	decoder := json.NewDecoder(r.Body)
 	if err := decoder.Decode(target); err != nil {
 		if err == io.EOF {
-			return errors.New("request body is empty")
+			return errBodyEmpty
 		}
 		// Provide more detailed error messages for common JSON decoding issues
 		var syntaxError *json.SyntaxError
@@ -132,32 +145,33 @@ func runningInProduction() bool {
 	return metadata.OnGCE()
 }
 
-// sendResponse is a helper function that simplifies sending HTTP responses
-// with a given status code and message.
-func sendResponse(w http.ResponseWriter, statusCode int, message string) {
-	response := Text(statusCode, message)
+// sendError is a helper function that simplifies sending an HTTPError as a
+// problem details response.
+func sendError(w http.ResponseWriter, err *HTTPError) {
+	response := err.Response()
 	router.SendResponse(w, response.StatusCode, response.Headers, response.Body)
 }
 
 // ValidateStruct validates a struct (or pointer to struct) using `validate` tags.
 // It returns a slice of errors (nil if the struct is valid). Field names in
-// error messages prefer the JSON tag when present.
+// error messages prefer the JSON tag when present. Each validation failure is
+// a *FieldError, identifying the field that failed.
 func ValidateStruct(s any) []error {
 	v := validator.New()
 
-	// Prefer JSON tag names in error messages (e.g., "first_name" instead of "FirstName")
+	// Prefer JSON tag names in error messages (e.g., "first_name" instead of "FirstName"),
+	// falling back to the query and path parameter names used by typed endpoints
 	v.RegisterTagNameFunc(func(fld reflect.StructField) string {
-		name := fld.Tag.Get("json")
-		if name == "-" || name == "" {
-			return fld.Name
-		}
-		if i := strings.IndexByte(name, ','); i >= 0 {
-			name = name[:i]
-		}
-		if name == "" {
-			return fld.Name
+		for _, key := range []string{"json", "query", "path"} {
+			name := fld.Tag.Get(key)
+			if i := strings.IndexByte(name, ','); i >= 0 {
+				name = name[:i]
+			}
+			if name != "" && name != "-" {
+				return name
+			}
 		}
-		return name
+		return fld.Name
 	})
 
 	if err := v.Struct(s); err != nil {
@@ -170,16 +184,18 @@ func ValidateStruct(s any) []error {
 		out := make([]error, 0, len(verrs))
 		for _, fe := range verrs {
 			field := fe.Field()
+			fieldErr := &FieldError{Field: field, Tag: fe.Tag()}
 			switch fe.Tag() {
 			case "required":
-				out = append(out, fmt.Errorf("%s is required", field))
+				fieldErr.Message = fmt.Sprintf("%s is required", field)
 			case "min":
-				out = append(out, fmt.Errorf("%s must be at least %s character(s)", field, fe.Param()))
+				fieldErr.Message = fmt.Sprintf("%s must be at least %s character(s)", field, fe.Param())
 			case "max":
-				out = append(out, fmt.Errorf("%s must be at most %s character(s)", field, fe.Param()))
+				fieldErr.Message = fmt.Sprintf("%s must be at most %s character(s)", field, fe.Param())
 			default:
-				out = append(out, fmt.Errorf("%s failed %s validation", field, fe.Tag()))
+				fieldErr.Message = fmt.Sprintf("%s failed %s validation", field, fe.Tag())
 			}
+			out = append(out, fieldErr)
 		}
 		return out
 	}